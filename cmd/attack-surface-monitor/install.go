@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/scanners"
+	"github.com/IxBahy/ASM/pkg/client"
+)
+
+// installAll installs scanners concurrently, reporting each tool's progress
+// as it changes, and finishes with a summary of every install. With
+// jsonEvents the events are written to stdout as JSON lines, and everything
+// else goes to stderr so the stream stays parseable.
+func installAll(installer *toolInstaller, list []scanners.Scanner, jobs int, jsonEvents bool) error {
+	// Downloads run side by side, so a progress line updated in place
+	// would be overwritten by the others.
+	client.SetProgressFunc(printDownloadDone)
+
+	out := os.Stdout
+	events := printInstallEvent
+	summary := io.Writer(os.Stdout)
+	if jsonEvents {
+		encoder := json.NewEncoder(out)
+		events = func(event scanners.InstallEvent) {
+			encoder.Encode(event)
+		}
+		summary = os.Stderr
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = out }()
+	}
+
+	results := scanners.InstallAll(list, scanners.InstallOptions{
+		Jobs:    jobs,
+		Install: installer.install,
+		Events:  events,
+	})
+
+	failed := 0
+	fmt.Fprintln(summary)
+	w := tabwriter.NewWriter(summary, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSTATUS\tVERSION\tTIME\tERROR")
+	for _, result := range results {
+		version, message := result.Version, ""
+		if version == "" {
+			version = "-"
+		}
+		if result.Err != nil {
+			failed++
+			message = result.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Tool, result.Type, result.Status, version, result.Elapsed.Round(100*time.Millisecond), message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tools failed to install", failed, len(results))
+	}
+	return nil
+}
+
+// printInstallEvent prints the progress of one tool in installAll.
+func printInstallEvent(event scanners.InstallEvent) {
+	switch event.Status {
+	case scanners.InstallStarted:
+		fmt.Printf("[%s] installing (%s)\n", event.Tool, event.Type)
+	case scanners.InstallSucceeded:
+		fmt.Printf("[%s] installed %s in %s\n", event.Tool, event.Version, event.Elapsed.Round(100*time.Millisecond))
+	case scanners.InstallSkipped:
+		fmt.Printf("[%s] already installed\n", event.Tool)
+	case scanners.InstallDryRun:
+		fmt.Printf("[%s] not installed: dry run\n", event.Tool)
+	case scanners.InstallFailed:
+		fmt.Printf("[%s] failed: %s\n", event.Tool, event.Error)
+	}
+}
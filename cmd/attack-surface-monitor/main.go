@@ -2,9 +2,65 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"os"
+
+	"github.com/IxBahy/ASM/pkg/client"
 )
 
+const usage = `Usage: attack-surface-monitor <command> [flags]
+
+Commands:
+  serve        Run the HTTP API server
+  scan         Run scanners against a target and record the results in a workspace
+  workspace    Create, list, update, delete, export and import workspaces
+  token        Create, list and revoke API tokens
+  tools        Install, upgrade and uninstall scanners, and bundle them for offline use
+  self-update  Update this binary from a signed release source
+  version      Print the version of this binary
+
+Run "attack-surface-monitor <command> -h" for the flags of a command.
+`
+
+// version is the release version of the binary, set at build time with
+// -ldflags "-X main.version=<version>".
+var version = "dev"
+
 func main() {
-	fmt.Println("Attack Surface Monitor starting...")
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Tools installed into isolated environments are reached through their
+	// shims.
+	client.AddBinDirToPath()
+	loadSuffixList()
+
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = runServe(os.Args[2:])
+	case "scan":
+		err = runScan(os.Args[2:])
+	case "workspace":
+		err = runWorkspace(os.Args[2:])
+	case "token":
+		err = runToken(os.Args[2:])
+	case "tools":
+		err = runTools(os.Args[2:])
+	case "self-update":
+		err = runSelfUpdate(os.Args[2:])
+	case "version":
+		fmt.Println(version)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
 
+	if err != nil {
+		log.Fatal(err)
+	}
 }
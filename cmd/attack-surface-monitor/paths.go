@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dataDir returns the directory ASM keeps its state in, following the XDG
+// base directory specification.
+func dataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "asm")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", "asm")
+	}
+	return ".asm"
+}
+
+func defaultPath(name string) string {
+	return filepath.Join(dataDir(), name)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/IxBahy/ASM/pkg/client"
+)
+
+// printProgress renders download progress on stderr: a line updated in
+// place on a terminal, and only the summary of each download otherwise.
+func printProgress(event client.DownloadProgress) {
+	if !isTerminal(os.Stderr) {
+		printDownloadDone(event)
+		return
+	}
+	if event.Cached {
+		return
+	}
+
+	line, rate := formatProgress(event)
+	// Clear the rest of the line, which may hold a longer previous update.
+	fmt.Fprintf(os.Stderr, "\r%s: %s, %s\x1b[K", event.Name, line, rate)
+	if event.Done {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// printDownloadDone prints the summary of each finished download on stderr.
+func printDownloadDone(event client.DownloadProgress) {
+	if event.Cached || !event.Done {
+		return
+	}
+	line, rate := formatProgress(event)
+	fmt.Fprintf(os.Stderr, "Downloaded %s: %s at %s\n", event.Name, line, rate)
+}
+
+func formatProgress(event client.DownloadProgress) (string, string) {
+	line := client.FormatBytes(event.Downloaded)
+	if event.Total >= 0 {
+		line = fmt.Sprintf("%s / %s (%d%%)", line, client.FormatBytes(event.Total), percent(event.Downloaded, event.Total))
+	}
+	return line, fmt.Sprintf("%s/s", client.FormatBytes(int64(event.Rate)))
+}
+
+func percent(n, total int64) int64 {
+	if total <= 0 {
+		return 100
+	}
+	return n * 100 / total
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
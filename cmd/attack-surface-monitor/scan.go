@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/IxBahy/ASM/internal/scanners"
+	"github.com/IxBahy/ASM/internal/scanners/builtin"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+const scanUsage = `Usage: attack-surface-monitor scan [flags] <target>
+
+Scanners that take options get them with -options, as a JSON object by
+scanner name:
+
+  attack-surface-monitor scan -scanners nmap -options '{"nmap": {"ports": "1-1024", "scripts": ["http-title"]}}' example.com
+
+`
+
+// runScan runs scanners against a target in the foreground and adds the
+// results to the workspace's inventory.
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	workspacesPath := flags.String("workspaces", defaultPath("workspaces"), "directory workspaces are stored in")
+	workspaceName := flags.String("workspace", workspace.Default, "workspace to record the results in")
+	scannerNames := flags.String("scanners", "", "comma-separated scanners to run in order")
+	optionsJSON := flags.String("options", "", "JSON object of scanner options by scanner name")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, scanUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	target := flags.Arg(0)
+	names := splitList(*scannerNames)
+	if len(names) == 0 {
+		return fmt.Errorf("-scanners is required")
+	}
+	options := map[string]json.RawMessage{}
+	if *optionsJSON != "" {
+		if err := json.Unmarshal([]byte(*optionsJSON), &options); err != nil {
+			return fmt.Errorf("invalid -options: %w", err)
+		}
+	}
+	for name := range options {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("options given for %s, which is not in -scanners", name)
+		}
+	}
+
+	manager, err := workspace.Open(*workspacesPath)
+	if err != nil {
+		return err
+	}
+	ws, exists := manager.Get(*workspaceName)
+	if !exists {
+		return fmt.Errorf("%w: %s", workspace.ErrNotFound, *workspaceName)
+	}
+	if !ws.Scope.Allows(target) {
+		return fmt.Errorf("target %s is outside the scope of workspace %s", target, ws.Name)
+	}
+	store, _ := manager.Inventory(ws.Name)
+
+	registry := scanners.NewScannerRegistry()
+	builtin.Register(registry)
+
+	for _, name := range names {
+		scanner, exists := registry.Get(name)
+		if !exists {
+			return fmt.Errorf("unknown scanner: %s", name)
+		}
+		if !scanner.IsInstalled() {
+			return fmt.Errorf("%s is not installed", name)
+		}
+		if err := scanners.ValidateOptions(scanner, options[name]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Running %s against %s...\n", name, target)
+		result, err := scanners.RunScanWithOptions(scanner, target, options[name])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", name, err)
+		}
+		if err := store.Ingest(name, target, result.Data); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		}
+		fmt.Printf("%s produced %d results\n", name, len(result.Data))
+	}
+
+	if err := manager.SaveInventory(ws.Name); err != nil {
+		return err
+	}
+	fmt.Printf("Workspace %s now holds %d assets\n", ws.Name, store.Size())
+	return nil
+}
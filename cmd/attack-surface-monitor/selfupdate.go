@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aead.dev/minisign"
+	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/Masterminds/semver/v3"
+)
+
+const selfUpdateUsage = `Usage: attack-surface-monitor self-update [-source <dir|url>] [-key <public key>] [-check] [-force]
+       attack-surface-monitor self-update -rollback
+       attack-surface-monitor self-update publish -key <secret key> -version <version> -o <dir> <os>/<arch>=<binary>...
+
+self-update replaces this binary with the release published to a release
+source: a directory, such as a mounted share, or an HTTP(S) mirror of one.
+The source holds release.json, signed with minisign, and the build for
+every platform it lists. The release must be signed with the public key
+passed with -key or in ASM_UPDATE_KEY, and the build must match the digest
+in it. The source defaults to ASM_UPDATE_SOURCE.
+
+The new binary is swapped in with a rename and run once with "version";
+if it does not report the release's version, the previous binary is put
+back. The previous binary is kept next to this one, and -rollback restores
+it.
+
+"self-update publish" writes a release source for the given builds, signed
+with a key from "tools keygen".
+`
+
+func runSelfUpdate(args []string) error {
+	if len(args) > 0 && args[0] == "publish" {
+		return publishRelease(args[1:])
+	}
+
+	flags := flag.NewFlagSet("self-update", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, selfUpdateUsage); flags.PrintDefaults() }
+	source := flags.String("source", os.Getenv(client.UpdateSourceEnv), "release source: a directory or HTTP(S) URL")
+	publicKey := flags.String("key", os.Getenv(client.UpdateKeyEnv), "minisign public key, or public key file, releases must be signed with")
+	check := flags.Bool("check", false, "only report whether an update is available")
+	force := flags.Bool("force", false, "install the release even if it is not newer")
+	rollback := flags.Bool("rollback", false, "restore the binary replaced by the last update")
+	flags.Parse(args)
+
+	target, err := executablePath()
+	if err != nil {
+		return err
+	}
+	if *rollback {
+		if err := client.RollbackExecutable(target); err != nil {
+			return err
+		}
+		fmt.Printf("Restored the previous version of %s\n", target)
+		return nil
+	}
+
+	updateSource, err := client.NewUpdateSource(*source, *publicKey)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	release, err := updateSource.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if !*force && !newerVersion(release.Version, version) {
+		fmt.Printf("attack-surface-monitor %s is up to date\n", version)
+		return nil
+	}
+	if *check {
+		fmt.Printf("attack-surface-monitor %s is available, %s is running\n", release.Version, version)
+		return nil
+	}
+
+	client.SetProgressFunc(printProgress)
+	defer client.SetProgressFunc(nil)
+	fmt.Printf("Updating %s from %s to %s\n", target, version, release.Version)
+	if err := updateSource.Apply(ctx, release, target); err != nil {
+		return err
+	}
+	fmt.Printf("Updated to %s; \"self-update -rollback\" restores %s\n", release.Version, version)
+	return nil
+}
+
+func publishRelease(args []string) error {
+	flags := flag.NewFlagSet("self-update publish", flag.ExitOnError)
+	keyPath := flags.String("key", "asm-bundle.key", "minisign secret key to sign the release with")
+	releaseVersion := flags.String("version", "", "version of the release")
+	output := flags.String("o", "asm-release", "directory to write the release source to")
+	flags.Parse(args)
+
+	if *releaseVersion == "" || flags.NArg() == 0 {
+		return fmt.Errorf("usage: self-update publish -key <secret key> -version <version> -o <dir> <os>/<arch>=<binary>...")
+	}
+	builds := make(map[client.Platform]string, flags.NArg())
+	for _, arg := range flags.Args() {
+		name, path, found := strings.Cut(arg, "=")
+		platforms, err := parsePlatforms(name)
+		if !found || err != nil || len(platforms) != 1 {
+			return fmt.Errorf("invalid build %q, expected <os>/<arch>=<binary>", arg)
+		}
+		builds[platforms[0]] = path
+	}
+
+	key, err := minisign.PrivateKeyFromFile(os.Getenv(bundleKeyPasswordEnv), *keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+	release, err := client.PublishRelease(*output, *releaseVersion, builds, key)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Published %s for %d platforms to %s\n", release.Version, len(release.Builds), *output)
+	return nil
+}
+
+// executablePath returns the path of the running binary, with symlinks
+// resolved so the update replaces the binary rather than the link.
+func executablePath() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the running binary: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the running binary: %w", err)
+	}
+	return resolved, nil
+}
+
+// newerVersion reports whether release is newer than current. A current
+// version that is not a semantic version, such as "dev", is always older.
+func newerVersion(release, current string) bool {
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return true
+	}
+	releaseVersion, err := semver.NewVersion(release)
+	if err != nil {
+		return release != current
+	}
+	return releaseVersion.GreaterThan(currentVersion)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/api"
+	"github.com/IxBahy/ASM/internal/audit"
+	"github.com/IxBahy/ASM/internal/auth"
+	"github.com/IxBahy/ASM/internal/dashboard"
+	"github.com/IxBahy/ASM/internal/jobs"
+	"github.com/IxBahy/ASM/internal/metrics"
+	"github.com/IxBahy/ASM/internal/notify"
+	"github.com/IxBahy/ASM/internal/scanners"
+	"github.com/IxBahy/ASM/internal/scanners/builtin"
+	"github.com/IxBahy/ASM/internal/scheduler"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	workers := flags.Int("workers", 2, "number of scan jobs to run concurrently")
+	queueSize := flags.Int("queue", 100, "maximum number of queued scan jobs")
+	keepJobs := flags.Int("keep-jobs", jobs.DefaultRetention, "number of finished jobs kept for the API")
+	tokensPath := flags.String("tokens", defaultPath("tokens.json"), "API token store")
+	auditPath := flags.String("audit-log", defaultPath("audit.log"), "file the audit trail is appended to")
+	workspacesPath := flags.String("workspaces", defaultPath("workspaces"), "directory workspaces and their inventories are stored in")
+	noAuth := flags.Bool("no-auth", false, "disable authentication and treat every request as admin")
+	flags.Parse(args)
+
+	fmt.Println("Attack Surface Monitor starting...")
+
+	authenticator, err := newAuthenticator(*tokensPath, *noAuth)
+	if err != nil {
+		return err
+	}
+
+	auditLog, auditFile, err := audit.Open(*auditPath)
+	if err != nil {
+		return err
+	}
+	defer auditFile.Close()
+
+	workspaces, err := workspace.Open(*workspacesPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	registry := scanners.NewScannerRegistry()
+	builtin.Register(registry)
+
+	manager := jobs.NewManager(registry, *queueSize)
+	manager.SetRetention(*keepJobs)
+	manager.AddListener(func(event jobs.Event) {
+		store, exists := workspaces.Inventory(event.Job.Workspace)
+		if !exists {
+			return
+		}
+		switch {
+		case event.Type == jobs.EventResult:
+			if err := store.Ingest(event.Result.Scanner, event.Job.Target, event.Result.Data); err != nil {
+				log.Printf("inventory: %v", err)
+			}
+		case event.Job.Done():
+			if err := workspaces.SaveInventory(event.Job.Workspace); err != nil {
+				log.Printf("inventory: %v", err)
+			}
+		}
+	})
+	manager.AddListener(func(event jobs.Event) {
+		if event.Type != jobs.EventStatus || !event.Job.Done() {
+			return
+		}
+		auditLog.Record(audit.Entry{
+			Identity: event.Job.RequestedBy,
+			Action:   "job.finish",
+			Resource: event.Job.ID,
+			Detail:   fmt.Sprintf("workspace=%s status=%s", event.Job.Workspace, event.Job.Status),
+		})
+	})
+	manager.Start(ctx, *workers)
+
+	go scheduler.New(workspaces, manager).Run(ctx, time.Minute)
+	go notify.NewNotifier(workspaces).Run(ctx)
+
+	collector := newCollector(registry, manager, workspaces)
+	manager.AddListener(collector.ObserveEvent)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", authenticator.Require(auth.RoleViewer, collector))
+	mux.Handle("GET /ui/", dashboard.Handler("/ui/"))
+	mux.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+	mux.Handle("/", api.NewServer(api.Config{
+		Registry:   registry,
+		Jobs:       manager,
+		Workspaces: workspaces,
+		Auth:       authenticator,
+		Audit:      auditLog,
+	}))
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("API server listening on %s", *addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("API server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// newAuthenticator loads the token store, refusing to start an
+// authenticated server that nobody could use.
+func newAuthenticator(tokensPath string, noAuth bool) (*auth.Authenticator, error) {
+	if noAuth {
+		log.Printf("WARNING: authentication is disabled, every request is treated as admin")
+		return auth.Disabled(), nil
+	}
+
+	tokens, err := auth.OpenStore(tokensPath)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.Len() == 0 {
+		return nil, fmt.Errorf("no API tokens in %s: create one with \"attack-surface-monitor token create -name <name> -role admin\" or pass -no-auth", tokensPath)
+	}
+	return auth.NewAuthenticator(tokens), nil
+}
+
+func newCollector(registry *scanners.ScannerRegistry, manager *jobs.Manager, workspaces *workspace.Manager) *metrics.Collector {
+	collector := metrics.NewCollector()
+	collector.RegisterScanners(registry.Names()...)
+
+	collector.AddGauge("asm_job_queue_depth", "Number of scan jobs waiting for a worker.", func() float64 {
+		return float64(manager.QueueDepth())
+	})
+	collector.AddGauge("asm_jobs_in_flight", "Number of scan jobs currently running.", func() float64 {
+		return float64(manager.InFlight())
+	})
+	collector.AddGauge("asm_inventory_assets", "Number of assets in the inventories of all workspaces.", func() float64 {
+		return float64(workspaces.InventorySize())
+	})
+	collector.AddGauge("asm_tools_installed", "Number of registered scanners whose tool is installed.", func() float64 {
+		installed := 0
+		for _, status := range registry.Statuses() {
+			if status.Installed {
+				installed++
+			}
+		}
+		return float64(installed)
+	})
+
+	return collector
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
+)
+
+// suffixListEnv names the public suffix list file that replaces the one
+// built into ASM, overriding suffixListPath.
+const suffixListEnv = "ASM_PUBLIC_SUFFIX_LIST"
+
+// suffixListPath returns the file of the refreshed public suffix list: the
+// value of suffixListEnv, or public_suffix_list.dat in the data directory.
+func suffixListPath() string {
+	if path := os.Getenv(suffixListEnv); path != "" {
+		return path
+	}
+	return defaultPath("public_suffix_list.dat")
+}
+
+// loadSuffixList makes the refreshed public suffix list, if there is one,
+// the list registrable domains are found with. If it cannot be loaded, a
+// warning is printed and the built-in list is kept.
+func loadSuffixList() {
+	path := suffixListPath()
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	list, err := extractor.LoadSuffixList(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %v; using the built-in public suffix list\n", err)
+		return
+	}
+	extractor.SetDefaultSuffixList(list)
+}
+
+// runSuffixes shows the public suffix list in use, or with -update
+// replaces it with a copy of publicsuffix.org/list/public_suffix_list.dat
+// downloaded elsewhere.
+func runSuffixes(flags *flag.FlagSet, args []string) error {
+	update := flags.String("update", "", "public suffix list file to install in place of the current one")
+	reset := flags.Bool("reset", false, "remove the installed list and go back to the built-in one")
+	flags.Parse(args)
+
+	path := suffixListPath()
+	switch {
+	case *update != "":
+		data, err := os.ReadFile(*update)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", *update, err)
+		}
+		list, err := extractor.ParseSuffixList(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%s is not a valid public suffix list: %w", *update, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", tmp, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to install %s: %w", path, err)
+		}
+		fmt.Printf("Installed %s\n", path)
+		printSuffixList(path, list)
+		return nil
+
+	case *reset:
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		extractor.SetDefaultSuffixList(nil)
+		printSuffixList("built in", extractor.EmbeddedSuffixList())
+		return nil
+	}
+
+	source := "built in"
+	if extractor.DefaultSuffixList() != extractor.EmbeddedSuffixList() {
+		source = path
+	}
+	printSuffixList(source, extractor.DefaultSuffixList())
+	fmt.Printf("\nRefresh it with \"tools psl -update <file>\"; a refreshed list is read from %s\n", path)
+	return nil
+}
+
+func printSuffixList(source string, list *extractor.SuffixList) {
+	fmt.Printf("Public suffix list: %s\n", source)
+	fmt.Printf("  rules:  %d ICANN, %d private\n", list.ICANN, list.Private)
+	fmt.Printf("  sha256: %s\n", list.SHA256)
+}
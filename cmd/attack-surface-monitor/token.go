@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IxBahy/ASM/internal/auth"
+)
+
+const tokenUsage = `Usage: attack-surface-monitor token <create|list|revoke> [flags]
+`
+
+func runToken(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, tokenUsage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("token "+args[0], flag.ExitOnError)
+	tokensPath := flags.String("tokens", defaultPath("tokens.json"), "API token store")
+
+	switch args[0] {
+	case "create":
+		name := flags.String("name", "", "name of the person or system the token is for")
+		roleName := flags.String("role", string(auth.RoleViewer), "role of the token: viewer, operator or admin")
+		workspaceNames := flags.String("workspaces", "", "comma-separated workspaces the token may access (default all)")
+		flags.Parse(args[1:])
+
+		if *name == "" {
+			return fmt.Errorf("-name is required")
+		}
+		role, err := auth.ParseRole(*roleName)
+		if err != nil {
+			return err
+		}
+
+		store, err := auth.OpenStore(*tokensPath)
+		if err != nil {
+			return err
+		}
+		secret, token, err := store.Create(*name, role, splitList(*workspaceNames))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created %s token %s for %s. It will not be shown again:\n\n%s\n", token.Role, token.ID, token.Name, secret)
+		return nil
+
+	case "list":
+		flags.Parse(args[1:])
+
+		store, err := auth.OpenStore(*tokensPath)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tROLE\tWORKSPACES\tCREATED")
+		for _, token := range store.List() {
+			workspaces := "all"
+			if len(token.Workspaces) > 0 {
+				workspaces = strings.Join(token.Workspaces, ",")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", token.ID, token.Name, token.Role, workspaces, token.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+
+	case "revoke":
+		id := flags.String("id", "", "id of the token to revoke")
+		flags.Parse(args[1:])
+
+		if *id == "" {
+			return fmt.Errorf("-id is required")
+		}
+		store, err := auth.OpenStore(*tokensPath)
+		if err != nil {
+			return err
+		}
+		if err := store.Revoke(*id); err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked token %s\n", *id)
+		return nil
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown token command: %s\n\n%s", args[0], tokenUsage)
+		os.Exit(2)
+	}
+	return nil
+}
@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"aead.dev/minisign"
+	"github.com/IxBahy/ASM/internal/scanners"
+	"github.com/IxBahy/ASM/internal/scanners/builtin"
+	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/client/utils"
+)
+
+// bundleKeyPasswordEnv holds the password of the minisign secret key used to
+// sign bundles.
+const bundleKeyPasswordEnv = "ASM_BUNDLE_KEY_PASSWORD"
+
+const toolsUsage = `Usage: attack-surface-monitor tools <install|outdated|upgrade|rollback|uninstall|catalog|cache|psl|bundle|keygen|verify> [flags] [scanner...]
+
+install, outdated and upgrade act on every scanner unless some are named.
+Any tool in the catalog can be named, see "tools catalog".
+Installs are pinned to the versions in the lockfile, which records the
+exact version, download URL and SHA-256 of every tool installed; share it
+to run the same builds everywhere.
+
+Installs are atomic: a new version is staged next to the old one, run once
+with --version, and renamed into place, so a failed install or upgrade
+leaves the installed version untouched. The version it replaced is kept,
+and "tools rollback" restores it, along with its lockfile entry.
+
+"tools install -all" installs every scanner concurrently, taking turns
+only for system packages, and prints a summary of what failed; -json
+prints its progress as JSON lines.
+
+Bundles let ASM install its scanners without network access. Create one on
+a connected machine with "tools bundle", copy it over, and point the
+installers at it:
+
+  ASM_TOOLS_MIRROR=asm-tools.tar.gz ASM_TOOLS_MIRROR_KEY=asm-bundle.pub attack-surface-monitor serve
+
+ASM_TOOLS_MIRROR may also name a directory with the layout of an unpacked
+bundle. Scanners installed with the system package manager are not bundled.
+
+Tools are installed into $XDG_DATA_HOME/asm/tools/bin, which is owned by
+the user, so installing them never needs root. Only system packages do;
+pass -sudo, or set ASM_ALLOW_SUDO=1, to let ASM run those through sudo.
+"tools install -dry-run", or ASM_INSTALL_DRY_RUN=1, prints the commands
+that would install system packages and installs nothing; the lockfile is
+left as it is.
+
+Downloaded release assets are cached, see "tools cache"; set
+ASM_DOWNLOAD_CACHE to another directory, or to "off" to disable the cache.
+
+Registrable domains are found with the public suffix list built into ASM.
+"tools psl -update <file>" replaces it with a newer copy of
+https://publicsuffix.org/list/public_suffix_list.dat fetched elsewhere, and
+"tools psl -reset" goes back to the built-in list.
+`
+
+func runTools(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, toolsUsage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("tools "+args[0], flag.ExitOnError)
+	client.SetProgressFunc(printProgress)
+	defer client.SetProgressFunc(nil)
+
+	switch args[0] {
+	case "install":
+		lockPath := flags.String("lockfile", defaultPath("tools.lock"), "lockfile pinning tool versions")
+		sudo := flags.Bool("sudo", false, "allow sudo for installs that need root, such as system packages")
+		frozen := flags.Bool("frozen", false, "fail instead of installing tools missing from the lockfile")
+		upgrade := flags.Bool("upgrade", false, "upgrade installed tools older than the scanner's minimum version without asking")
+		all := flags.Bool("all", false, "install every scanner concurrently and print a summary")
+		jobs := flags.Int("jobs", scanners.DefaultInstallJobs, "with -all, how many tools to install at once")
+		jsonEvents := flags.Bool("json", false, "with -all, print progress events as JSON lines on stdout")
+		dryRun := flags.Bool("dry-run", false, "print the commands that would install system packages, and install nothing")
+		flags.Parse(args[1:])
+		if *sudo {
+			utils.AllowSudo()
+		}
+		if *dryRun {
+			client.EnableDryRun()
+		}
+
+		if *all && flags.NArg() > 0 {
+			return fmt.Errorf("-all installs every scanner and takes no scanner names")
+		}
+
+		lock, selected, err := loadTools(*lockPath, flags.Args())
+		if err != nil {
+			return err
+		}
+		installer := &toolInstaller{
+			lock:        lock,
+			lockPath:    *lockPath,
+			frozen:      *frozen,
+			upgrade:     *upgrade,
+			interactive: !*all,
+		}
+		if *all {
+			return installAll(installer, selected, *jobs, *jsonEvents)
+		}
+		for _, scanner := range selected {
+			if _, err := installer.install(scanner); err != nil && !errors.Is(err, client.ErrDryRun) {
+				return err
+			}
+		}
+		return nil
+
+	case "outdated":
+		lockPath := flags.String("lockfile", defaultPath("tools.lock"), "lockfile pinning tool versions")
+		flags.Parse(args[1:])
+
+		lock, selected, err := loadTools(*lockPath, flags.Args())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tLOCKED\tLATEST\tSTATUS")
+		for _, scanner := range selected {
+			config := scanner.GetConfig()
+			locked, ok := lock.Tools[config.Name]
+			lockedVersion := "-"
+			if ok {
+				lockedVersion = locked.Version
+			}
+
+			latest, status := "-", "not locked"
+			if config.InstallationType == client.InstallationTypeShell {
+				status = "managed by the system package manager"
+			} else if version, err := scanners.LatestVersion(scanner); err != nil {
+				status = err.Error()
+			} else {
+				latest = version
+				if ok && sameVersion(locked.Version, latest) {
+					status = "up to date"
+				} else if ok {
+					status = "outdated"
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", config.Name, config.InstallationType, lockedVersion, latest, status)
+		}
+		return w.Flush()
+
+	case "upgrade":
+		lockPath := flags.String("lockfile", defaultPath("tools.lock"), "lockfile pinning tool versions")
+		sudo := flags.Bool("sudo", false, "allow sudo for installs that need root, such as system packages")
+		flags.Parse(args[1:])
+		if *sudo {
+			utils.AllowSudo()
+		}
+
+		lock, selected, err := loadTools(*lockPath, flags.Args())
+		if err != nil {
+			return err
+		}
+		installer := &toolInstaller{lock: lock, lockPath: *lockPath}
+		for _, scanner := range selected {
+			if err := installer.upgradeTool(scanner); err != nil && !errors.Is(err, client.ErrDryRun) {
+				return err
+			}
+		}
+		return nil
+
+	case "uninstall":
+		lockPath := flags.String("lockfile", defaultPath("tools.lock"), "lockfile pinning tool versions")
+		sudo := flags.Bool("sudo", false, "allow sudo for installs that need root, such as system packages")
+		flags.Parse(args[1:])
+		if *sudo {
+			utils.AllowSudo()
+		}
+
+		if flags.NArg() == 0 {
+			return fmt.Errorf("usage: tools uninstall <scanner>...")
+		}
+		lock, selected, err := loadTools(*lockPath, flags.Args())
+		if err != nil {
+			return err
+		}
+		for _, scanner := range selected {
+			name := scanner.GetConfig().Name
+			if err := scanners.Uninstall(scanner); errors.Is(err, client.ErrDryRun) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			delete(lock.Tools, name)
+			if err := lock.Save(*lockPath); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "catalog":
+		flags.Parse(args[1:])
+
+		catalog := scanners.DefaultCatalog()
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tSOURCE\tDESCRIPTION")
+		for _, name := range catalog.Names() {
+			entry, _ := catalog.Entry(name)
+			source := entry.Repo
+			if source == "" {
+				source = entry.Package
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, entry.Type, source, entry.Description)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nUser entries are read from %s\n", scanners.CatalogPath())
+		return nil
+
+	case "rollback":
+		lockPath := flags.String("lockfile", defaultPath("tools.lock"), "lockfile pinning tool versions")
+		flags.Parse(args[1:])
+
+		if flags.NArg() == 0 {
+			return fmt.Errorf("usage: tools rollback <scanner>...")
+		}
+		lock, selected, err := loadTools(*lockPath, flags.Args())
+		if err != nil {
+			return err
+		}
+		for _, scanner := range selected {
+			name := scanner.GetConfig().Name
+			if err := scanners.Rollback(scanner); err != nil {
+				return err
+			}
+			if locked, ok := lock.Tools[name]; ok && locked.Previous != nil {
+				lock.Tools[name] = *locked.Previous
+				if err := lock.Save(*lockPath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case "bundle":
+		output := flags.String("o", "asm-tools.tar.gz", "bundle to write")
+		keyPath := flags.String("key", "", "minisign secret key to sign the bundle with; its password is read from "+bundleKeyPasswordEnv)
+		platformList := flags.String("platforms", client.CurrentPlatform().String(), "comma-separated os/arch platforms to bundle release assets and Python wheels for")
+		scannerNames := flags.String("scanners", "", "comma-separated scanners to bundle (default all)")
+		flags.Parse(args[1:])
+
+		if *keyPath == "" {
+			return fmt.Errorf("-key is required")
+		}
+		key, err := minisign.PrivateKeyFromFile(os.Getenv(bundleKeyPasswordEnv), *keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read secret key: %w", err)
+		}
+		platforms, err := parsePlatforms(*platformList)
+		if err != nil {
+			return err
+		}
+		selected, err := selectScanners(splitList(*scannerNames))
+		if err != nil {
+			return err
+		}
+
+		builder, err := client.NewBundleBuilder(platforms)
+		if err != nil {
+			return err
+		}
+		defer builder.Close()
+
+		for _, scanner := range selected {
+			name := scanner.GetConfig().Name
+			added, err := scanners.AddToBundle(builder, scanner)
+			if err != nil {
+				return fmt.Errorf("failed to bundle %s: %w", name, err)
+			}
+			if !added {
+				fmt.Printf("Skipping %s: it is installed with the system package manager or built in\n", name)
+			}
+		}
+
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		if err := builder.Write(file, key); err != nil {
+			os.Remove(*output)
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *output, err)
+		}
+
+		fmt.Printf("Wrote %s with %d artifacts\n", *output, len(builder.Tools()))
+		return nil
+
+	case "keygen":
+		output := flags.String("o", "asm-bundle", "path prefix of the key pair; writes <prefix>.key and <prefix>.pub")
+		flags.Parse(args[1:])
+
+		secretPath, publicPath := *output+".key", *output+".pub"
+		for _, path := range []string{secretPath, publicPath} {
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%s already exists", path)
+			}
+		}
+
+		publicKey, secretKey, err := minisign.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		encrypted, err := minisign.EncryptKey(os.Getenv(bundleKeyPasswordEnv), secretKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt key: %w", err)
+		}
+		publicText, err := publicKey.MarshalText()
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(secretPath, encrypted, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", secretPath, err)
+		}
+		if err := os.WriteFile(publicPath, append(publicText, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", publicPath, err)
+		}
+
+		fmt.Printf("Wrote %s and %s. The secret key is encrypted with the password in %s.\n", secretPath, publicPath, bundleKeyPasswordEnv)
+		return nil
+
+	case "cache":
+		flags.Parse(args[1:])
+
+		cache := client.OpenDownloadCache()
+		if cache == nil {
+			fmt.Printf("The download cache is disabled by %s\n", client.DownloadCacheEnv)
+			return nil
+		}
+		switch flags.Arg(0) {
+		case "":
+			files, size, err := cache.Size()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: %d files, %s\n", cache.Dir, files, client.FormatBytes(size))
+		case "clear":
+			if err := cache.Clear(); err != nil {
+				return err
+			}
+			fmt.Printf("Cleared %s\n", cache.Dir)
+		default:
+			return fmt.Errorf("usage: tools cache [clear]")
+		}
+		return nil
+
+	case "psl":
+		return runSuffixes(flags, args[1:])
+
+	case "verify":
+		publicKey := flags.String("key", "", "minisign public key, or public key file, the bundle must be signed with")
+		flags.Parse(args[1:])
+
+		if *publicKey == "" || flags.NArg() != 1 {
+			return fmt.Errorf("usage: tools verify -key <public key> <bundle>")
+		}
+		manifest, err := client.VerifyBundle(flags.Arg(0), *publicKey)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Bundle created %s for %s\n\n", manifest.CreatedAt.Format("2006-01-02 15:04"), strings.Join(manifest.Platforms, ", "))
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tVERSION")
+		for _, tool := range manifest.Tools {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", tool.Name, tool.Type, tool.Version)
+		}
+		return w.Flush()
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown tools command: %s\n\n%s", args[0], toolsUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+// loadTools loads the lockfile and the installable scanners with the given
+// names, or all of them when names is empty.
+func loadTools(lockPath string, names []string) (*scanners.Lockfile, []scanners.Scanner, error) {
+	lock, err := scanners.LoadLockfile(lockPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	selected, err := selectScanners(names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	installable := selected[:0]
+	for _, scanner := range selected {
+		if scanner.GetConfig().InstallationType != "" {
+			installable = append(installable, scanner)
+		} else if len(names) > 0 {
+			return nil, nil, fmt.Errorf("%s is built into ASM", scanner.GetConfig().Name)
+		}
+	}
+	return lock, installable, nil
+}
+
+// toolInstaller installs tools and records them in the lockfile. It is safe
+// for concurrent use: the lockfile is only touched under mu.
+type toolInstaller struct {
+	mu       sync.Mutex
+	lock     *scanners.Lockfile
+	lockPath string
+	// frozen fails the install of tools missing from the lockfile.
+	frozen bool
+	// upgrade upgrades installed tools older than their minimum version.
+	// Otherwise the user is asked, if interactive is set.
+	upgrade     bool
+	interactive bool
+}
+
+// install installs scanner at its locked version, unless it is already
+// installed, and reports whether it was skipped because it was.
+func (t *toolInstaller) install(scanner scanners.Scanner) (bool, error) {
+	name := scanner.GetConfig().Name
+	t.mu.Lock()
+	pinned := t.lock.Pin(scanner)
+	lockedVersion := t.lock.Tools[name].Version
+	t.mu.Unlock()
+	if !pinned && t.frozen {
+		return false, fmt.Errorf("%s is not in the lockfile %s", name, t.lockPath)
+	}
+
+	if scanner.IsInstalled() {
+		var tooOld *scanners.VersionTooOldError
+		err := scanners.CheckVersion(scanner)
+		if errors.As(err, &tooOld) && tooOld.Upgradable && (t.upgrade || t.interactive && confirm(err.Error()+". Upgrade it now?")) {
+			// Upgrade from a fresh scanner, not pinned to the locked
+			// version that is too old.
+			fresh, selectErr := selectScanners([]string{name})
+			if selectErr != nil {
+				return false, selectErr
+			}
+			return false, t.upgradeTool(fresh[0])
+		}
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("%s is already installed\n", name)
+		return true, nil
+	}
+	if err := dryRunInstall(scanner); err != nil {
+		return false, err
+	}
+	if pinned {
+		fmt.Printf("Installing %s %s from the lockfile\n", name, lockedVersion)
+	}
+	if err := scanner.Setup(); err != nil {
+		return false, fmt.Errorf("failed to install %s: %w", name, err)
+	}
+	return false, t.record(scanner)
+}
+
+// upgradeTool installs the latest version of scanner over the installed
+// one and records it in the lockfile.
+func (t *toolInstaller) upgradeTool(scanner scanners.Scanner) error {
+	config := scanner.GetConfig()
+	if config.InstallationType == client.InstallationTypeShell {
+		fmt.Printf("Skipping %s: it is upgraded by the system package manager\n", config.Name)
+		return nil
+	}
+	latest, err := scanners.LatestVersion(scanner)
+	if err != nil {
+		return fmt.Errorf("failed to check %s for updates: %w", config.Name, err)
+	}
+	t.mu.Lock()
+	locked, ok := t.lock.Tools[config.Name]
+	t.mu.Unlock()
+	if ok && sameVersion(locked.Version, latest) && scanner.IsInstalled() {
+		fmt.Printf("%s %s is up to date\n", config.Name, locked.Version)
+		return nil
+	}
+
+	if err := dryRunInstall(scanner); err != nil {
+		return err
+	}
+	if scanner.IsInstalled() {
+		if err := scanners.Reinstall(scanner); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Upgrading %s to %s\n", config.Name, latest)
+	if err := scanner.Setup(); err != nil {
+		return fmt.Errorf("failed to install %s: %w", config.Name, err)
+	}
+	return t.record(scanner)
+}
+
+// dryRunInstall returns client.ErrDryRun for scanners that a dry run does
+// not reach: only system package installs print their commands, so the
+// others are reported and skipped here.
+func dryRunInstall(scanner scanners.Scanner) error {
+	config := scanner.GetConfig()
+	if !client.DryRun() || config.InstallationType == client.InstallationTypeShell {
+		return nil
+	}
+	fmt.Printf("Dry run: %s would be installed with the %s installer\n", config.Name, config.InstallationType)
+	return client.ErrDryRun
+}
+
+// record records what was installed for scanner and saves the lockfile, so
+// an interrupted run keeps what it already installed.
+func (t *toolInstaller) record(scanner scanners.Scanner) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.lock.Record(scanner) {
+		return nil
+	}
+	return t.lock.Save(t.lockPath)
+}
+
+// confirm asks question on the terminal and reports whether it was answered
+// with yes. Without a terminal the answer is no.
+func confirm(question string) bool {
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// selectScanners returns the built-in scanners with the given names, or all
+// of them when names is empty. Names of tools that are only in the catalog
+// select a scanner that installs them from their catalog entry.
+func selectScanners(names []string) ([]scanners.Scanner, error) {
+	all := builtin.Scanners()
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	byName := make(map[string]scanners.Scanner, len(all))
+	for _, scanner := range all {
+		byName[scanner.GetConfig().Name] = scanner
+	}
+	selected := make([]scanners.Scanner, 0, len(names))
+	for _, name := range names {
+		scanner, ok := byName[name]
+		if !ok {
+			if _, found := scanners.DefaultCatalog().Entry(name); !found {
+				return nil, fmt.Errorf("unknown scanner: %s", name)
+			}
+			var err error
+			if scanner, err = scanners.DefaultCatalog().Scanner(name); err != nil {
+				return nil, err
+			}
+		}
+		selected = append(selected, scanner)
+	}
+	return selected, nil
+}
+
+func parsePlatforms(list string) ([]client.Platform, error) {
+	var platforms []client.Platform
+	for _, value := range splitList(list) {
+		osName, arch, found := strings.Cut(value, "/")
+		if !found || osName == "" || arch == "" {
+			return nil, fmt.Errorf("invalid platform %q: expected os/arch", value)
+		}
+		platforms = append(platforms, client.Platform{OS: osName, Arch: arch})
+	}
+	return platforms, nil
+}
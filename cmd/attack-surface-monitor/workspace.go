@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+const workspaceUsage = `Usage: attack-surface-monitor workspace <create|list|show|update|delete|export|import> [flags]
+
+Workspaces are read from disk when the server starts; stop the server
+before changing them here, or use the API while it is running.
+`
+
+func runWorkspace(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, workspaceUsage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("workspace "+args[0], flag.ExitOnError)
+	workspacesPath := flags.String("workspaces", defaultPath("workspaces"), "directory workspaces are stored in")
+
+	switch args[0] {
+	case "create":
+		name := flags.String("name", "", "name of the workspace")
+		description := flags.String("description", "", "description of the workspace")
+		include := flags.String("include", "", "comma-separated domains, IPs or CIDR ranges in scope (default everything)")
+		exclude := flags.String("exclude", "", "comma-separated domains, IPs or CIDR ranges out of scope")
+		targets := flags.String("targets", "", "comma-separated targets scanned by the workspace's schedules")
+		flags.Parse(args[1:])
+
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+		ws, err := manager.Create(workspace.Workspace{
+			Name:        *name,
+			Description: *description,
+			Scope: workspace.Scope{
+				Include: splitList(*include),
+				Exclude: splitList(*exclude),
+			},
+			Targets: splitList(*targets),
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created workspace %s\n", ws.Name)
+		return nil
+
+	case "list":
+		flags.Parse(args[1:])
+
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTARGETS\tSCHEDULES\tASSETS\tDESCRIPTION")
+		for _, ws := range manager.List() {
+			store, _ := manager.Inventory(ws.Name)
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", ws.Name, len(ws.Targets), len(ws.Schedules), store.Size(), ws.Description)
+		}
+		return w.Flush()
+
+	case "show":
+		name := flags.String("name", workspace.Default, "name of the workspace")
+		flags.Parse(args[1:])
+
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+		ws, exists := manager.Get(*name)
+		if !exists {
+			return fmt.Errorf("%w: %s", workspace.ErrNotFound, *name)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(ws)
+
+	case "update":
+		name := flags.String("name", "", "name of the workspace")
+		file := flags.String("file", "", "JSON file with the new configuration, as printed by \"workspace show\"")
+		flags.Parse(args[1:])
+
+		if *name == "" || *file == "" {
+			return fmt.Errorf("-name and -file are required")
+		}
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", *file, err)
+		}
+		var ws workspace.Workspace
+		if err := json.Unmarshal(data, &ws); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", *file, err)
+		}
+		ws.Name = *name
+
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+		if _, err := manager.Update(ws); err != nil {
+			return err
+		}
+
+		fmt.Printf("Updated workspace %s\n", *name)
+		return nil
+
+	case "delete":
+		name := flags.String("name", "", "name of the workspace")
+		flags.Parse(args[1:])
+
+		if *name == "" {
+			return fmt.Errorf("-name is required")
+		}
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+		if err := manager.Delete(*name); err != nil {
+			return err
+		}
+
+		fmt.Printf("Deleted workspace %s\n", *name)
+		return nil
+
+	case "export":
+		name := flags.String("name", workspace.Default, "name of the workspace")
+		output := flags.String("o", "", "file to write the export to (default stdout)")
+		flags.Parse(args[1:])
+
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			out, err = os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", *output, err)
+			}
+			defer out.Close()
+		}
+		return manager.Export(*name, out)
+
+	case "import":
+		file := flags.String("file", "", "export file to import")
+		name := flags.String("name", "", "import under this name instead of the exported one")
+		flags.Parse(args[1:])
+
+		if *file == "" {
+			return fmt.Errorf("-file is required")
+		}
+		in, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", *file, err)
+		}
+		defer in.Close()
+
+		manager, err := workspace.Open(*workspacesPath)
+		if err != nil {
+			return err
+		}
+		ws, err := manager.Import(in, *name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported workspace %s\n", ws.Name)
+		return nil
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown workspace command: %s\n\n%s", args[0], workspaceUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
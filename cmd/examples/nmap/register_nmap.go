@@ -21,8 +21,11 @@ func main() {
 		}
 
 		fmt.Println("Scan results:")
-		for _, line := range result {
-			fmt.Println(line)
+		for _, host := range result {
+			fmt.Println(host.Address(), host.Hostname())
+			for _, port := range host.Ports {
+				fmt.Printf("  %s/%s %s %s %s\n", port.PortID, port.Protocol, port.Service.Name, port.Service.Product, port.Service.Version)
+			}
 		}
 	}
 }
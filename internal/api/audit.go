@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/IxBahy/ASM/internal/audit"
+	"github.com/IxBahy/ASM/internal/auth"
+)
+
+// statusRecorder captures the status code written by a handler while still
+// supporting the flushing that server-sent events rely on.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// audited records every request in the audit trail together with the
+// identity that made it, as the authentication middleware in next found
+// it. Requests rejected by authentication are recorded under the identity
+// "unauthenticated".
+func (s *Server) audited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, slot := auth.WithIdentitySlot(r.Context())
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		identity := *slot
+		if identity.Name == "" && identity.TokenID == "" {
+			identity = auth.Identity{Name: "unauthenticated"}
+		}
+		s.record(identity, audit.Entry{
+			Action:   r.Pattern,
+			Resource: r.URL.Path,
+			Status:   recorder.status,
+		})
+	})
+}
+
+func (s *Server) record(identity auth.Identity, entry audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+	entry.Identity = identity.String()
+	entry.Role = string(identity.Role)
+	s.audit.Record(entry)
+}
+
+// identity returns the caller of an authenticated request.
+func identity(r *http.Request) auth.Identity {
+	if identity, ok := auth.IdentityFrom(r.Context()); ok {
+		return identity
+	}
+	return auth.Identity{Name: "unauthenticated"}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/IxBahy/ASM/internal/inventory"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+func (s *Server) handleListAssets(w http.ResponseWriter, r *http.Request, _ workspace.Workspace, store *inventory.Store) {
+	query := r.URL.Query()
+	filter := inventory.AssetFilter{
+		Type:         inventory.AssetType(query.Get("type")),
+		Target:       query.Get("target"),
+		Query:        query.Get("q"),
+		Organization: query.Get("organization"),
+	}
+
+	page, err := paginate(r, store.Assets(filter))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleGetAsset(w http.ResponseWriter, r *http.Request, _ workspace.Workspace, store *inventory.Store) {
+	asset, exists := store.Asset(r.PathValue("id"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "asset not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, asset)
+}
+
+func (s *Server) handleGetAssetDetail(w http.ResponseWriter, r *http.Request, _ workspace.Workspace, store *inventory.Store) {
+	detail, exists := store.Detail(r.PathValue("id"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "asset not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (s *Server) handleListOrganizations(w http.ResponseWriter, r *http.Request, _ workspace.Workspace, store *inventory.Store) {
+	page, err := paginate(r, store.Organizations())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleListFindings(w http.ResponseWriter, r *http.Request, _ workspace.Workspace, store *inventory.Store) {
+	query := r.URL.Query()
+	filter := inventory.FindingFilter{
+		Scanner: query.Get("scanner"),
+		Target:  query.Get("target"),
+	}
+	if severity := query.Get("severity"); severity != "" {
+		filter.Severity = inventory.ParseSeverity(severity)
+	}
+
+	page, err := paginate(r, store.Findings(filter))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleListHistory(w http.ResponseWriter, r *http.Request, _ workspace.Workspace, store *inventory.Store) {
+	page, err := paginate(r, store.History(r.URL.Query().Get("asset_id")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
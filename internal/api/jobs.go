@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/IxBahy/ASM/internal/audit"
+	"github.com/IxBahy/ASM/internal/auth"
+	"github.com/IxBahy/ASM/internal/inventory"
+	"github.com/IxBahy/ASM/internal/jobs"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+type scanRequest struct {
+	Scanner string          `json:"scanner"`
+	Target  string          `json:"target"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+type pipelineRequest struct {
+	Scanners []string                   `json:"scanners"`
+	Target   string                     `json:"target"`
+	Options  map[string]json.RawMessage `json:"options,omitempty"`
+}
+
+func (s *Server) handleCreateScan(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	var req scanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Scanner == "" {
+		writeError(w, http.StatusBadRequest, jobs.ErrNoScanners.Error())
+		return
+	}
+
+	var options map[string]json.RawMessage
+	if len(req.Options) > 0 {
+		options = map[string]json.RawMessage{req.Scanner: req.Options}
+	}
+	s.submit(w, r, ws, req.Target, options, req.Scanner)
+}
+
+func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	var req pipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	s.submit(w, r, ws, req.Target, req.Options, req.Scanners...)
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, target string, options map[string]json.RawMessage, scannerNames ...string) {
+	caller := identity(r)
+	if err := s.authorizeScanners(caller, scannerNames); err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if target != "" && !ws.Scope.Allows(target) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("target %s is outside the scope of workspace %s", target, ws.Name))
+		return
+	}
+
+	job, err := s.jobs.Submit(jobs.Request{
+		Workspace:   ws.Name,
+		Target:      target,
+		Scanners:    scannerNames,
+		Options:     options,
+		RequestedBy: caller.String(),
+	})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.record(caller, audit.Entry{
+		Action:   "job.submit",
+		Resource: job.ID,
+		Detail:   fmt.Sprintf("workspace=%s scanners=%s target=%s", ws.Name, strings.Join(job.Scanners, ","), job.Target),
+	})
+
+	w.Header().Set("Location", "/api/v1/workspaces/"+ws.Name+"/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// authorizeScanners checks that caller may run every named scanner:
+// intrusive scanners require the admin role.
+func (s *Server) authorizeScanners(caller auth.Identity, scannerNames []string) error {
+	for _, name := range scannerNames {
+		status, exists := s.registry.Status(name)
+		if !exists {
+			continue
+		}
+		if status.Config.Intrusive && !caller.Role.Allows(auth.RoleAdmin) {
+			return fmt.Errorf("%s is an intrusive scanner and requires the admin role", name)
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	status := jobs.Status(r.URL.Query().Get("status"))
+
+	items := []jobs.Job{}
+	for _, job := range s.jobs.List() {
+		if job.Workspace == ws.Name && (status == "" || job.Status == status) {
+			items = append(items, job)
+		}
+	}
+
+	page, err := paginate(r, items)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists || job.Workspace != ws.Name {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleJobEvents streams the job's events as server-sent events. The current
+// state of the job is sent first, followed by a "result" event for each
+// scanner that finishes and "status" events as the job progresses. The stream
+// ends when the job finishes.
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	job, events, cancel, exists := s.jobs.Subscribe(r.PathValue("id"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	defer cancel()
+	if job.Workspace != ws.Name {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent(w, jobs.Event{Type: jobs.EventStatus, Job: job})
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event jobs.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// Page is the envelope for every list response.
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// paginate slices items according to the limit and offset query parameters.
+func paginate[T any](r *http.Request, items []T) (Page[T], error) {
+	limit, err := queryInt(r, "limit", defaultPageLimit)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if limit < 1 || limit > maxPageLimit {
+		return Page[T]{}, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+	}
+
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if offset < 0 {
+		return Page[T]{}, fmt.Errorf("offset must not be negative")
+	}
+
+	page := Page[T]{
+		Items:  []T{},
+		Total:  len(items),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if offset < len(items) {
+		end := min(offset+limit, len(items))
+		page.Items = items[offset:end]
+	}
+	return page, nil
+}
+
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/IxBahy/ASM/internal/audit"
+	"github.com/IxBahy/ASM/internal/scanners"
+)
+
+type scannerResponse struct {
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	InstallationType string `json:"installation_type"`
+	Intrusive        bool   `json:"intrusive"`
+	Installed        bool   `json:"installed"`
+	InstalledVersion string `json:"installed_version,omitempty"`
+}
+
+func newScannerResponse(status scanners.ScannerStatus) scannerResponse {
+	return scannerResponse{
+		Name:             status.Config.Name,
+		Version:          status.Config.Version,
+		InstallationType: string(status.Config.InstallationType),
+		Intrusive:        status.Config.Intrusive,
+		Installed:        status.Installed,
+		InstalledVersion: status.State.Version,
+	}
+}
+
+func (s *Server) handleListScanners(w http.ResponseWriter, r *http.Request) {
+	items := []scannerResponse{}
+	for _, name := range s.registry.Names() {
+		if status, exists := s.registry.Status(name); exists {
+			items = append(items, newScannerResponse(status))
+		}
+	}
+
+	page, err := paginate(r, items)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleGetScanner(w http.ResponseWriter, r *http.Request) {
+	status, exists := s.registry.Status(r.PathValue("name"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "scanner not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, newScannerResponse(status))
+}
+
+// handleInstallScanner installs the scanner's tool and responds once the
+// installation has finished. Scans of the scanner that are running are
+// waited for, and queued ones wait for the installation.
+func (s *Server) handleInstallScanner(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, exists := s.registry.Status(name); !exists {
+		writeError(w, http.StatusNotFound, "scanner not found")
+		return
+	}
+
+	status, err := s.registry.Install(name)
+
+	entry := audit.Entry{Action: "scanner.install", Resource: name}
+	if err != nil {
+		entry.Detail = err.Error()
+	}
+	s.record(identity(r), entry)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to install %s: %v", name, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, newScannerResponse(status))
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/IxBahy/ASM/internal/audit"
+	"github.com/IxBahy/ASM/internal/auth"
+	"github.com/IxBahy/ASM/internal/inventory"
+	"github.com/IxBahy/ASM/internal/jobs"
+	"github.com/IxBahy/ASM/internal/scanners"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Config holds the components the API server exposes.
+type Config struct {
+	Registry   *scanners.ScannerRegistry
+	Jobs       *jobs.Manager
+	Workspaces *workspace.Manager
+	Auth       *auth.Authenticator
+	Audit      *audit.Logger
+}
+
+// Server exposes the scanner registry, workspaces and their scan jobs and
+// asset inventories over HTTP. The routes are described in openapi.yaml.
+type Server struct {
+	registry   *scanners.ScannerRegistry
+	jobs       *jobs.Manager
+	workspaces *workspace.Manager
+	auth       *auth.Authenticator
+	audit      *audit.Logger
+	mux        *http.ServeMux
+}
+
+func NewServer(config Config) *Server {
+	s := &Server{
+		registry:   config.Registry,
+		jobs:       config.Jobs,
+		workspaces: config.Workspaces,
+		auth:       config.Auth,
+		audit:      config.Audit,
+		mux:        http.NewServeMux(),
+	}
+	s.routes()
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/v1/openapi.yaml", s.handleOpenAPI)
+
+	s.handle("GET /api/v1/scanners", auth.RoleViewer, s.handleListScanners)
+	s.handle("GET /api/v1/scanners/{name}", auth.RoleViewer, s.handleGetScanner)
+	s.handle("POST /api/v1/scanners/{name}/install", auth.RoleAdmin, s.handleInstallScanner)
+
+	s.handle("GET /api/v1/workspaces", auth.RoleViewer, s.handleListWorkspaces)
+	s.handle("POST /api/v1/workspaces", auth.RoleAdmin, s.handleCreateWorkspace)
+	s.handle("POST /api/v1/workspaces/import", auth.RoleAdmin, s.handleImportWorkspace)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}", auth.RoleViewer, s.handleGetWorkspace)
+	s.handleWorkspace("PUT /api/v1/workspaces/{workspace}", auth.RoleAdmin, s.handleUpdateWorkspace)
+	s.handleWorkspace("DELETE /api/v1/workspaces/{workspace}", auth.RoleAdmin, s.handleDeleteWorkspace)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/export", auth.RoleViewer, s.handleExportWorkspace)
+
+	// Intrusive scanners additionally require admin; see authorizeScanners.
+	s.handleWorkspace("POST /api/v1/workspaces/{workspace}/scans", auth.RoleOperator, s.handleCreateScan)
+	s.handleWorkspace("POST /api/v1/workspaces/{workspace}/pipelines", auth.RoleOperator, s.handleCreatePipeline)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/jobs", auth.RoleViewer, s.handleListJobs)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/jobs/{id}", auth.RoleViewer, s.handleGetJob)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/jobs/{id}/events", auth.RoleViewer, s.handleJobEvents)
+
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/assets", auth.RoleViewer, s.handleListAssets)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/assets/{id}", auth.RoleViewer, s.handleGetAsset)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/assets/{id}/detail", auth.RoleViewer, s.handleGetAssetDetail)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/organizations", auth.RoleViewer, s.handleListOrganizations)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/findings", auth.RoleViewer, s.handleListFindings)
+	s.handleWorkspace("GET /api/v1/workspaces/{workspace}/history", auth.RoleViewer, s.handleListHistory)
+}
+
+// handle registers handler for pattern behind authentication requiring role,
+// recording every request in the audit trail.
+func (s *Server) handle(pattern string, role auth.Role, handler http.HandlerFunc) {
+	s.mux.Handle(pattern, s.audited(s.auth.Require(role, handler)))
+}
+
+// workspaceHandler handles a request within the workspace named in its path.
+type workspaceHandler func(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, store *inventory.Store)
+
+// handleWorkspace registers a route under /api/v1/workspaces/{workspace}. The
+// handler only runs if the workspace exists and the caller's token may
+// access it.
+func (s *Server) handleWorkspace(pattern string, role auth.Role, handler workspaceHandler) {
+	s.handle(pattern, role, func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("workspace")
+		if !identity(r).CanAccess(name) {
+			writeError(w, http.StatusForbidden, "this token may not access workspace "+name)
+			return
+		}
+		ws, exists := s.workspaces.Get(name)
+		if !exists {
+			writeError(w, http.StatusNotFound, workspace.ErrNotFound.Error())
+			return
+		}
+		store, _ := s.workspaces.Inventory(name)
+		handler(w, r, ws, store)
+	})
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/IxBahy/ASM/internal/audit"
+	"github.com/IxBahy/ASM/internal/inventory"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+func (s *Server) handleListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	caller := identity(r)
+
+	items := []workspace.Workspace{}
+	for _, ws := range s.workspaces.List() {
+		if caller.CanAccess(ws.Name) {
+			items = append(items, ws)
+		}
+	}
+
+	page, err := paginate(r, items)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	caller := identity(r)
+	if len(caller.Workspaces) > 0 {
+		writeError(w, http.StatusForbidden, "tokens restricted to workspaces may not create workspaces")
+		return
+	}
+
+	var req workspace.Workspace
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	ws, err := s.workspaces.Create(req)
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+
+	s.record(caller, audit.Entry{Action: "workspace.create", Resource: ws.Name})
+	w.Header().Set("Location", "/api/v1/workspaces/"+ws.Name)
+	writeJSON(w, http.StatusCreated, ws)
+}
+
+func (s *Server) handleGetWorkspace(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	writeJSON(w, http.StatusOK, ws)
+}
+
+// handleUpdateWorkspace replaces the workspace configuration. The name in
+// the path always wins over one in the body.
+func (s *Server) handleUpdateWorkspace(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	var req workspace.Workspace
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	req.Name = ws.Name
+
+	updated, err := s.workspaces.Update(req)
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+
+	s.record(identity(r), audit.Entry{Action: "workspace.update", Resource: ws.Name})
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) handleDeleteWorkspace(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	if err := s.workspaces.Delete(ws.Name); err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+
+	s.record(identity(r), audit.Entry{Action: "workspace.delete", Resource: ws.Name})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleExportWorkspace(w http.ResponseWriter, r *http.Request, ws workspace.Workspace, _ *inventory.Store) {
+	s.record(identity(r), audit.Entry{Action: "workspace.export", Resource: ws.Name})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ws.Name+".json"))
+	if err := s.workspaces.Export(ws.Name, w); err != nil {
+		writeWorkspaceError(w, err)
+	}
+}
+
+// handleImportWorkspace creates a workspace from an export. The optional
+// "name" query parameter imports it under a different name.
+func (s *Server) handleImportWorkspace(w http.ResponseWriter, r *http.Request) {
+	caller := identity(r)
+	if len(caller.Workspaces) > 0 {
+		writeError(w, http.StatusForbidden, "tokens restricted to workspaces may not import workspaces")
+		return
+	}
+
+	ws, err := s.workspaces.Import(r.Body, r.URL.Query().Get("name"))
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+
+	s.record(caller, audit.Entry{Action: "workspace.import", Resource: ws.Name})
+	w.Header().Set("Location", "/api/v1/workspaces/"+ws.Name)
+	writeJSON(w, http.StatusCreated, ws)
+}
+
+func writeWorkspaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, workspace.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, workspace.ErrAlreadyExists):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is one record in the audit trail.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Identity string    `json:"identity"`
+	Role     string    `json:"role,omitempty"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource,omitempty"`
+	Status   int       `json:"status,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// Logger appends audit entries as JSON lines.
+type Logger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Open returns a Logger that appends to the file at path.
+func Open(path string) (*Logger, io.Closer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return NewLogger(file), file, nil
+}
+
+func (l *Logger) Record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.w.Write(append(data, '\n'))
+}
@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Role string
+
+const (
+	// RoleViewer can read the scanner registry, jobs, inventory and findings.
+	RoleViewer Role = "viewer"
+	// RoleOperator can also run passive scanners.
+	RoleOperator Role = "operator"
+	// RoleAdmin can also run intrusive scanners and install tools.
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+var ErrTokenNotFound = errors.New("token not found")
+
+func ParseRole(value string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q: must be viewer, operator or admin", value)
+	}
+	return role, nil
+}
+
+// Allows reports whether r grants at least the permissions of required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// Token is an API token as stored at rest. Only the SHA-256 hash of the
+// secret is kept; the secret itself is shown once when the token is created.
+type Token struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// Workspaces limits the token to the named workspaces. An empty list
+	// grants access to every workspace.
+	Workspaces []string  `json:"workspaces,omitempty"`
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	TokenID    string   `json:"token_id"`
+	Name       string   `json:"name"`
+	Role       Role     `json:"role"`
+	Workspaces []string `json:"workspaces,omitempty"`
+}
+
+// CanAccess reports whether the identity may use the named workspace.
+func (i Identity) CanAccess(workspace string) bool {
+	if len(i.Workspaces) == 0 {
+		return true
+	}
+	for _, name := range i.Workspaces {
+		if name == workspace {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the identity in the form recorded in the audit trail.
+func (i Identity) String() string {
+	if i.TokenID == "" {
+		return i.Name
+	}
+	return fmt.Sprintf("%s (%s)", i.Name, i.TokenID)
+}
+
+// Anonymous is the identity of every request when authentication is disabled.
+var Anonymous = Identity{Name: "anonymous", Role: RoleAdmin}
+
+type contextKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// IdentityFrom returns the identity stored in ctx by the authentication
+// middleware.
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
+
+type slotKey struct{}
+
+// WithIdentitySlot returns a copy of ctx in which the authentication
+// middleware records the identity of the request, and the identity it
+// records. It is for handlers wrapping the middleware, which do not see the
+// context the middleware passes on; the identity stays empty for requests
+// without a valid token.
+func WithIdentitySlot(ctx context.Context) (context.Context, *Identity) {
+	slot := new(Identity)
+	return context.WithValue(ctx, slotKey{}, slot), slot
+}
+
+// recordIdentity stores identity in the slot of ctx, if it has one.
+func recordIdentity(ctx context.Context, identity Identity) {
+	if slot, ok := ctx.Value(slotKey{}).(*Identity); ok {
+		*slot = identity
+	}
+}
+
+// Store keeps API tokens in a JSON file. The file is read again whenever it
+// changes, so tokens created or revoked by another process, such as the
+// token command next to a running server, take effect at once.
+type Store struct {
+	path   string
+	mu     sync.RWMutex
+	tokens []Token
+	// file is the version of the file tokens were read from, or nil if
+	// there was none.
+	file os.FileInfo
+}
+
+// OpenStore loads the tokens in path. A missing file is treated as an
+// empty store and is created on the first write.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the tokens from the file, unless it is the version they were
+// read from already. The caller must hold the write lock.
+func (s *Store) load() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.tokens, s.file = nil, nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read token store: %w", err)
+	}
+	if !changed(s.file, info) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read token store: %w", err)
+	}
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("failed to parse token store %s: %w", s.path, err)
+	}
+	s.tokens, s.file = tokens, info
+	return nil
+}
+
+// refresh reloads the tokens if the file changed since they were read. If
+// it cannot be read, every token is rejected until it is fixed, rather
+// than accepting tokens that may have been revoked.
+func (s *Store) refresh() {
+	s.mu.RLock()
+	info, err := os.Stat(s.path)
+	stale := (err == nil && changed(s.file, info)) || (err != nil && s.file != nil)
+	s.mu.RUnlock()
+	if !stale {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		log.Printf("WARNING: %v; rejecting every token until it is fixed", err)
+		s.tokens, s.file = nil, nil
+	}
+}
+
+// changed reports whether info describes another version of the file than
+// previous. The store replaces the file on every write, so a new file is a
+// new version even if its size and time match.
+func changed(previous, info os.FileInfo) bool {
+	return previous == nil || !os.SameFile(previous, info) ||
+		!previous.ModTime().Equal(info.ModTime()) || previous.Size() != info.Size()
+}
+
+// Create generates a new token and returns its secret, which is not stored.
+// A token with no workspaces may access every workspace.
+func (s *Store) Create(name string, role Role, workspaces []string) (string, Token, error) {
+	if _, ok := roleRank[role]; !ok {
+		return "", Token{}, fmt.Errorf("unknown role: %s", role)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", Token{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := "asm_" + base64.RawURLEncoding.EncodeToString(raw)
+	hash := hashSecret(secret)
+
+	token := Token{
+		ID:         hash[:12],
+		Name:       name,
+		Role:       role,
+		Workspaces: workspaces,
+		Hash:       hash,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", Token{}, err
+	}
+	s.tokens = append(s.tokens, token)
+	if err := s.save(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return "", Token{}, err
+	}
+	return secret, token, nil
+}
+
+// Revoke deletes the token with the given id.
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i, token := range s.tokens {
+		if token.ID == id {
+			previous := s.tokens
+			s.tokens = append(append([]Token{}, s.tokens[:i]...), s.tokens[i+1:]...)
+			if err := s.save(); err != nil {
+				s.tokens = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return ErrTokenNotFound
+}
+
+// List returns all tokens ordered by creation time.
+func (s *Store) List() []Token {
+	s.refresh()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := append([]Token{}, s.tokens...)
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+func (s *Store) Len() int {
+	s.refresh()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.tokens)
+}
+
+// Authenticate returns the identity of the token whose secret is given.
+func (s *Store) Authenticate(secret string) (Identity, bool) {
+	hash := []byte(hashSecret(secret))
+	s.refresh()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(token.Hash)) == 1 {
+			return Identity{TokenID: token.ID, Name: token.Name, Role: token.Role, Workspaces: token.Workspaces}, true
+		}
+	}
+	return Identity{}, false
+}
+
+// save writes the tokens to a temporary file and renames it into place so a
+// failed write never truncates the store. The caller must hold the lock.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode token store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token store directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tokens-*")
+	if err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	s.file = info
+	return nil
+}
+
+// hashSecret returns the hex SHA-256 of a token secret. Secrets are 256-bit
+// random values, so an unsalted hash is enough to keep them safe at rest.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
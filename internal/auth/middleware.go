@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Authenticator resolves the bearer token of each request to an Identity.
+type Authenticator struct {
+	store    *Store
+	disabled bool
+}
+
+func NewAuthenticator(store *Store) *Authenticator {
+	return &Authenticator{store: store}
+}
+
+// Disabled returns an Authenticator that treats every request as Anonymous.
+func Disabled() *Authenticator {
+	return &Authenticator{disabled: true}
+}
+
+// Identify returns the identity of the request's bearer token.
+func (a *Authenticator) Identify(r *http.Request) (Identity, bool) {
+	if a.disabled {
+		return Anonymous, true
+	}
+
+	scheme, secret, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return Identity{}, false
+	}
+	return a.store.Authenticate(strings.TrimSpace(secret))
+}
+
+// Require wraps next so that it only runs for requests whose identity has at
+// least the given role. The identity is added to the request context, and
+// recorded in its identity slot even if the role does not allow the request.
+func (a *Authenticator) Require(role Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := a.Identify(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="asm"`)
+			writeError(w, http.StatusUnauthorized, "a valid API token is required")
+			return
+		}
+		recordIdentity(r.Context(), identity)
+		if !identity.Role.Allows(role) {
+			writeError(w, http.StatusForbidden, "this action requires the "+string(role)+" role")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,21 @@
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Handler serves the dashboard's static files under prefix. The dashboard
+// is a single page that reads everything from the /api/v1 endpoints, so it
+// needs no assets from outside the binary.
+func Handler(prefix string) http.Handler {
+	root, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(prefix, http.FileServer(http.FS(root)))
+}
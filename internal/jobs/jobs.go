@@ -0,0 +1,511 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/scanners"
+)
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrNoScanners  = errors.New("at least one scanner is required")
+	ErrEmptyTarget = errors.New("target is required")
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job is a scan of a single target by one scanner, or by several scanners
+// run in order as a pipeline.
+type Job struct {
+	ID        string   `json:"id"`
+	Workspace string   `json:"workspace"`
+	Target    string   `json:"target"`
+	Scanners  []string `json:"scanners"`
+	// Options holds the options of the scanners that were given some, by
+	// scanner name.
+	Options     map[string]json.RawMessage `json:"options,omitempty"`
+	RequestedBy string                     `json:"requested_by,omitempty"`
+	Status      Status                     `json:"status"`
+	Results     []Result                   `json:"results"`
+	Error       string                     `json:"error,omitempty"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	StartedAt   time.Time                  `json:"started_at"`
+	FinishedAt  time.Time                  `json:"finished_at"`
+}
+
+// Request describes a job to submit.
+type Request struct {
+	// Workspace is the workspace whose inventory receives the results.
+	Workspace string
+	Target    string
+	Scanners  []string
+	// Options are passed to the scanners, by name, as JSON objects. Only
+	// scanners that implement scanners.OptionsScanner take options.
+	Options map[string]json.RawMessage
+	// RequestedBy identifies who submitted the job, for the audit trail.
+	RequestedBy string
+}
+
+// ErrorClass groups scanner failures by cause.
+type ErrorClass string
+
+const (
+	ErrorClassNotInstalled ErrorClass = "not_installed"
+	ErrorClassTimeout      ErrorClass = "timeout"
+	ErrorClassExitStatus   ErrorClass = "exit_status"
+	ErrorClassOther        ErrorClass = "other"
+)
+
+// Result holds the output of one scanner within a job.
+type Result struct {
+	Scanner    string     `json:"scanner"`
+	Data       []string   `json:"data"`
+	Errors     []string   `json:"errors"`
+	Error      string     `json:"error,omitempty"`
+	ErrorClass ErrorClass `json:"error_class,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	FinishedAt time.Time  `json:"finished_at"`
+}
+
+// Done reports whether the job has finished running.
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+type EventType string
+
+const (
+	EventStatus EventType = "status"
+	EventResult EventType = "result"
+)
+
+// Event is published whenever a job changes status or a scanner in the job
+// produces a result.
+type Event struct {
+	Type   EventType `json:"type"`
+	Job    Job       `json:"job"`
+	Result *Result   `json:"result,omitempty"`
+}
+
+// DefaultRetention is how many finished jobs a Manager keeps by default.
+const DefaultRetention = 1000
+
+// Manager queues jobs and runs them on a fixed pool of workers using the
+// scanners in the registry.
+type Manager struct {
+	registry *scanners.ScannerRegistry
+	queue    chan string
+	inFlight atomic.Int64
+
+	mu          sync.RWMutex
+	jobs        map[string]*Job
+	order       []string
+	retention   int
+	subscribers map[string]map[chan Event]struct{}
+	listeners   []func(Event)
+}
+
+func NewManager(registry *scanners.ScannerRegistry, queueSize int) *Manager {
+	return &Manager{
+		registry:    registry,
+		queue:       make(chan string, queueSize),
+		jobs:        make(map[string]*Job),
+		retention:   DefaultRetention,
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// SetRetention sets how many finished jobs are kept. Once more have
+// finished, the oldest are forgotten; queued and running jobs are always
+// kept.
+func (m *Manager) SetRetention(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retention = max(n, 0)
+	m.prune()
+}
+
+// AddListener registers fn to be called for every event of every job.
+// Listeners run on the worker goroutine and should not block.
+func (m *Manager) AddListener(fn func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listeners = append(m.listeners, fn)
+}
+
+// Start launches workers that run queued jobs until ctx is cancelled.
+func (m *Manager) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go m.work(ctx)
+	}
+}
+
+// Submit queues a job that runs the requested scanners against the target
+// in order.
+func (m *Manager) Submit(req Request) (Job, error) {
+	target := strings.TrimSpace(req.Target)
+	if target == "" {
+		return Job{}, ErrEmptyTarget
+	}
+	if len(req.Scanners) == 0 {
+		return Job{}, ErrNoScanners
+	}
+	for _, name := range req.Scanners {
+		if _, exists := m.registry.Get(name); !exists {
+			return Job{}, fmt.Errorf("unknown scanner: %s", name)
+		}
+	}
+	var options map[string]json.RawMessage
+	for name, scannerOptions := range req.Options {
+		scanner, exists := m.registry.Get(name)
+		if !exists || !slices.Contains(req.Scanners, name) {
+			return Job{}, fmt.Errorf("options given for %s, which the job does not run", name)
+		}
+		if err := scanners.ValidateOptions(scanner, scannerOptions); err != nil {
+			return Job{}, err
+		}
+		if len(scannerOptions) > 0 {
+			if options == nil {
+				options = make(map[string]json.RawMessage)
+			}
+			options[name] = scannerOptions
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	job := &Job{
+		ID:          id,
+		Workspace:   req.Workspace,
+		Target:      target,
+		Scanners:    append([]string(nil), req.Scanners...),
+		Options:     options,
+		RequestedBy: req.RequestedBy,
+		Status:      StatusQueued,
+		Results:     []Result{},
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	select {
+	case m.queue <- id:
+	default:
+		m.mu.Unlock()
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[id] = job
+	m.order = append(m.order, id)
+	snapshot := job.clone()
+	m.mu.Unlock()
+
+	m.publish(Event{Type: EventStatus, Job: snapshot})
+	return snapshot, nil
+}
+
+// Get returns a copy of the job with the given id.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, false
+	}
+	return job.clone(), true
+}
+
+// List returns copies of all jobs, most recently created first.
+func (m *Manager) List() []Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]Job, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		result = append(result, m.jobs[m.order[i]].clone())
+	}
+	return result
+}
+
+// QueueDepth returns the number of jobs waiting for a worker.
+func (m *Manager) QueueDepth() int {
+	return len(m.queue)
+}
+
+// InFlight returns the number of jobs currently running.
+func (m *Manager) InFlight() int {
+	return int(m.inFlight.Load())
+}
+
+// Subscribe returns the current state of the job and a channel that receives
+// its subsequent events. The channel is closed after the job's final status
+// event, or when the returned cancel function is called.
+func (m *Manager) Subscribe(id string) (Job, <-chan Event, func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, nil, nil, false
+	}
+
+	ch := make(chan Event, 64)
+	if job.Done() {
+		close(ch)
+		return job.clone(), ch, func() {}, true
+	}
+
+	if m.subscribers[id] == nil {
+		m.subscribers[id] = make(map[chan Event]struct{})
+	}
+	m.subscribers[id][ch] = struct{}{}
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.subscribers[id][ch]; ok {
+			delete(m.subscribers[id], ch)
+			close(ch)
+		}
+	}
+	return job.clone(), ch, cancel, true
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(id)
+		}
+	}
+}
+
+func (m *Manager) run(id string) {
+	m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+
+	m.update(id, func(job *Job) {
+		job.Status = StatusRunning
+		job.StartedAt = time.Now().UTC()
+	}, nil)
+
+	job, _ := m.Get(id)
+
+	var failures []string
+	for _, name := range job.Scanners {
+		result := m.runScanner(name, job.Target, job.Options[name])
+		if result.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", name, result.Error))
+		}
+
+		m.update(id, func(job *Job) {
+			job.Results = append(job.Results, result)
+		}, &result)
+	}
+
+	m.finish(id, failures)
+}
+
+// finish marks the job as done and sends its final state to every
+// subscriber, closing their channels. A subscriber whose buffer is full
+// loses its oldest pending event instead, so it always learns how the job
+// ended.
+func (m *Manager) finish(id string, failures []string) {
+	m.mu.Lock()
+	job, exists := m.jobs[id]
+	if !exists {
+		m.mu.Unlock()
+		return
+	}
+	job.FinishedAt = time.Now().UTC()
+	if len(failures) > 0 {
+		job.Status = StatusFailed
+		job.Error = strings.Join(failures, "; ")
+	} else {
+		job.Status = StatusSucceeded
+	}
+	event := Event{Type: EventStatus, Job: job.clone()}
+	subscribers := m.subscribers[id]
+	delete(m.subscribers, id)
+	listeners := append([]func(Event){}, m.listeners...)
+	m.prune()
+	m.mu.Unlock()
+
+	// The channels were removed from the subscribers under the lock, so
+	// nothing else sends on or closes them now.
+	for ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- event
+		}
+		close(ch)
+	}
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// prune forgets the oldest finished jobs beyond the retention. It must be
+// called with m.mu held.
+func (m *Manager) prune() {
+	finished := 0
+	for _, id := range m.order {
+		if m.jobs[id].Done() {
+			finished++
+		}
+	}
+	if finished <= m.retention {
+		return
+	}
+
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if finished > m.retention && m.jobs[id].Done() {
+			delete(m.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	clear(m.order[len(kept):])
+	m.order = kept
+}
+
+func (m *Manager) runScanner(name, target string, options json.RawMessage) Result {
+	start := time.Now()
+	result := Result{Scanner: name}
+
+	scanner, installed, release := m.registry.Acquire(name)
+	defer release()
+	if scanner == nil {
+		result.Error = fmt.Sprintf("unknown scanner: %s", name)
+		result.ErrorClass = ErrorClassOther
+	} else if !installed {
+		result.Error = fmt.Sprintf("%s is not installed", name)
+		result.ErrorClass = ErrorClassNotInstalled
+	} else {
+		scanResult, err := scanners.RunScanWithOptions(scanner, target, options)
+		result.Data = scanResult.Data
+		result.Errors = scanResult.Errors
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorClass = classifyError(err)
+		}
+	}
+
+	if result.Data == nil {
+		result.Data = []string{}
+	}
+	if result.Errors == nil {
+		result.Errors = []string{}
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.FinishedAt = time.Now().UTC()
+	return result
+}
+
+// classifyError maps a scanner error to its ErrorClass. Processes that were
+// killed, either by a context deadline or by SIGKILL, count as timeouts.
+func classifyError(err error) ErrorClass {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGKILL {
+			return ErrorClassTimeout
+		}
+		return ErrorClassExitStatus
+	}
+
+	return ErrorClassOther
+}
+
+// update applies fn to the job under lock and publishes the resulting event.
+func (m *Manager) update(id string, fn func(*Job), result *Result) {
+	m.mu.Lock()
+	job, exists := m.jobs[id]
+	if !exists {
+		m.mu.Unlock()
+		return
+	}
+	fn(job)
+	snapshot := job.clone()
+	m.mu.Unlock()
+
+	event := Event{Type: EventStatus, Job: snapshot}
+	if result != nil {
+		event.Type = EventResult
+		event.Result = result
+	}
+	m.publish(event)
+}
+
+func (m *Manager) publish(event Event) {
+	m.mu.RLock()
+	listeners := append([]func(Event){}, m.listeners...)
+	for ch := range m.subscribers[event.Job.ID] {
+		select {
+		case ch <- event:
+		default:
+			// Slow subscribers miss intermediate events rather than
+			// stalling the worker; finish always delivers the final
+			// state.
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func (j *Job) clone() Job {
+	c := *j
+	c.Scanners = append([]string(nil), j.Scanners...)
+	c.Results = append([]Result{}, j.Results...)
+	return c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a manager holding jobs with the given statuses,
+// oldest first, without a registry or workers.
+func newTestManager(statuses ...Status) *Manager {
+	m := NewManager(nil, 1)
+	for i, status := range statuses {
+		id := string(rune('a' + i))
+		m.jobs[id] = &Job{ID: id, Status: status}
+		m.order = append(m.order, id)
+	}
+	return m
+}
+
+func TestSetRetentionKeepsUnfinishedJobs(t *testing.T) {
+	m := newTestManager(StatusSucceeded, StatusRunning, StatusFailed, StatusQueued, StatusSucceeded)
+	m.SetRetention(1)
+
+	var ids []string
+	for _, job := range m.List() {
+		ids = append(ids, job.ID)
+	}
+	if want := []string{"e", "d", "b"}; !slices.Equal(ids, want) {
+		t.Errorf("List() after SetRetention(1) = %q, want %q", ids, want)
+	}
+	if _, exists := m.Get("a"); exists {
+		t.Error("the oldest finished job is still kept")
+	}
+}
+
+func TestFinishPrunesFinishedJobs(t *testing.T) {
+	m := newTestManager(StatusSucceeded, StatusRunning)
+	m.SetRetention(1)
+	m.finish("b", nil)
+
+	if _, exists := m.Get("a"); exists {
+		t.Error("job a is kept beyond the retention")
+	}
+	if job, exists := m.Get("b"); !exists || job.Status != StatusSucceeded {
+		t.Errorf("Get(b) = %+v, %v, want the succeeded job", job, exists)
+	}
+}
+
+func TestFinishDeliversFinalEventToSlowSubscriber(t *testing.T) {
+	m := newTestManager(StatusRunning)
+	_, events, cancel, _ := m.Subscribe("a")
+	defer cancel()
+
+	// Fill the subscriber's buffer without reading it.
+	for i := 0; i < cap(events)+10; i++ {
+		m.update("a", func(job *Job) {}, nil)
+	}
+	m.finish("a", []string{"nmap: failed"})
+
+	var last Event
+	count := 0
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				if count != cap(events) {
+					t.Errorf("received %d events, want %d", count, cap(events))
+				}
+				if last.Job.Status != StatusFailed || last.Job.Error != "nmap: failed" {
+					t.Errorf("last event = %+v, want the failed job", last.Job)
+				}
+				return
+			}
+			last = event
+			count++
+		case <-timeout:
+			t.Fatal("the subscriber channel was not closed")
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/jobs"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the scanner duration
+// histogram. Scans range from sub-second DNS lookups to hour-long crawls.
+var durationBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range durationBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type gauge struct {
+	name  string
+	help  string
+	value func() float64
+}
+
+type failureKey struct {
+	scanner string
+	class   jobs.ErrorClass
+}
+
+// Collector records per-scanner run metrics from job events and exposes
+// them, together with any registered gauges, in the Prometheus text format.
+type Collector struct {
+	mu        sync.Mutex
+	runs      map[string]uint64
+	failures  map[failureKey]uint64
+	results   map[string]uint64
+	killed    map[string]uint64
+	durations map[string]*histogram
+	gauges    []gauge
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		runs:      make(map[string]uint64),
+		failures:  make(map[failureKey]uint64),
+		results:   make(map[string]uint64),
+		killed:    make(map[string]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+// RegisterScanners initializes the per-scanner series to zero so that every
+// scanner is exported before it first runs.
+func (c *Collector) RegisterScanners(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		c.ensure(name)
+	}
+}
+
+// AddGauge exports the value returned by fn under name on every scrape.
+func (c *Collector) AddGauge(name, help string, fn func() float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gauges = append(c.gauges, gauge{name: name, help: help, value: fn})
+}
+
+// ObserveEvent records the outcome of a scanner run. It is meant to be
+// registered with jobs.Manager.AddListener.
+func (c *Collector) ObserveEvent(event jobs.Event) {
+	if event.Type != jobs.EventResult || event.Result == nil {
+		return
+	}
+	result := event.Result
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ensure(result.Scanner)
+	c.runs[result.Scanner]++
+	c.results[result.Scanner] += uint64(len(result.Data))
+	c.durations[result.Scanner].observe((time.Duration(result.DurationMs) * time.Millisecond).Seconds())
+
+	if result.Error != "" {
+		class := result.ErrorClass
+		if class == "" {
+			class = jobs.ErrorClassOther
+		}
+		c.failures[failureKey{result.Scanner, class}]++
+		if class == jobs.ErrorClassTimeout {
+			c.killed[result.Scanner]++
+		}
+	}
+}
+
+func (c *Collector) ensure(name string) {
+	if _, exists := c.durations[name]; exists {
+		return
+	}
+	c.runs[name] = 0
+	c.results[name] = 0
+	c.killed[name] = 0
+	c.durations[name] = &histogram{counts: make([]uint64, len(durationBuckets))}
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	gauges := append([]gauge(nil), c.gauges...)
+	var b strings.Builder
+
+	names := make([]string, 0, len(c.durations))
+	for name := range c.durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(&b, "asm_scanner_runs_total", "counter", "Number of scanner runs.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "asm_scanner_runs_total{scanner=%s} %d\n", quote(name), c.runs[name])
+	}
+
+	writeHeader(&b, "asm_scanner_failures_total", "counter", "Number of failed scanner runs by error class.")
+	failureKeys := make([]failureKey, 0, len(c.failures))
+	for key := range c.failures {
+		failureKeys = append(failureKeys, key)
+	}
+	sort.Slice(failureKeys, func(i, j int) bool {
+		if failureKeys[i].scanner != failureKeys[j].scanner {
+			return failureKeys[i].scanner < failureKeys[j].scanner
+		}
+		return failureKeys[i].class < failureKeys[j].class
+	})
+	for _, key := range failureKeys {
+		fmt.Fprintf(&b, "asm_scanner_failures_total{scanner=%s,class=%s} %d\n", quote(key.scanner), quote(string(key.class)), c.failures[key])
+	}
+
+	writeHeader(&b, "asm_scanner_results_total", "counter", "Number of result entries emitted by scanners.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "asm_scanner_results_total{scanner=%s} %d\n", quote(name), c.results[name])
+	}
+
+	writeHeader(&b, "asm_scanner_timeout_kills_total", "counter", "Number of scanner processes killed after running out of time.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "asm_scanner_timeout_kills_total{scanner=%s} %d\n", quote(name), c.killed[name])
+	}
+
+	writeHeader(&b, "asm_scanner_duration_seconds", "histogram", "Duration of scanner runs.")
+	for _, name := range names {
+		h := c.durations[name]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(&b, "asm_scanner_duration_seconds_bucket{scanner=%s,le=%s} %d\n", quote(name), quote(formatFloat(bound)), h.counts[i])
+		}
+		fmt.Fprintf(&b, "asm_scanner_duration_seconds_bucket{scanner=%s,le=\"+Inf\"} %d\n", quote(name), h.count)
+		fmt.Fprintf(&b, "asm_scanner_duration_seconds_sum{scanner=%s} %s\n", quote(name), formatFloat(h.sum))
+		fmt.Fprintf(&b, "asm_scanner_duration_seconds_count{scanner=%s} %d\n", quote(name), h.count)
+	}
+	c.mu.Unlock()
+
+	// Gauges are evaluated outside the lock since they call into other
+	// components, such as the registry, that may be slow.
+	for _, g := range gauges {
+		writeHeader(&b, g.name, "gauge", g.help)
+		fmt.Fprintf(&b, "%s %s\n", g.name, formatFloat(g.value()))
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeHeader(b *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// quote escapes a label value as required by the text exposition format.
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
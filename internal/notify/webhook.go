@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/inventory"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+// Payload is the JSON body POSTed to a webhook for each new finding.
+type Payload struct {
+	Workspace string             `json:"workspace"`
+	FindingID string             `json:"finding_id"`
+	Severity  inventory.Severity `json:"severity"`
+	Title     string             `json:"title"`
+	Target    string             `json:"target"`
+	Time      time.Time          `json:"time"`
+}
+
+type notification struct {
+	workspace string
+	change    inventory.Change
+}
+
+// Notifier sends new findings to the webhooks configured in their
+// workspace. Deliveries happen on a single background goroutine so that
+// inventory updates never wait on a slow webhook.
+type Notifier struct {
+	workspaces *workspace.Manager
+	client     *http.Client
+	queue      chan notification
+}
+
+// NewNotifier returns a Notifier watching every inventory in workspaces.
+// Call Run to start delivering.
+func NewNotifier(workspaces *workspace.Manager) *Notifier {
+	n := &Notifier{
+		workspaces: workspaces,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan notification, 256),
+	}
+	workspaces.OnInventoryChange(n.enqueue)
+	return n
+}
+
+// Run delivers queued notifications until ctx is cancelled.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-n.queue:
+			n.deliver(ctx, item)
+		}
+	}
+}
+
+// enqueue is called with the inventory locked, so it only queues the change
+// and drops it if the queue is full.
+func (n *Notifier) enqueue(workspaceName string, change inventory.Change) {
+	if change.Kind != inventory.ChangeFindingAdded {
+		return
+	}
+	select {
+	case n.queue <- notification{workspace: workspaceName, change: change}:
+	default:
+		log.Printf("notify: queue full, dropping notification for finding %s", change.FindingID)
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, item notification) {
+	ws, exists := n.workspaces.Get(item.workspace)
+	if !exists {
+		return
+	}
+
+	payload := Payload{
+		Workspace: ws.Name,
+		FindingID: item.change.FindingID,
+		Severity:  inventory.Severity(item.change.Type),
+		Title:     item.change.Value,
+		Target:    item.change.Detail,
+		Time:      item.change.Time,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("notify: failed to encode notification: %v", err)
+		return
+	}
+
+	for _, webhook := range ws.Notifications.Webhooks {
+		if webhook.MinSeverity != "" && !payload.Severity.AtLeast(webhook.MinSeverity) {
+			continue
+		}
+		if err := n.post(ctx, webhook.URL, body); err != nil {
+			log.Printf("notify: workspace %s: %v", ws.Name, err)
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", url, resp.Status)
+	}
+	return nil
+}
@@ -1,9 +1,10 @@
 package aiodnsbrute
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -25,9 +26,12 @@ type DNSBruteResult struct {
 
 func NewAioDNSBruteScanner() *AioDNSBruteScanner {
 	config := scanners.ScannerConfig{
-		Name:             "aiodnsbrute",
-		Version:          "latest",
-		ExecutablePath:   "/usr/local/bin/aiodnsbrute",
+		Name:    "aiodnsbrute",
+		Version: "latest",
+		PythonOptions: scanners.PythonOptions{
+			Package: "aiodnsbrute",
+		},
+		ExecutablePath:   client.BinPath("aiodnsbrute"),
 		Base_Command:     "aiodnsbrute",
 		InstallationType: client.InstallationTypePython,
 	}
@@ -49,14 +53,14 @@ func NewAioDNSBruteScanner() *AioDNSBruteScanner {
 
 func (s *AioDNSBruteScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
 	//
-	installArgs := []string{"aiodnsbrute", s.Config.Version}
+	installArgs := s.Config.PythonOptions.InstallArgs(s.Config.Version)
 
 	var err error
-	s.installClient, err = client.ClientFactory(s.Config.InstallationType, installArgs, 5)
+	s.installClient, err = s.NewInstaller(s.Config.InstallationType, installArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client: %w", err)
 	}
@@ -65,7 +69,7 @@ func (s *AioDNSBruteScanner) Setup() error {
 		return fmt.Errorf("failed to install aiodnsbrute: %w", err)
 	}
 
-	if path, err := exec.LookPath("aiodnsbrute"); err == nil {
+	if path, err := client.LookPath("aiodnsbrute"); err == nil {
 		s.Config.ExecutablePath = path
 	}
 
@@ -79,17 +83,24 @@ func (s *AioDNSBruteScanner) Scan(target string) (scanners.ScannerResult, error)
 		Errors: []string{},
 	}
 
-	domain := extractor.ExtractDomain(target)
+	parsed, err := extractor.Parse(target)
+	if err == nil && parsed.Kind != extractor.KindDomain {
+		err = fmt.Errorf("aiodnsbrute needs a domain, not %s", parsed)
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	domain := parsed.Host
 
 	cmdParts := strings.Fields(s.Config.Base_Command)
 	cmdParts = append(cmdParts, domain, "--output", "json", "-f", "aiodnsbrute.json")
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, runErr := s.CombinedOutput(cmdParts)
 
-	if err != nil && len(output) == 0 {
-		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", err))
-		return result, err
+	if runErr != nil && len(output) == 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", runErr))
+		return result, runErr
 	}
 
 	subdomains := []string{}
@@ -113,19 +124,29 @@ func (s *AioDNSBruteScanner) Scan(target string) (scanners.ScannerResult, error)
 		}
 	}
 
-	dnsResult := DNSBruteResult{
-		Domain:     domain,
-		Subdomains: subdomains,
-		TimeStamp:  time.Now().Format(time.RFC3339),
-	}
+	// Names resolved through CNAMEs may lie under other organizations'
+	// domains, so results are grouped by registrable domain, one per
+	// organization.
+	timestamp := time.Now().Format(time.RFC3339)
+	for _, group := range scanners.GroupSubdomains(domain, subdomains) {
+		dnsResult := DNSBruteResult{
+			Domain:     group.Domain,
+			Subdomains: group.Names,
+			TimeStamp:  timestamp,
+		}
 
-	jsonData, err := json.MarshalIndent(dnsResult, "", "  ")
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("json encoding error: %v", err))
-		return result, err
+		jsonData, err := json.MarshalIndent(dnsResult, "", "  ")
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("json encoding error: %v", err))
+			return result, err
+		}
+		result.Data = append(result.Data, string(jsonData))
+	}
+	// A scan stopped at its deadline still reports what it found.
+	if errors.Is(runErr, context.DeadlineExceeded) {
+		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", runErr))
+		return result, runErr
 	}
-
-	result.Data = append(result.Data, string(jsonData))
 	return result, nil
 }
 
@@ -0,0 +1,46 @@
+package builtin
+
+import (
+	"github.com/IxBahy/ASM/internal/scanners"
+	"github.com/IxBahy/ASM/internal/scanners/aiodnsbrute"
+	"github.com/IxBahy/ASM/internal/scanners/dnsx"
+	"github.com/IxBahy/ASM/internal/scanners/katana"
+	"github.com/IxBahy/ASM/internal/scanners/masscan"
+	"github.com/IxBahy/ASM/internal/scanners/naabu"
+	"github.com/IxBahy/ASM/internal/scanners/nmap"
+	"github.com/IxBahy/ASM/internal/scanners/nuclei"
+	"github.com/IxBahy/ASM/internal/scanners/semgrep"
+	"github.com/IxBahy/ASM/internal/scanners/sqlmap"
+	"github.com/IxBahy/ASM/internal/scanners/subfinder"
+	"github.com/IxBahy/ASM/internal/scanners/tlsx"
+	"github.com/IxBahy/ASM/internal/scanners/trufflehog"
+	"github.com/IxBahy/ASM/internal/scanners/whois"
+	"github.com/IxBahy/ASM/internal/scanners/wpscan"
+)
+
+// Scanners returns a new instance of every scanner shipped with ASM.
+func Scanners() []scanners.Scanner {
+	return []scanners.Scanner{
+		aiodnsbrute.NewAioDNSBruteScanner(),
+		dnsx.NewDNSxScanner(),
+		katana.NewKatanaScanner(),
+		masscan.NewMassScanScanner(),
+		naabu.NewNaabuScanner(),
+		nmap.NewNmapScanner(),
+		nuclei.NewNucleiScanner(),
+		semgrep.NewSemgrepScanner(),
+		sqlmap.NewSQLMapScanner(),
+		subfinder.NewSubfinderScanner(),
+		tlsx.NewTLSXScanner(),
+		trufflehog.NewTruffleHogScanner(),
+		whois.NewWhoisScanner(),
+		wpscan.NewWPScanScanner(),
+	}
+}
+
+// Register adds every built-in scanner to registry without installing it.
+func Register(registry *scanners.ScannerRegistry) {
+	for _, scanner := range Scanners() {
+		registry.Add(scanner)
+	}
+}
@@ -0,0 +1,44 @@
+package scanners
+
+import (
+	"github.com/IxBahy/ASM/pkg/client"
+)
+
+// Bundler is implemented by scanners that need more than their installation
+// artifacts to run offline, such as nuclei's templates.
+type Bundler interface {
+	Bundle(builder *client.BundleBuilder) error
+}
+
+// AddToBundle adds the artifacts scanner installs from to builder, so it
+// can be installed from the resulting offline bundle. Scanners installed
+// with the system package manager, or built into ASM, have none; the
+// returned bool reports whether anything was added.
+func AddToBundle(builder *client.BundleBuilder, scanner Scanner) (bool, error) {
+	config := scanner.GetConfig()
+
+	var err error
+	added := true
+	switch config.InstallationType {
+	case client.InstallationTypeGithub:
+		options := config.GithubOptions
+		err = builder.AddGithubRelease(config.Name, options.InstallLink, options.InstallPattern, options.Verification())
+	case client.InstallationTypePython:
+		err = builder.AddPythonPackage(config.Name, config.PythonOptions.Package, config.Version)
+	case client.InstallationTypeInternal:
+		err = builder.AddGoModule(config.Name, config.GoOptions.Package, config.Version)
+	default:
+		added = false
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if bundler, ok := scanner.(Bundler); ok {
+		if err := bundler.Bundle(builder); err != nil {
+			return false, err
+		}
+		added = true
+	}
+	return added, nil
+}
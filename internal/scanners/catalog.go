@@ -0,0 +1,290 @@
+package scanners
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/IxBahy/ASM/pkg/client"
+)
+
+// CatalogEnv names the file with the user's catalog entries, overriding
+// CatalogPath.
+const CatalogEnv = "ASM_TOOL_CATALOG"
+
+const catalogVersion = 1
+
+//go:embed catalog.json
+var embeddedCatalog []byte
+
+// Catalog describes how to install tools by name, so a tool released on
+// GitHub, PyPI or as a Go module can be installed without a scanner package
+// of its own. The catalog shipped with ASM is merged with the user's.
+type Catalog struct {
+	entries map[string]CatalogEntry
+}
+
+// CatalogEntry is the catalog entry of one tool.
+type CatalogEntry struct {
+	Name        string `json:"-"`
+	Description string `json:"description,omitempty"`
+	// Type is the installation type: github, python or internal (a Go
+	// package built from source).
+	Type client.InstallationType `json:"type"`
+	// Version is the version to install, "latest" by default.
+	Version string `json:"version,omitempty"`
+	// Repo is the GitHub repository releasing the tool, as owner/name.
+	Repo string `json:"repo,omitempty"`
+	// AssetPattern matches the release asset to install, see
+	// GithubOptions.InstallPattern.
+	AssetPattern    string `json:"asset_pattern,omitempty"`
+	ChecksumPattern string `json:"checksum_pattern,omitempty"`
+	Prerelease      bool   `json:"prerelease,omitempty"`
+	// ArchiveMember is the path of the executable in the release archive,
+	// see GithubOptions.ArchiveMember.
+	ArchiveMember string `json:"archive_member,omitempty"`
+	// Package is the PyPI package or Go package path to install.
+	Package string `json:"package,omitempty"`
+	// Binary is the name of the installed executable, the tool's name by
+	// default.
+	Binary string `json:"binary,omitempty"`
+	// VersionCommand and VersionRegex configure the scanner's
+	// VersionProbe.
+	VersionCommand []string `json:"version_command,omitempty"`
+	VersionRegex   string   `json:"version_regex,omitempty"`
+	// MinVersion is the oldest version Setup accepts.
+	MinVersion string `json:"min_version,omitempty"`
+	Intrusive  bool   `json:"intrusive,omitempty"`
+}
+
+type catalogFile struct {
+	Version int                        `json:"version"`
+	Tools   map[string]json.RawMessage `json:"tools"`
+}
+
+// CatalogPath returns the file with the user's catalog entries: the value
+// of CatalogEnv, or catalog.json next to the managed tools directory.
+func CatalogPath() string {
+	if path := os.Getenv(CatalogEnv); path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(client.ToolsDir()), "catalog.json")
+}
+
+// LoadCatalog reads the embedded catalog and merges the entries in the file
+// at path into it, if it exists. A user entry for a tool that is already in
+// the catalog only overrides the fields it sets.
+func LoadCatalog(path string) (*Catalog, error) {
+	catalog := &Catalog{entries: make(map[string]CatalogEntry)}
+	if err := catalog.merge(embeddedCatalog); err != nil {
+		return nil, fmt.Errorf("failed to parse the embedded catalog: %w", err)
+	}
+	if path == "" {
+		return catalog, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return catalog, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	if err := catalog.merge(data); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+	return catalog, nil
+}
+
+var (
+	defaultCatalog     *Catalog
+	defaultCatalogOnce sync.Once
+)
+
+// DefaultCatalog returns the catalog loaded from CatalogPath. If the user's
+// catalog cannot be loaded, a warning is printed and the embedded catalog
+// is used alone.
+func DefaultCatalog() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		catalog, err := LoadCatalog(CatalogPath())
+		if err != nil {
+			fmt.Printf("WARNING: %v; using the built-in catalog\n", err)
+			catalog, err = LoadCatalog("")
+			if err != nil {
+				panic(err)
+			}
+		}
+		defaultCatalog = catalog
+	})
+	return defaultCatalog
+}
+
+// CatalogConfig returns the configuration of the tool name in the default
+// catalog, for scanner packages that install their tool from it. If the
+// user's entry is invalid, the built-in one is used instead.
+func CatalogConfig(name string) ScannerConfig {
+	config, err := DefaultCatalog().Config(name)
+	if err == nil {
+		return config
+	}
+
+	builtin, loadErr := LoadCatalog("")
+	if loadErr != nil {
+		panic(loadErr)
+	}
+	fallback, fallbackErr := builtin.Config(name)
+	if fallbackErr != nil {
+		panic(fmt.Sprintf("built-in catalog entry %s: %v", name, fallbackErr))
+	}
+	fmt.Printf("WARNING: %v; using the built-in catalog entry\n", err)
+	return fallback
+}
+
+func (c *Catalog) merge(data []byte) error {
+	var file catalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if file.Version != catalogVersion {
+		return fmt.Errorf("unsupported catalog version %d", file.Version)
+	}
+
+	for name, raw := range file.Tools {
+		entry := c.entries[name]
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return fmt.Errorf("entry %s: %w", name, err)
+		}
+		entry.Name = name
+		c.entries[name] = entry
+	}
+	return nil
+}
+
+// Names returns the names of the tools in the catalog in sorted order.
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Entry returns the catalog entry of the tool name.
+func (c *Catalog) Entry(name string) (CatalogEntry, bool) {
+	entry, ok := c.entries[name]
+	return entry, ok
+}
+
+// Config returns the scanner configuration for installing the tool name.
+func (c *Catalog) Config(name string) (ScannerConfig, error) {
+	entry, ok := c.entries[name]
+	if !ok {
+		return ScannerConfig{}, fmt.Errorf("%s is not in the catalog", name)
+	}
+	return entry.Config()
+}
+
+// Scanner returns a scanner that installs the tool name as its catalog
+// entry describes. It can be installed, upgraded, rolled back and
+// uninstalled like any other scanner, but not run.
+func (c *Catalog) Scanner(name string) (Scanner, error) {
+	config, err := c.Config(name)
+	if err != nil {
+		return nil, err
+	}
+	s := &CatalogScanner{BaseScanner: &BaseScanner{Config: config}}
+	s.InstallState.Installed = s.IsInstalled()
+	return s, nil
+}
+
+// Config validates the entry and returns the scanner configuration for
+// installing it.
+func (e CatalogEntry) Config() (ScannerConfig, error) {
+	binary := e.Binary
+	if binary == "" {
+		binary = e.Name
+	}
+	version := e.Version
+	if version == "" {
+		version = "latest"
+	}
+	if e.MinVersion != "" {
+		if _, err := ParseVersion(e.MinVersion); err != nil {
+			return ScannerConfig{}, fmt.Errorf("catalog entry %s: invalid min_version: %w", e.Name, err)
+		}
+	}
+	if e.VersionRegex != "" {
+		if _, err := regexp.Compile(e.VersionRegex); err != nil {
+			return ScannerConfig{}, fmt.Errorf("catalog entry %s: invalid version_regex: %w", e.Name, err)
+		}
+	}
+
+	config := ScannerConfig{
+		Name:             e.Name,
+		Version:          version,
+		ExecutablePath:   client.BinPath(binary),
+		InstallationType: e.Type,
+		Intrusive:        e.Intrusive,
+		MinVersion:       e.MinVersion,
+		VersionProbe: VersionProbe{
+			Args:    e.VersionCommand,
+			Pattern: e.VersionRegex,
+		},
+	}
+
+	switch e.Type {
+	case client.InstallationTypeGithub:
+		owner, repo, found := strings.Cut(e.Repo, "/")
+		if !found || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return ScannerConfig{}, fmt.Errorf("catalog entry %s: repo must be owner/name, got %q", e.Name, e.Repo)
+		}
+		if e.AssetPattern == "" {
+			return ScannerConfig{}, fmt.Errorf("catalog entry %s: asset_pattern is required", e.Name)
+		}
+		config.GithubOptions = GithubOptions{
+			InstallLink:     "https://api.github.com/repos/" + e.Repo + "/releases/latest",
+			InstallPattern:  e.AssetPattern,
+			ChecksumPattern: e.ChecksumPattern,
+			Prerelease:      e.Prerelease,
+			ArchiveMember:   e.ArchiveMember,
+		}
+	case client.InstallationTypePython:
+		pkg := e.Package
+		if pkg == "" {
+			pkg = e.Name
+		}
+		config.PythonOptions = PythonOptions{Package: pkg}
+		if binary != pkg {
+			config.PythonOptions.Executable = binary
+		}
+	case client.InstallationTypeInternal:
+		if e.Package == "" {
+			return ScannerConfig{}, fmt.Errorf("catalog entry %s: package is required", e.Name)
+		}
+		config.GoOptions = GoOptions{Package: e.Package}
+		config.ExecutablePath = client.BinPath(client.GoBinaryName(e.Package))
+	default:
+		return ScannerConfig{}, fmt.Errorf("catalog entry %s: unsupported type %q", e.Name, e.Type)
+	}
+	return config, nil
+}
+
+// CatalogScanner is a tool known only from its catalog entry.
+type CatalogScanner struct {
+	*BaseScanner
+}
+
+func (s *CatalogScanner) Setup() error {
+	if s.IsInstalled() {
+		return s.CheckVersion()
+	}
+	return s.Install()
+}
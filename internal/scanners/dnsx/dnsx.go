@@ -60,7 +60,15 @@ func (s *DNSxScanner) Scan(target string) (scanners.ScannerResult, error) {
 		Errors: []string{},
 	}
 
-	domain := extractor.ExtractDomain(target)
+	parsed, err := extractor.Parse(target)
+	if err == nil && parsed.Kind != extractor.KindDomain {
+		err = fmt.Errorf("dnsx resolves domains, not %s", parsed)
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	domain := parsed.Host
 
 	options := dnsx.DefaultOptions
 
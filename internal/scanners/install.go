@@ -0,0 +1,177 @@
+package scanners
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/client"
+)
+
+// DefaultInstallJobs is how many tools InstallAll installs at once unless
+// told otherwise.
+const DefaultInstallJobs = 4
+
+// InstallStatus is the state of one tool in InstallAll.
+type InstallStatus string
+
+const (
+	InstallQueued    InstallStatus = "queued"
+	InstallStarted   InstallStatus = "installing"
+	InstallSucceeded InstallStatus = "installed"
+	InstallSkipped   InstallStatus = "skipped"
+	InstallDryRun    InstallStatus = "dry-run"
+	InstallFailed    InstallStatus = "failed"
+)
+
+// InstallEvent reports a change of a tool's status in InstallAll.
+type InstallEvent struct {
+	Tool    string                  `json:"tool"`
+	Type    client.InstallationType `json:"type"`
+	Status  InstallStatus           `json:"status"`
+	Version string                  `json:"version,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+	Time    time.Time               `json:"time"`
+	// Elapsed is how long the tool took to install, in nanoseconds, once
+	// it is done.
+	Elapsed time.Duration `json:"elapsed,omitempty"`
+}
+
+// InstallResult is the outcome of installing one tool with InstallAll.
+type InstallResult struct {
+	Tool    string
+	Type    client.InstallationType
+	Status  InstallStatus
+	Version string
+	Err     error
+	Elapsed time.Duration
+}
+
+// InstallOptions configures InstallAll.
+type InstallOptions struct {
+	// Jobs bounds how many tools install at once, DefaultInstallJobs if
+	// zero. The system package manager lane counts as one of them.
+	Jobs int
+	// Install installs one scanner and reports whether it was skipped
+	// because it is already installed. By default it runs the scanner's
+	// Setup unless IsInstalled, and checks the version of an installed one.
+	Install func(scanner Scanner) (skipped bool, err error)
+	// Events, if set, receives every status change. Calls are serialized.
+	Events func(event InstallEvent)
+}
+
+// InstallAll installs scanners concurrently and returns their results in
+// the order given. Scanners installed with the system package manager run
+// one after another in a lane of their own, since apt, dnf and the others
+// lock their database and fail rather than wait for each other; GitHub,
+// Python and Go installs run in parallel with that lane.
+func InstallAll(list []Scanner, options InstallOptions) []InstallResult {
+	jobs := options.Jobs
+	if jobs <= 0 {
+		jobs = DefaultInstallJobs
+	}
+	install := options.Install
+	if install == nil {
+		install = setupScanner
+	}
+
+	var eventsMu sync.Mutex
+	emit := func(event InstallEvent) {
+		if options.Events == nil {
+			return
+		}
+		event.Time = time.Now()
+		eventsMu.Lock()
+		defer eventsMu.Unlock()
+		options.Events(event)
+	}
+
+	results := make([]InstallResult, len(list))
+	run := func(i int) {
+		scanner := list[i]
+		config := scanner.GetConfig()
+		emit(InstallEvent{Tool: config.Name, Type: config.InstallationType, Status: InstallStarted})
+
+		start := time.Now()
+		skipped, err := install(scanner)
+		result := InstallResult{
+			Tool:    config.Name,
+			Type:    config.InstallationType,
+			Status:  InstallSucceeded,
+			Version: scanner.GetInstallationState().Version,
+			Err:     err,
+			Elapsed: time.Since(start),
+		}
+		event := InstallEvent{Tool: result.Tool, Type: result.Type, Version: result.Version, Elapsed: result.Elapsed}
+		switch {
+		case errors.Is(err, client.ErrDryRun):
+			result.Status = InstallDryRun
+			result.Err = nil
+		case err != nil:
+			result.Status = InstallFailed
+			event.Error = err.Error()
+		case skipped:
+			result.Status = InstallSkipped
+		}
+		event.Status = result.Status
+		results[i] = result
+		emit(event)
+	}
+
+	var system, parallel []int
+	for i, scanner := range list {
+		config := scanner.GetConfig()
+		emit(InstallEvent{Tool: config.Name, Type: config.InstallationType, Status: InstallQueued})
+		if config.InstallationType == client.InstallationTypeShell {
+			system = append(system, i)
+		} else {
+			parallel = append(parallel, i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan int)
+	work := func() {
+		for i := range queue {
+			run(i)
+		}
+	}
+	workers := jobs
+	if len(system) > 0 {
+		workers--
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range system {
+				run(i)
+			}
+			// With a single job the lane is the only worker, and takes
+			// the other installs once the system packages are done.
+			if workers == 0 {
+				work()
+			}
+		}()
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+
+	for _, i := range parallel {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// setupScanner is the default InstallOptions.Install.
+func setupScanner(scanner Scanner) (bool, error) {
+	if scanner.IsInstalled() {
+		return true, CheckVersion(scanner)
+	}
+	return false, scanner.Setup()
+}
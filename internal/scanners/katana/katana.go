@@ -41,6 +41,7 @@ func NewKatanaScanner() *KatanaScanner {
 		Version:        "embedded",
 		ExecutablePath: "",
 		Base_Command:   "",
+		Intrusive:      true,
 	}
 
 	base := &scanners.BaseScanner{
@@ -75,8 +76,16 @@ func (s *KatanaScanner) Scan(target string) (scanners.ScannerResult, error) {
 		Errors: []string{},
 	}
 
-	target = normalizeURL(target)
-	domain := extractor.ExtractDomain(target)
+	parsed, err := extractor.Parse(target)
+	if err == nil && !parsed.IsHost() {
+		err = fmt.Errorf("katana crawls a single site, not %s", parsed)
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	target = parsed.URL("https")
+	domain := parsed.Host
 
 	var crawledURLs []URLResult
 
@@ -162,14 +171,6 @@ func (s *KatanaScanner) Scan(target string) (scanners.ScannerResult, error) {
 	return result, nil
 }
 
-func normalizeURL(url string) string {
-
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "https://" + url
-	}
-	return url
-}
-
 func extractContentType(headers map[string]string) string {
 	if ct, ok := headers["Content-Type"]; ok {
 		return ct
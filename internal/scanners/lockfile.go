@@ -0,0 +1,297 @@
+package scanners
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/client"
+)
+
+const lockfileVersion = 1
+
+// Lockfile records the exact version of every installed tool, and for
+// GitHub releases the asset and digest installed on each platform, so every
+// machine sharing the lockfile runs the same builds.
+type Lockfile struct {
+	Version int                   `json:"version"`
+	Tools   map[string]LockedTool `json:"tools"`
+}
+
+// LockedTool is the lockfile entry of one scanner.
+type LockedTool struct {
+	Type    client.InstallationType `json:"type"`
+	Version string                  `json:"version"`
+	// Tag is the GitHub release tag the version came from.
+	Tag string `json:"tag,omitempty"`
+	// Source is the package or module installed, or for system packages
+	// the package manager and package names.
+	Source string `json:"source,omitempty"`
+	// Assets maps a platform, such as "linux/amd64", to the release asset
+	// installed on it.
+	Assets      map[string]LockedAsset `json:"assets,omitempty"`
+	InstalledAt time.Time              `json:"installed_at"`
+	// Previous is the entry this one replaced, which "tools rollback"
+	// restores.
+	Previous *LockedTool `json:"previous,omitempty"`
+}
+
+// LockedAsset is a GitHub release asset pinned by its digest.
+type LockedAsset struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// lockable is implemented by every scanner embedding BaseScanner.
+type lockable interface {
+	SetConfig(config ScannerConfig)
+	Resolution() (client.Resolution, bool)
+	Reinstall()
+	ResetInstallation()
+}
+
+// LoadLockfile reads the lockfile at path, or returns an empty one if it
+// does not exist yet.
+func LoadLockfile(path string) (*Lockfile, error) {
+	lock := &Lockfile{Version: lockfileVersion, Tools: make(map[string]LockedTool)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.Version != lockfileVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d", lock.Version)
+	}
+	if lock.Tools == nil {
+		lock.Tools = make(map[string]LockedTool)
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile to path atomically.
+func (l *Lockfile) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Pin configures scanner to install the version recorded for it, and for
+// GitHub releases the exact asset and digest recorded for this platform.
+// It reports whether the lockfile has an entry for the scanner.
+func (l *Lockfile) Pin(scanner Scanner) bool {
+	locked, ok := l.Tools[scanner.GetConfig().Name]
+	target, canPin := scanner.(lockable)
+	if !ok || !canPin {
+		return false
+	}
+
+	config := scanner.GetConfig()
+	switch config.InstallationType {
+	case client.InstallationTypeGithub:
+		// The version selects the release tagged with it, also when another
+		// platform locked it without an asset for this one.
+		config.Version = locked.Version
+		if asset, found := locked.Assets[client.CurrentPlatform().String()]; found {
+			config.GithubOptions.SHA256 = asset.SHA256
+			if os.Getenv(client.MirrorEnv) == "" {
+				config.GithubOptions.InstallLink = asset.URL
+			}
+		}
+	case client.InstallationTypePython, client.InstallationTypeInternal:
+		config.Version = locked.Version
+	default:
+		// System packages are versioned by the distribution.
+	}
+	target.SetConfig(config)
+	return true
+}
+
+// Record stores what scanner's installer just installed. It reports false
+// if the scanner was not installed in this process, e.g. because it was
+// already present.
+func (l *Lockfile) Record(scanner Scanner) bool {
+	source, ok := scanner.(lockable)
+	if !ok {
+		return false
+	}
+	resolution, ok := source.Resolution()
+	if !ok {
+		return false
+	}
+
+	config := scanner.GetConfig()
+	locked := LockedTool{
+		Type:        config.InstallationType,
+		Version:     resolution.Version,
+		Tag:         resolution.Tag,
+		InstalledAt: time.Now().UTC(),
+	}
+	if locked.Version == "" {
+		locked.Version = scanner.GetInstallationState().Version
+	}
+	if previous, found := l.Tools[config.Name]; found {
+		if previous.Version != locked.Version {
+			previous.Previous = nil
+			locked.Previous = &previous
+		} else {
+			locked.Previous = previous.Previous
+		}
+	}
+
+	if config.InstallationType == client.InstallationTypeGithub {
+		// Keep the assets other platforms installed from the same release.
+		previous := l.Tools[config.Name]
+		locked.Assets = make(map[string]LockedAsset)
+		if previous.Version == locked.Version {
+			for platform, asset := range previous.Assets {
+				locked.Assets[platform] = asset
+			}
+			if locked.Tag == "" {
+				locked.Tag = previous.Tag
+			}
+		}
+		locked.Assets[client.CurrentPlatform().String()] = LockedAsset{URL: resolution.Source, SHA256: resolution.SHA256}
+	} else {
+		locked.Source = resolution.Source
+	}
+
+	l.Tools[config.Name] = locked
+	return true
+}
+
+// Uninstall removes scanner with the installer it was installed with, or
+// its own Uninstall method if it has one.
+func Uninstall(scanner Scanner) error {
+	config := scanner.GetConfig()
+
+	if custom, ok := scanner.(interface{ Uninstall() error }); ok {
+		if err := custom.Uninstall(); err != nil {
+			return err
+		}
+	} else {
+		installer, err := installerFor(config)
+		if err != nil {
+			return err
+		}
+		uninstaller, ok := installer.(client.Uninstaller)
+		if !ok {
+			return fmt.Errorf("%s installs cannot be uninstalled", config.InstallationType)
+		}
+		if err := uninstaller.UninstallTool(); err != nil {
+			return fmt.Errorf("failed to uninstall %s: %w", config.Name, err)
+		}
+	}
+
+	if target, ok := scanner.(lockable); ok {
+		target.ResetInstallation()
+	}
+	return nil
+}
+
+// Reinstall makes the next Setup of scanner install it again over the
+// installed version, so a failed upgrade leaves the old one in place.
+// Scanners that cannot be reinstalled in place are uninstalled instead.
+func Reinstall(scanner Scanner) error {
+	if target, ok := scanner.(lockable); ok {
+		target.Reinstall()
+		return nil
+	}
+	return Uninstall(scanner)
+}
+
+// Rollback restores the version of scanner that its last install replaced,
+// with its own Rollback method if it has one.
+func Rollback(scanner Scanner) error {
+	config := scanner.GetConfig()
+
+	if custom, ok := scanner.(interface{ Rollback() error }); ok {
+		if err := custom.Rollback(); err != nil {
+			return err
+		}
+	} else {
+		installer, err := installerFor(config)
+		if err != nil {
+			return err
+		}
+		rollbacker, ok := installer.(client.Rollbacker)
+		if !ok {
+			return fmt.Errorf("%s installs cannot be rolled back", config.InstallationType)
+		}
+		if err := rollbacker.RollbackTool(); err != nil {
+			return fmt.Errorf("failed to roll back %s: %w", config.Name, err)
+		}
+	}
+
+	if target, ok := scanner.(lockable); ok {
+		target.ResetInstallation()
+	}
+	return nil
+}
+
+// LatestVersion looks up the newest version of scanner available upstream.
+func LatestVersion(scanner Scanner) (string, error) {
+	installer, err := installerFor(scanner.GetConfig())
+	if err != nil {
+		return "", err
+	}
+	checker, ok := installer.(client.VersionChecker)
+	if !ok {
+		return "", fmt.Errorf("%s installs have no upstream version", scanner.GetConfig().InstallationType)
+	}
+	return checker.LatestVersion()
+}
+
+// installerFor creates the install client for a scanner's configuration,
+// for operations other than installing it.
+func installerFor(config ScannerConfig) (client.ToolInstaller, error) {
+	installArgs, err := installArgs(config)
+	if err != nil {
+		return nil, err
+	}
+	return client.ClientFactory(config.InstallationType, installArgs, 5*time.Minute)
+}
+
+// installArgs returns the install client arguments for a scanner's
+// configuration. For system packages they name only the tool.
+func installArgs(config ScannerConfig) ([]string, error) {
+	switch config.InstallationType {
+	case client.InstallationTypeGithub:
+		return config.GithubOptions.InstallArgs(config.Version, config.ExecutablePath), nil
+	case client.InstallationTypePython:
+		return config.PythonOptions.InstallArgs(config.Version), nil
+	case client.InstallationTypeInternal:
+		return config.GoOptions.InstallArgs(config.Version, client.BinDir()), nil
+	case client.InstallationTypeShell:
+		return []string{config.Name}, nil
+	default:
+		return nil, fmt.Errorf("%s is built into ASM", config.Name)
+	}
+}
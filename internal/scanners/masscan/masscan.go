@@ -1,11 +1,12 @@
 package masscan
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -31,8 +32,10 @@ func NewMassScanScanner() *MassScanScanner {
 		Name:             "masscan",
 		Version:          "installed",
 		ExecutablePath:   "/usr/bin/masscan",
-		Base_Command:     "sudo masscan",
+		Base_Command:     "masscan",
+		Intrusive:        true,
 		InstallationType: client.InstallationTypeShell,
+		VersionProbe:     scanners.VersionProbe{Pattern: `Masscan version ([0-9][^\s]*)`},
 	}
 
 	base := &scanners.BaseScanner{
@@ -52,13 +55,13 @@ func NewMassScanScanner() *MassScanScanner {
 
 func (s *MassScanScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
 	installArgs := []string{"masscan", "libpcap-dev", "-y"}
 
 	var err error
-	s.installClient, err = client.ClientFactory(client.InstallationTypeShell, installArgs, 5)
+	s.installClient, err = s.NewInstaller(client.InstallationTypeShell, installArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client: %w", err)
 	}
@@ -94,18 +97,27 @@ func (s *MassScanScanner) Scan(target string) (scanners.ScannerResult, error) {
 	defer os.Remove(outputFile.Name())
 	outputFile.Close()
 
-	cmdArgs := []string{
-		"sudo",
-		"masscan",
+	cmdArgs := strings.Fields(s.Config.Base_Command)
+	cmdArgs = append(cmdArgs,
 		"-p80,443,8000-8100",
 		targetIP,
 		"--rate=1000",
 		"--wait=0",
 		"-oJ", outputFile.Name(),
+	)
+	// masscan sends raw packets, which needs root.
+	if os.Geteuid() != 0 {
+		if cmdArgs, err = client.Elevate(cmdArgs); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result, err
+		}
 	}
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	cmdOutput, err := cmd.CombinedOutput()
+	cmdOutput, err := s.CombinedOutput(cmdArgs)
+	if errors.Is(err, context.DeadlineExceeded) {
+		result.Errors = append(result.Errors, fmt.Sprintf("masscan error: %v", err))
+		return result, err
+	}
 
 	if err != nil {
 
@@ -168,13 +180,17 @@ func (s *MassScanScanner) Scan(target string) (scanners.ScannerResult, error) {
 	return result, nil
 }
 
+// resolveTarget returns the target as masscan takes it: addresses,
+// networks and ranges as they are, and domains resolved to an address.
 func resolveTarget(target string) (string, error) {
-
-	host := extractor.ExtractDomain(target)
-
-	if net.ParseIP(host) != nil {
-		return host, nil
+	parsed, err := extractor.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Kind != extractor.KindDomain {
+		return parsed.String(), nil
 	}
+	host := parsed.Host
 
 	ips, err := net.LookupHost(host)
 	if err != nil {
@@ -30,6 +30,7 @@ func NewNaabuScanner() *NaabuScanner {
 		Version:        "embedded",
 		ExecutablePath: "",
 		Base_Command:   "",
+		Intrusive:      true,
 	}
 
 	base := &scanners.BaseScanner{
@@ -64,12 +65,20 @@ func (s *NaabuScanner) Scan(target string) (scanners.ScannerResult, error) {
 		Errors: []string{},
 	}
 
-	host := extractor.ExtractDomain(target)
+	parsed, err := extractor.Parse(target)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	host := parsed.String()
+	if !parsed.IsNetwork() {
+		host = parsed.Host
+	}
 
 	var ports []string
 
 	options := runner.Options{
-		Host:    goflags.StringSlice{host},
+		Host:    goflags.StringSlice(parsed.HostSpecs()),
 		Ports:   "80,443,8080,8443",
 		Silent:  true,
 		NoColor: true,
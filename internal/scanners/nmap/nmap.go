@@ -1,14 +1,16 @@
 package nmap
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/IxBahy/ASM/internal/scanners"
 	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 func NewNmapScanner() *NmapScanner {
@@ -16,8 +18,10 @@ func NewNmapScanner() *NmapScanner {
 		Name:             "nmap",
 		Version:          "latest",
 		ExecutablePath:   "/usr/bin/nmap",
-		Base_Command:     "nmap -T4",
+		Base_Command:     "nmap",
+		Intrusive:        true,
 		InstallationType: client.InstallationTypeShell,
+		VersionProbe:     scanners.VersionProbe{Pattern: `Nmap version ([0-9][^\s]*)`},
 	}
 	base := &scanners.BaseScanner{
 		Config: config,
@@ -36,12 +40,12 @@ func NewNmapScanner() *NmapScanner {
 func (s *NmapScanner) Setup() error {
 
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
 	installArgs := []string{"nmap", "-y"}
 	var err error
-	s.installClient, err = client.ClientFactory(s.Config.InstallationType, installArgs, 5)
+	s.installClient, err = s.NewInstaller(s.Config.InstallationType, installArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client: %w", err)
 	}
@@ -53,91 +57,178 @@ func (s *NmapScanner) Setup() error {
 	return s.RegisterInstallationStats()
 }
 
-func (s *NmapScanner) Scan(target string) ([]Port, error) {
+// Scan scans target, a host, network or range, with the default Options
+// and returns the hosts that are up.
+func (s *NmapScanner) Scan(target string) ([]Host, error) {
+	hosts, _, err := s.ScanWithOptions(target, Options{})
+	return hosts, err
+}
+
+// ScanWithOptions scans target as options configure and returns the hosts
+// that are up, with their open ports, along with warnings about parts of
+// the scan that were left out.
+func (s *NmapScanner) ScanWithOptions(target string, options Options) ([]Host, []string, error) {
 	if !s.IsInstalled() {
-		return nil, fmt.Errorf("nmap is not installed")
+		return nil, nil, fmt.Errorf("nmap is not installed")
+	}
+	if err := options.Validate(); err != nil {
+		return nil, nil, err
+	}
+	parsed, err := extractor.Parse(target)
+	if err == nil && parsed.Wildcard {
+		err = fmt.Errorf("nmap scans hosts and networks, not %s", parsed)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	scanTypes, defaulted := options.scanTypes()
+	elevate := false
+	if options.privileged(scanTypes) && os.Geteuid() != 0 {
+		_, err := client.Elevate([]string{"nmap"})
+		if err == nil && options.runsScripts() {
+			// Scripts and their arguments come from the request, so they
+			// never run through sudo.
+			err = fmt.Errorf("scripts never run with sudo: use the connect scan type without os_detection")
+		}
+		if err != nil {
+			if !defaulted || options.OSDetection {
+				return nil, nil, err
+			}
+			// UDP is only part of the default scan, so it is left out
+			// rather than failing the TCP scan with it.
+			scanTypes = []string{"connect"}
+			warnings = append(warnings, fmt.Sprintf("UDP ports were not scanned: %v", err))
+		} else {
+			elevate = true
+		}
 	}
-	openPorts, err := s.scanPorts(target, 20)
 
+	run, err := s.run(options.args(scanTypes), parsed.HostSpecs(), elevate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan target: %w", err)
+		return nil, warnings, fmt.Errorf("failed to scan target: %w", err)
 	}
 
-	return openPorts, nil
+	hosts := []Host{}
+	for _, host := range run.Hosts {
+		if host.Status.State != "up" {
+			continue
+		}
+		openPorts := []Port{}
+		for _, port := range host.Ports {
+			if port.StateDetails.Value == "open" {
+				openPorts = append(openPorts, port)
+			}
+		}
+		host.Ports = openPorts
+		hosts = append(hosts, host)
+	}
+	return hosts, warnings, nil
 }
-func (s *NmapScanner) scanPorts(target string, topCount int) ([]Port, error) {
 
-	tcpPorts, err := s.scanTCPTopPorts(target, topCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan TCP ports: %w", err)
-	}
+// ScanResult runs Scan and reports each host that is up as a JSON document
+// in the result data.
+func (s *NmapScanner) ScanResult(target string) (scanners.ScannerResult, error) {
+	return s.scanResult(target, Options{})
+}
+
+// ValidateOptions checks options for ScanResultWithOptions.
+func (s *NmapScanner) ValidateOptions(options json.RawMessage) error {
+	_, err := ParseOptions(options)
+	return err
+}
 
-	udpPorts, err := s.scanUDPTopPorts(target, topCount)
+// ScanResultWithOptions is ScanResult with Options decoded from JSON.
+func (s *NmapScanner) ScanResultWithOptions(target string, options json.RawMessage) (scanners.ScannerResult, error) {
+	parsed, err := ParseOptions(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan UDP ports: %w", err)
+		return scanners.ScannerResult{Data: []string{}, Errors: []string{err.Error()}}, err
 	}
-
-	ports := append(tcpPorts, udpPorts...)
-	return ports, nil
+	return s.scanResult(target, parsed)
 }
 
-func (s *NmapScanner) scanTCPTopPorts(target string, top_count int) ([]Port, error) {
-	cmdParts := strings.Fields(s.Config.Base_Command)
-	tempFileName := "scanTCPTopPorts.xml"
-	cmdParts = append(cmdParts, "-sT", "-sV", "--top-ports", fmt.Sprintf("%d", top_count), target, "-oX", tempFileName)
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to run nmap scan: %w", err)
+func (s *NmapScanner) scanResult(target string, options Options) (scanners.ScannerResult, error) {
+	result := scanners.ScannerResult{
+		Data:   []string{},
+		Errors: []string{},
 	}
 
-	openPorts, err := filterOpenPortsInFile(tempFileName)
+	hosts, warnings, err := s.ScanWithOptions(target, options)
+	result.Errors = append(result.Errors, warnings...)
 	if err != nil {
-		return nil, err
+		result.Errors = append(result.Errors, fmt.Sprintf("scan error: %v", err))
+		return result, err
 	}
-	if err := os.Remove(tempFileName); err != nil {
-		fmt.Printf("Warning: Failed to remove temporary file %s: %v\n", tempFileName, err)
+
+	for _, host := range hosts {
+		jsonData, err := json.Marshal(host)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("json encoding error: %v", err))
+			continue
+		}
+		result.Data = append(result.Data, string(jsonData))
 	}
-	return openPorts, nil
 
+	return result, nil
 }
 
-func (s *NmapScanner) scanUDPTopPorts(target string, top_count int) ([]Port, error) {
+// run runs nmap with args against hosts, through sudo if elevate is set,
+// and parses its XML output. Each run writes to a temporary file of its
+// own, so concurrent scans do not overwrite each other's output.
+func (s *NmapScanner) run(args, hosts []string, elevate bool) (*NmapRun, error) {
+	outputFile, err := os.CreateTemp("", "nmap-*.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(outputFile.Name())
+	outputFile.Close()
+
 	cmdParts := strings.Fields(s.Config.Base_Command)
-	tempFileName := "scanUDPTopPorts.xml"
-	cmdParts = append(cmdParts, "-sV", "-sU", "--top-ports", fmt.Sprintf("%d", top_count), target, "-oX", tempFileName)
+	cmdParts = append(cmdParts, args...)
+	cmdParts = append(cmdParts, "-oX", outputFile.Name())
+	cmdParts = append(cmdParts, hosts...)
+	if elevate {
+		if cmdParts, err = client.Elevate(cmdParts); err != nil {
+			return nil, err
+		}
+	}
 
-	cmd := exec.Command("sudo", cmdParts...)
-	if err := cmd.Run(); err != nil {
+	if output, err := s.CombinedOutput(cmdParts); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return nil, fmt.Errorf("failed to run nmap scan: %w: %s", err, lastLine(message))
+		}
 		return nil, fmt.Errorf("failed to run nmap scan: %w", err)
 	}
 
-	openPorts, err := filterOpenPortsInFile(tempFileName)
-	if err != nil {
-		return nil, err
-	}
-	if err := os.Remove(tempFileName); err != nil {
-		fmt.Printf("Warning: Failed to remove temporary file %s: %v\n", tempFileName, err)
-	}
-	return openPorts, nil
+	return parseRunFile(outputFile.Name())
 }
 
-func filterOpenPortsInFile(functionName string) ([]Port, error) {
-	var nmapRun NmapRun
-	xmlData, err := os.ReadFile(functionName)
+// parseRunFile parses the nmap XML output in the file at path.
+func parseRunFile(path string) (*NmapRun, error) {
+	xmlData, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read XML file: %w", err)
 	}
+	return parseRun(xmlData)
+}
 
-	xml.Unmarshal(xmlData, &nmapRun)
-
-	openPorts := []Port{}
-	for _, host := range nmapRun.Hosts {
-		for _, port := range host.Ports {
-			if port.StateDetails.Value == "open" {
-				openPorts = append(openPorts, port)
-			}
-		}
+// parseRun parses nmap XML output, failing for a run nmap reports as
+// failed, and extracts the results of the scripts ASM understands.
+func parseRun(xmlData []byte) (*NmapRun, error) {
+	var nmapRun NmapRun
+	if err := xml.Unmarshal(xmlData, &nmapRun); err != nil {
+		return nil, fmt.Errorf("failed to parse nmap XML output: %w", err)
 	}
+	if nmapRun.RunStats.Finished.Exit == "error" {
+		return nil, fmt.Errorf("nmap failed: %s", nmapRun.RunStats.Finished.ErrorMsg)
+	}
+	nmapRun.extractScripts()
+	return &nmapRun, nil
+}
 
-	return openPorts, nil
+// lastLine returns the last line of output, where nmap reports why it
+// stopped.
+func lastLine(output string) string {
+	return output[strings.LastIndexByte(output, '\n')+1:]
 }
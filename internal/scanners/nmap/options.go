@@ -0,0 +1,260 @@
+package nmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTopPorts is how many of the most common ports a scan covers when
+// its options name no ports.
+const DefaultTopPorts = 20
+
+// Options configures an nmap scan. The zero value scans the top 20 TCP
+// and UDP ports with version detection at the aggressive timing template.
+type Options struct {
+	// Ports is an nmap port specification, such as "22,80,443", "1-1024"
+	// or "T:80,U:53". When it is empty the TopPorts most common ports are
+	// scanned.
+	Ports string `json:"ports,omitempty"`
+	// TopPorts is how many of the most common ports to scan when Ports is
+	// empty, DefaultTopPorts if zero.
+	TopPorts int `json:"top_ports,omitempty"`
+	// ScanTypes are the scan techniques to run: one TCP technique of
+	// "connect", "syn", "ack", "window", "maimon", "null", "fin" or
+	// "xmas", and "udp" or "sctp". All but "connect" need root.
+	// Default: "connect" and "udp", leaving UDP out when ASM cannot get
+	// root.
+	ScanTypes []string `json:"scan_types,omitempty"`
+	// VersionIntensity is the intensity of version detection, from 0 for
+	// the lightest probes to 9 for all of them. nmap's default is 7.
+	VersionIntensity *int `json:"version_intensity,omitempty"`
+	// OSDetection enables OS detection (-O), which needs root.
+	OSDetection bool `json:"os_detection,omitempty"`
+	// Scripts are NSE scripts, categories or patterns to run, such as
+	// "http-title", "vuln" or "ssl-*". Scripts in the excluded categories
+	// never run, whatever selects them, except the scripts ASM parses.
+	Scripts []string `json:"scripts,omitempty"`
+	// ScriptArgs are passed to the scripts with --script-args. Values may
+	// not name paths, and newtargets and max-newtargets may not be set.
+	ScriptArgs map[string]string `json:"script_args,omitempty"`
+	// Timing is a timing template, by number from 0 to 5 or by name from
+	// "paranoid" to "insane". Default: "aggressive" (4).
+	Timing string `json:"timing,omitempty"`
+}
+
+// scanTypeFlags maps scan types to their nmap flags.
+var scanTypeFlags = map[string]string{
+	"connect": "-sT",
+	"syn":     "-sS",
+	"ack":     "-sA",
+	"window":  "-sW",
+	"maimon":  "-sM",
+	"null":    "-sN",
+	"fin":     "-sF",
+	"xmas":    "-sX",
+	"udp":     "-sU",
+	"sctp":    "-sY",
+}
+
+// timingTemplates are the names of the timing templates, by number.
+var timingTemplates = []string{"paranoid", "sneaky", "polite", "normal", "aggressive", "insane"}
+
+// excludedCategories are the script categories a scan never runs: scripts
+// that guess credentials, exploit, fuzz or may crash their targets, and
+// broadcast scripts, which probe the local network whatever the target.
+var excludedCategories = []string{"broadcast", "brute", "dos", "exploit", "fuzzer", "intrusive"}
+
+// deniedScriptArgs are script arguments a scan request may not set. They
+// let scripts such as targets-asn add hosts to the scan, which would scan
+// hosts outside the workspace scope.
+var deniedScriptArgs = []string{"newtargets", "max-newtargets"}
+
+// parsedScripts are scripts in an excluded category that run when named,
+// because their results are parsed. ssl-enum-ciphers is intrusive only for
+// the number of handshakes it makes.
+var parsedScripts = map[string]bool{"ssl-enum-ciphers": true}
+
+var (
+	portsPattern     = regexp.MustCompile(`^([TUS]:)?[0-9]*-?[0-9]*(,([TUS]:)?[0-9]*-?[0-9]*)*$`)
+	scriptPattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.*-]*$`)
+	scriptArgPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+// ParseOptions decodes options from JSON and validates them. Empty options
+// are the zero Options.
+func ParseOptions(data json.RawMessage) (Options, error) {
+	var options Options
+	if len(data) == 0 {
+		return options, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&options); err != nil {
+		return options, fmt.Errorf("failed to parse nmap options: %w", err)
+	}
+	return options, options.Validate()
+}
+
+// Validate checks that the options make a valid nmap command line. Only
+// script names are accepted, not paths, so a scan request cannot run
+// scripts from the file system, and script arguments can neither name files
+// for scripts to read or write nor add targets to the scan.
+func (o Options) Validate() error {
+	if o.Ports != "" && (!portsPattern.MatchString(o.Ports) || strings.Trim(o.Ports, ",-:") == "") {
+		return fmt.Errorf("invalid port specification %q", o.Ports)
+	}
+	if o.TopPorts < 0 || o.TopPorts > 65535 {
+		return fmt.Errorf("top_ports must be between 0 and 65535 (0 uses the default)")
+	}
+
+	tcp := ""
+	for _, scanType := range o.ScanTypes {
+		if _, ok := scanTypeFlags[scanType]; !ok {
+			return fmt.Errorf("unknown scan type %q", scanType)
+		}
+		if scanType == "udp" || scanType == "sctp" {
+			continue
+		}
+		if tcp != "" && tcp != scanType {
+			return fmt.Errorf("scan types %s and %s cannot be combined", tcp, scanType)
+		}
+		tcp = scanType
+	}
+
+	if o.VersionIntensity != nil && (*o.VersionIntensity < 0 || *o.VersionIntensity > 9) {
+		return fmt.Errorf("version_intensity must be between 0 and 9")
+	}
+	for _, script := range o.Scripts {
+		if !scriptPattern.MatchString(script) {
+			return fmt.Errorf("invalid script %q: name a script, category or pattern", script)
+		}
+		if script == "all" {
+			return fmt.Errorf("script %q is not allowed: name scripts, categories or patterns", script)
+		}
+		if slices.Contains(excludedCategories, script) {
+			return fmt.Errorf("script category %q is not allowed: %s scripts never run", script, strings.Join(excludedCategories, ", "))
+		}
+	}
+	for key, value := range o.ScriptArgs {
+		if !scriptArgPattern.MatchString(key) {
+			return fmt.Errorf("invalid script argument name %q", key)
+		}
+		if slices.ContainsFunc(deniedScriptArgs, func(denied string) bool { return strings.EqualFold(key, denied) }) {
+			return fmt.Errorf("script argument %s is not allowed: scans may not add targets", key)
+		}
+		if strings.ContainsAny(value, ",={}\"\n/\\") {
+			return fmt.Errorf("invalid value for script argument %s: it may not contain , = { } \" / \\ or newlines", key)
+		}
+	}
+	if _, err := o.timing(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// scanTypes returns the scan types to run and whether they were defaulted.
+func (o Options) scanTypes() ([]string, bool) {
+	if len(o.ScanTypes) == 0 {
+		return []string{"connect", "udp"}, true
+	}
+	return o.ScanTypes, false
+}
+
+// timing returns the number of the timing template, 4 by default.
+func (o Options) timing() (int, error) {
+	if o.Timing == "" {
+		return 4, nil
+	}
+	if n, err := strconv.Atoi(o.Timing); err == nil && n >= 0 && n < len(timingTemplates) {
+		return n, nil
+	}
+	for n, name := range timingTemplates {
+		if strings.EqualFold(o.Timing, name) {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown timing %q: use 0 to 5 or %s", o.Timing, strings.Join(timingTemplates, ", "))
+}
+
+// runsScripts reports whether the options run NSE scripts.
+func (o Options) runsScripts() bool {
+	return len(o.Scripts) > 0 || len(o.ScriptArgs) > 0
+}
+
+// scriptExpression returns the --script expression for Scripts: the
+// parsed scripts they name, or any of the others not in an excluded
+// category.
+func (o Options) scriptExpression() string {
+	var named, selected []string
+	for _, script := range o.Scripts {
+		if parsedScripts[script] {
+			named = append(named, script)
+		} else {
+			selected = append(selected, script)
+		}
+	}
+	if len(selected) > 0 {
+		named = append(named, fmt.Sprintf("((%s) and not (%s))", strings.Join(selected, " or "), strings.Join(excludedCategories, " or ")))
+	}
+	return strings.Join(named, " or ")
+}
+
+// privileged reports whether scanning with scanTypes needs root.
+func (o Options) privileged(scanTypes []string) bool {
+	for _, scanType := range scanTypes {
+		if scanType != "connect" {
+			return true
+		}
+	}
+	return o.OSDetection
+}
+
+// args returns the nmap arguments for the options, without targets and
+// output.
+func (o Options) args(scanTypes []string) []string {
+	timing, _ := o.timing()
+	args := []string{fmt.Sprintf("-T%d", timing)}
+	for _, scanType := range scanTypes {
+		args = append(args, scanTypeFlags[scanType])
+	}
+
+	if o.Ports != "" {
+		args = append(args, "-p", o.Ports)
+	} else {
+		top := o.TopPorts
+		if top == 0 {
+			top = DefaultTopPorts
+		}
+		args = append(args, "--top-ports", strconv.Itoa(top))
+	}
+
+	args = append(args, "-sV")
+	if o.VersionIntensity != nil {
+		args = append(args, "--version-intensity", strconv.Itoa(*o.VersionIntensity))
+	}
+	if o.OSDetection {
+		args = append(args, "-O")
+	}
+	if len(o.Scripts) > 0 {
+		args = append(args, "--script", o.scriptExpression())
+	}
+	if len(o.ScriptArgs) > 0 {
+		keys := make([]string, 0, len(o.ScriptArgs))
+		for key := range o.ScriptArgs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+"="+o.ScriptArgs[key])
+		}
+		args = append(args, "--script-args", strings.Join(pairs, ","))
+	}
+	return args
+}
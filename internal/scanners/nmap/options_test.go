@@ -0,0 +1,87 @@
+package nmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateScriptArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr string
+	}{
+		{
+			name:    "plain argument",
+			options: Options{Scripts: []string{"http-title"}, ScriptArgs: map[string]string{"http.useragent": "asm"}},
+		},
+		{
+			name:    "newtargets",
+			options: Options{Scripts: []string{"targets-asn"}, ScriptArgs: map[string]string{"newtargets": "1", "targets-asn.asn": "32"}},
+			wantErr: "newtargets is not allowed",
+		},
+		{
+			name:    "max-newtargets",
+			options: Options{Scripts: []string{"targets-asn"}, ScriptArgs: map[string]string{"max-newtargets": "100"}},
+			wantErr: "max-newtargets is not allowed",
+		},
+		{
+			name:    "newtargets in another case",
+			options: Options{ScriptArgs: map[string]string{"NewTargets": "1"}},
+			wantErr: "NewTargets is not allowed",
+		},
+		{
+			name:    "path value",
+			options: Options{ScriptArgs: map[string]string{"http.useragent": "/etc/passwd"}},
+			wantErr: "invalid value",
+		},
+		{
+			name:    "broadcast category",
+			options: Options{Scripts: []string{"broadcast"}},
+			wantErr: `script category "broadcast" is not allowed`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTopPorts(t *testing.T) {
+	tests := []struct {
+		topPorts int
+		wantErr  bool
+	}{
+		{0, false},
+		{1, false},
+		{65535, false},
+		{-1, true},
+		{65536, true},
+	}
+	for _, tt := range tests {
+		err := Options{TopPorts: tt.topPorts}.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate with top_ports %d = %v, want error %v", tt.topPorts, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "between 0 and 65535 (0 uses the default)") {
+			t.Errorf("Validate with top_ports %d = %v, want the accepted range", tt.topPorts, err)
+		}
+	}
+}
+
+func TestScriptExpressionExcludesBroadcast(t *testing.T) {
+	got := Options{Scripts: []string{"discovery"}}.scriptExpression()
+	if !strings.Contains(got, "not (broadcast or ") {
+		t.Errorf("scriptExpression = %q, want broadcast scripts excluded", got)
+	}
+}
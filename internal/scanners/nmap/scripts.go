@@ -0,0 +1,250 @@
+package nmap
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ScriptResults are the typed results of the NSE scripts ASM understands,
+// extracted from their structured output. Each is set only if its script
+// ran and reported a result.
+type ScriptResults struct {
+	HTTPTitle       *HTTPTitle       `json:"http_title,omitempty"`
+	HTTPHeaders     []HTTPHeader     `json:"http_headers,omitempty"`
+	TLSCiphers      *TLSCiphers      `json:"ssl_enum_ciphers,omitempty"`
+	SMBSecurityMode *SMBSecurityMode `json:"smb_security_mode,omitempty"`
+	Vulnerabilities []Vulnerability  `json:"vulnerabilities,omitempty"`
+}
+
+// HTTPTitle is the result of http-title.
+type HTTPTitle struct {
+	Title string `json:"title"`
+	// RedirectURL is where the server redirected to, when it did to
+	// another host or port.
+	RedirectURL string `json:"redirect_url,omitempty"`
+}
+
+// HTTPHeader is a response header reported by http-headers.
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TLSCiphers is the result of ssl-enum-ciphers: the cipher suites the
+// server accepts for each protocol version, graded from A to F.
+type TLSCiphers struct {
+	Protocols []TLSProtocol `json:"protocols"`
+	// LeastStrength is the grade of the weakest cipher suite accepted.
+	LeastStrength string `json:"least_strength,omitempty"`
+}
+
+// TLSProtocol is a protocol version accepted by the server, such as
+// "TLSv1.2", and its cipher suites.
+type TLSProtocol struct {
+	Version string      `json:"version"`
+	Ciphers []TLSCipher `json:"ciphers"`
+	// CipherPreference is "server" or "client", whichever orders the
+	// cipher suites.
+	CipherPreference string   `json:"cipher_preference,omitempty"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+type TLSCipher struct {
+	Name     string `json:"name"`
+	KexInfo  string `json:"kex_info,omitempty"`
+	Strength string `json:"strength,omitempty"`
+}
+
+// SMBSecurityMode is the result of smb-security-mode.
+type SMBSecurityMode struct {
+	AccountUsed         string `json:"account_used,omitempty"`
+	AuthenticationLevel string `json:"authentication_level,omitempty"`
+	ChallengeResponse   string `json:"challenge_response,omitempty"`
+	// MessageSigning is "required", "supported" or "disabled".
+	MessageSigning string `json:"message_signing,omitempty"`
+}
+
+// Vulnerability is a vulnerability vulners reported for a service, by
+// the CPE version detection found for it.
+type Vulnerability struct {
+	CPE string `json:"cpe"`
+	ID  string `json:"id"`
+	// Type is the vulners database of ID, such as "cve" or
+	// "githubexploit".
+	Type    string  `json:"type"`
+	CVSS    float64 `json:"cvss"`
+	Exploit bool    `json:"exploit,omitempty"`
+}
+
+// IsCVE reports whether the vulnerability is a CVE entry, rather than an
+// exploit or advisory referring to one.
+func (v Vulnerability) IsCVE() bool {
+	return strings.EqualFold(v.Type, "cve") || strings.HasPrefix(v.ID, "CVE-")
+}
+
+// scriptExtractors fill in ScriptResults from the structured output of the
+// scripts ASM understands, by script id.
+var scriptExtractors = map[string]func(Script, *ScriptResults){
+	"http-title":        extractHTTPTitle,
+	"http-headers":      extractHTTPHeaders,
+	"ssl-enum-ciphers":  extractTLSCiphers,
+	"smb-security-mode": extractSMBSecurityMode,
+	"vulners":           extractVulners,
+}
+
+// ExtractScripts returns the results of the scripts ASM understands among
+// scripts, or nil if there are none.
+func ExtractScripts(scripts []Script) *ScriptResults {
+	var results ScriptResults
+	for _, script := range scripts {
+		if extract, ok := scriptExtractors[script.Name]; ok {
+			extract(script, &results)
+		}
+	}
+	if results.HTTPTitle == nil && len(results.HTTPHeaders) == 0 && results.TLSCiphers == nil &&
+		results.SMBSecurityMode == nil && len(results.Vulnerabilities) == 0 {
+		return nil
+	}
+	return &results
+}
+
+// extractScripts sets the ScriptResults of every host and port of the run.
+func (r *NmapRun) extractScripts() {
+	for i := range r.Hosts {
+		host := &r.Hosts[i]
+		host.ScriptResults = ExtractScripts(host.Scripts)
+		for j := range host.Ports {
+			host.Ports[j].ScriptResults = ExtractScripts(host.Ports[j].Scripts)
+		}
+	}
+}
+
+func extractHTTPTitle(script Script, results *ScriptResults) {
+	title := script.Elem("title")
+	if title == "" {
+		return
+	}
+	results.HTTPTitle = &HTTPTitle{Title: title, RedirectURL: script.Elem("redirect_url")}
+}
+
+// extractHTTPHeaders parses the "Name: value" lines http-headers reports,
+// skipping its notes such as "(Request type: HEAD)".
+func extractHTTPHeaders(script Script, results *ScriptResults) {
+	for _, line := range script.AllValues() {
+		name, value, found := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.HasPrefix(name, "(") || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		results.HTTPHeaders = append(results.HTTPHeaders, HTTPHeader{Name: name, Value: strings.TrimSpace(value)})
+	}
+}
+
+func extractTLSCiphers(script Script, results *ScriptResults) {
+	ciphers := TLSCiphers{LeastStrength: script.Elem("least strength")}
+	for _, table := range script.Tables {
+		if table.Key == "" {
+			continue
+		}
+		protocol := TLSProtocol{
+			Version:          table.Key,
+			Ciphers:          []TLSCipher{},
+			CipherPreference: table.Elem("cipher preference"),
+		}
+		if list, ok := table.Subtable("ciphers"); ok {
+			for _, cipher := range list.Tables {
+				protocol.Ciphers = append(protocol.Ciphers, TLSCipher{
+					Name:     cipher.Elem("name"),
+					KexInfo:  cipher.Elem("kex_info"),
+					Strength: cipher.Elem("strength"),
+				})
+			}
+		}
+		if warnings, ok := table.Subtable("warnings"); ok {
+			protocol.Warnings = warnings.Values()
+		}
+		ciphers.Protocols = append(ciphers.Protocols, protocol)
+	}
+	if len(ciphers.Protocols) == 0 {
+		return
+	}
+	results.TLSCiphers = &ciphers
+}
+
+func extractSMBSecurityMode(script Script, results *ScriptResults) {
+	mode := SMBSecurityMode{
+		AccountUsed:         script.Elem("account_used"),
+		AuthenticationLevel: script.Elem("authentication_level"),
+		ChallengeResponse:   script.Elem("challenge_response"),
+		MessageSigning:      script.Elem("message_signing"),
+	}
+	if mode == (SMBSecurityMode{}) {
+		return
+	}
+	results.SMBSecurityMode = &mode
+}
+
+// extractVulners reads the vulnerabilities vulners lists in a table per
+// CPE, one table per vulnerability.
+func extractVulners(script Script, results *ScriptResults) {
+	for _, cpe := range script.Tables {
+		for _, entry := range cpe.Tables {
+			id := entry.Elem("id")
+			if id == "" {
+				continue
+			}
+			cvss, _ := strconv.ParseFloat(entry.Elem("cvss"), 64)
+			results.Vulnerabilities = append(results.Vulnerabilities, Vulnerability{
+				CPE:     cpe.Key,
+				ID:      id,
+				Type:    entry.Elem("type"),
+				CVSS:    cvss,
+				Exploit: entry.Elem("is_exploit") == "true",
+			})
+		}
+	}
+}
+
+// Elem returns the value of the element with key, or "".
+func (t Table) Elem(key string) string {
+	for _, elem := range t.Elements {
+		if elem.Key == key {
+			return strings.TrimSpace(elem.Value)
+		}
+	}
+	return ""
+}
+
+// Subtable returns the nested table with key.
+func (t Table) Subtable(key string) (Table, bool) {
+	for _, table := range t.Tables {
+		if table.Key == key {
+			return table, true
+		}
+	}
+	return Table{}, false
+}
+
+// Values returns the values of the unkeyed elements, in order.
+func (t Table) Values() []string {
+	var values []string
+	for _, elem := range t.Elements {
+		if elem.Key == "" {
+			values = append(values, strings.TrimSpace(elem.Value))
+		}
+	}
+	return values
+}
+
+// AllValues returns the values of the unkeyed elements of the table and
+// of its unkeyed tables, depth first, for scripts that report a list
+// nested in tables.
+func (t Table) AllValues() []string {
+	values := t.Values()
+	for _, table := range t.Tables {
+		if table.Key == "" {
+			values = append(values, table.AllValues()...)
+		}
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package nmap
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseRunScripts(t *testing.T) {
+	data, err := os.ReadFile("testdata/scripts.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := parseRun(data)
+	if err != nil {
+		t.Fatalf("parseRun: %v", err)
+	}
+	if len(run.Hosts) != 1 || len(run.Hosts[0].Ports) != 2 {
+		t.Fatalf("parseRun found %d hosts, want 1 with 2 ports", len(run.Hosts))
+	}
+	host := run.Hosts[0]
+
+	wantHost := &ScriptResults{
+		SMBSecurityMode: &SMBSecurityMode{
+			AccountUsed:         "guest",
+			AuthenticationLevel: "user",
+			ChallengeResponse:   "supported",
+			MessageSigning:      "disabled",
+		},
+	}
+	if !reflect.DeepEqual(host.ScriptResults, wantHost) {
+		t.Errorf("host script results = %+v, want %+v", host.ScriptResults, wantHost)
+	}
+
+	wantSSH := &ScriptResults{
+		Vulnerabilities: []Vulnerability{
+			{CPE: "cpe:/a:openbsd:openssh:7.4", ID: "CVE-2023-38408", Type: "cve", CVSS: 9.8},
+			{CPE: "cpe:/a:openbsd:openssh:7.4", ID: "5E6968B4-DBD6-57FA-BF6E-D9B2219DB27A", Type: "githubexploit", CVSS: 9.8, Exploit: true},
+			{CPE: "cpe:/a:openbsd:openssh:7.4", ID: "CVE-2021-41617", Type: "cve", CVSS: 7.0},
+		},
+	}
+	if got := host.Ports[0].ScriptResults; !reflect.DeepEqual(got, wantSSH) {
+		t.Errorf("port 22 script results = %+v, want %+v", got, wantSSH)
+	}
+
+	wantHTTPS := &ScriptResults{
+		TLSCiphers: &TLSCiphers{
+			LeastStrength: "C",
+			Protocols: []TLSProtocol{
+				{
+					Version: "TLSv1.2",
+					Ciphers: []TLSCipher{
+						{Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", KexInfo: "secp256r1", Strength: "A"},
+						{Name: "TLS_RSA_WITH_3DES_EDE_CBC_SHA", KexInfo: "rsa 2048", Strength: "C"},
+					},
+					CipherPreference: "server",
+					Warnings:         []string{"64-bit block cipher 3DES vulnerable to SWEET32 attack"},
+				},
+				{
+					Version: "TLSv1.3",
+					Ciphers: []TLSCipher{
+						{Name: "TLS_AKE_WITH_AES_256_GCM_SHA384", KexInfo: "ecdh_x25519", Strength: "A"},
+					},
+					CipherPreference: "server",
+				},
+			},
+		},
+	}
+	if got := host.Ports[1].ScriptResults; !reflect.DeepEqual(got, wantHTTPS) {
+		t.Errorf("port 443 script results = %+v, want %+v", got, wantHTTPS)
+	}
+}
+
+func TestVulnerabilityIsCVE(t *testing.T) {
+	tests := []struct {
+		vulnerability Vulnerability
+		want          bool
+	}{
+		{Vulnerability{ID: "CVE-2023-38408", Type: "cve"}, true},
+		{Vulnerability{ID: "CVE-2023-38408", Type: "CVE"}, true},
+		{Vulnerability{ID: "CVE-2021-41617"}, true},
+		{Vulnerability{ID: "5E6968B4-DBD6-57FA-BF6E-D9B2219DB27A", Type: "githubexploit"}, false},
+		{Vulnerability{ID: "PACKETSTORM:173661", Type: "packetstorm"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.vulnerability.IsCVE(); got != tt.want {
+			t.Errorf("IsCVE(%+v) = %v, want %v", tt.vulnerability, got, tt.want)
+		}
+	}
+}
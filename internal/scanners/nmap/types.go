@@ -11,35 +11,214 @@ type NmapScanner struct {
 	*scanners.BaseScanner
 	installClient client.ToolInstaller
 }
+
+// NmapRun is the root of nmap's XML output (-oX).
 type NmapRun struct {
-	XMLName xml.Name `xml:"nmaprun"`
-	Hosts   []Host   `xml:"host"`
+	XMLName          xml.Name   `xml:"nmaprun"`
+	Scanner          string     `xml:"scanner,attr"`
+	Args             string     `xml:"args,attr"`
+	Start            int64      `xml:"start,attr"`
+	Version          string     `xml:"version,attr"`
+	XMLOutputVersion string     `xml:"xmloutputversion,attr"`
+	ScanInfo         []ScanInfo `xml:"scaninfo"`
+	Hosts            []Host     `xml:"host"`
+	RunStats         RunStats   `xml:"runstats"`
+}
+
+// ScanInfo describes one scan type of the run and the ports it covered.
+type ScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+// RunStats reports how the run ended.
+type RunStats struct {
+	Finished struct {
+		Time    int64   `xml:"time,attr"`
+		Elapsed float64 `xml:"elapsed,attr"`
+		Summary string  `xml:"summary,attr"`
+		// Exit is "success" or "error", with ErrorMsg set.
+		Exit     string `xml:"exit,attr"`
+		ErrorMsg string `xml:"errormsg,attr"`
+	} `xml:"finished"`
+	Hosts struct {
+		Up    int `xml:"up,attr"`
+		Down  int `xml:"down,attr"`
+		Total int `xml:"total,attr"`
+	} `xml:"hosts"`
 }
 
+// Host is everything nmap found out about one host.
 type Host struct {
-	Ports []Port `xml:"ports>port"`
+	StartTime  int64        `xml:"starttime,attr" json:"start_time,omitempty"`
+	EndTime    int64        `xml:"endtime,attr" json:"end_time,omitempty"`
+	Status     HostStatus   `xml:"status" json:"status"`
+	Addresses  []Address    `xml:"address" json:"addresses"`
+	Hostnames  []Hostname   `xml:"hostnames>hostname" json:"hostnames,omitempty"`
+	Ports      []Port       `xml:"ports>port" json:"ports"`
+	ExtraPorts []ExtraPorts `xml:"ports>extraports" json:"extra_ports,omitempty"`
+	OS         OS           `xml:"os" json:"os"`
+	Uptime     *Uptime      `xml:"uptime" json:"uptime,omitempty"`
+	Distance   *Distance    `xml:"distance" json:"distance,omitempty"`
+	Times      *Times       `xml:"times" json:"times,omitempty"`
+	// Scripts is the output of host scripts, such as smb-security-mode;
+	// port scripts are on their Port.
+	Scripts []Script `xml:"hostscript>script" json:"scripts,omitempty"`
+	// ScriptResults are the results of Scripts that ASM understands.
+	ScriptResults *ScriptResults `xml:"-" json:"script_results,omitempty"`
+}
+
+type HostStatus struct {
+	State  string `xml:"state,attr" json:"state"`
+	Reason string `xml:"reason,attr" json:"reason"`
+}
+
+// Address is an address of a host. Type is "ipv4", "ipv6" or "mac".
+type Address struct {
+	Addr   string `xml:"addr,attr" json:"addr"`
+	Type   string `xml:"addrtype,attr" json:"type"`
+	Vendor string `xml:"vendor,attr" json:"vendor,omitempty"`
+}
+
+// Hostname is a name of a host. Type is "user" for the name nmap was given
+// and "PTR" for one found by reverse DNS.
+type Hostname struct {
+	Name string `xml:"name,attr" json:"name"`
+	Type string `xml:"type,attr" json:"type"`
+}
+
+// ExtraPorts summarizes the ports nmap did not list one by one, such as
+// 980 closed ports.
+type ExtraPorts struct {
+	State string `xml:"state,attr" json:"state"`
+	Count int    `xml:"count,attr" json:"count"`
 }
 
 type Port struct {
-	PortID       string   `xml:"portid,attr"`
-	Protocol     string   `xml:"protocol,attr"`
-	StateDetails State    `xml:"state"`
-	Service      Service  `xml:"service"`
-	Scripts      []Script `xml:"script"`
+	PortID       string   `xml:"portid,attr" json:"port"`
+	Protocol     string   `xml:"protocol,attr" json:"protocol"`
+	StateDetails State    `xml:"state" json:"state"`
+	Service      Service  `xml:"service" json:"service"`
+	Scripts      []Script `xml:"script" json:"scripts,omitempty"`
+	// ScriptResults are the results of Scripts that ASM understands.
+	ScriptResults *ScriptResults `xml:"-" json:"script_results,omitempty"`
 }
 
+// Script is the output of an NSE script: the text nmap prints, and the
+// same result as elements and tables for scripts that report structured
+// output.
 type Script struct {
-	Name   string `xml:"id,attr"`
-	Result string `xml:"output,attr"`
+	Name   string `xml:"id,attr" json:"id"`
+	Result string `xml:"output,attr" json:"output"`
+	Table
+}
+
+// Table is a table of structured script output. Its elements and nested
+// tables are keyed when the script reports a record, and unkeyed when it
+// reports a list.
+type Table struct {
+	Key      string  `xml:"key,attr" json:"key,omitempty"`
+	Elements []Elem  `xml:"elem" json:"elements,omitempty"`
+	Tables   []Table `xml:"table" json:"tables,omitempty"`
+}
+
+// Elem is a value of structured script output.
+type Elem struct {
+	Key   string `xml:"key,attr" json:"key,omitempty"`
+	Value string `xml:",chardata" json:"value"`
 }
 
 type State struct {
-	Value     string `xml:"state,attr"`
-	Reason    string `xml:"reason,attr"`
-	ReasonTTL string `xml:"reason_ttl,attr"`
+	Value     string `xml:"state,attr" json:"state"`
+	Reason    string `xml:"reason,attr" json:"reason"`
+	ReasonTTL string `xml:"reason_ttl,attr" json:"reason_ttl"`
 }
 
+// Service is what nmap detected listening on a port. Method is "probed"
+// when version detection identified it and "table" when it is only the
+// usual service of the port number.
 type Service struct {
-	Name    string `xml:"name,attr"`
-	Product string `xml:"product,attr,omitempty"`
+	Name       string   `xml:"name,attr" json:"name"`
+	Product    string   `xml:"product,attr,omitempty" json:"product,omitempty"`
+	Version    string   `xml:"version,attr,omitempty" json:"version,omitempty"`
+	ExtraInfo  string   `xml:"extrainfo,attr,omitempty" json:"extra_info,omitempty"`
+	Hostname   string   `xml:"hostname,attr,omitempty" json:"hostname,omitempty"`
+	OSType     string   `xml:"ostype,attr,omitempty" json:"os_type,omitempty"`
+	DeviceType string   `xml:"devicetype,attr,omitempty" json:"device_type,omitempty"`
+	Tunnel     string   `xml:"tunnel,attr,omitempty" json:"tunnel,omitempty"`
+	Method     string   `xml:"method,attr,omitempty" json:"method,omitempty"`
+	Confidence int      `xml:"conf,attr,omitempty" json:"confidence,omitempty"`
+	CPE        []string `xml:"cpe" json:"cpe,omitempty"`
+}
+
+// OS holds the operating system guesses of OS detection (-O), best first.
+type OS struct {
+	Matches []OSMatch `xml:"osmatch" json:"matches,omitempty"`
+}
+
+type OSMatch struct {
+	Name     string    `xml:"name,attr" json:"name"`
+	Accuracy int       `xml:"accuracy,attr" json:"accuracy"`
+	Classes  []OSClass `xml:"osclass" json:"classes,omitempty"`
+}
+
+type OSClass struct {
+	Type       string   `xml:"type,attr" json:"type,omitempty"`
+	Vendor     string   `xml:"vendor,attr" json:"vendor,omitempty"`
+	Family     string   `xml:"osfamily,attr" json:"family,omitempty"`
+	Generation string   `xml:"osgen,attr" json:"generation,omitempty"`
+	Accuracy   int      `xml:"accuracy,attr" json:"accuracy"`
+	CPE        []string `xml:"cpe" json:"cpe,omitempty"`
+}
+
+// Uptime is nmap's estimate of how long the host has been up.
+type Uptime struct {
+	Seconds  int64  `xml:"seconds,attr" json:"seconds"`
+	LastBoot string `xml:"lastboot,attr" json:"last_boot"`
+}
+
+// Distance is the number of network hops to the host.
+type Distance struct {
+	Value int `xml:"value,attr" json:"value"`
+}
+
+// Times are the round-trip timing nmap measured for the host, in
+// microseconds.
+type Times struct {
+	SRTT   int `xml:"srtt,attr" json:"srtt"`
+	RTTVar int `xml:"rttvar,attr" json:"rttvar"`
+	To     int `xml:"to,attr" json:"timeout"`
+}
+
+// Address returns the IP address of the host, preferring IPv4.
+func (h Host) Address() string {
+	address := ""
+	for _, a := range h.Addresses {
+		switch a.Type {
+		case "ipv4":
+			return a.Addr
+		case "ipv6":
+			if address == "" {
+				address = a.Addr
+			}
+		}
+	}
+	return address
+}
+
+// Hostname returns the name the host was scanned by, or else the first
+// name reverse DNS found for it.
+func (h Host) Hostname() string {
+	name := ""
+	for _, hostname := range h.Hostnames {
+		if hostname.Type == "user" {
+			return hostname.Name
+		}
+		if name == "" {
+			name = hostname.Name
+		}
+	}
+	return name
 }
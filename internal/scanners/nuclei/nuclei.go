@@ -2,30 +2,33 @@ package nuclei
 
 import (
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/IxBahy/ASM/internal/scanners"
 	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
+)
+
+const (
+	templatesName = "nuclei-templates"
+	// templatesURL is an archive of the default branch of the templates
+	// repository, which nuclei itself would download on first run.
+	templatesURL = "https://api.github.com/repos/projectdiscovery/nuclei-templates/tarball"
 )
 
 type NucleiScanner struct {
 	*scanners.BaseScanner
-	installClient client.ToolInstaller
 }
 
 func NewNucleiScanner() *NucleiScanner {
-	config := scanners.ScannerConfig{
-		Name:    "nuclei",
-		Version: "latest",
-		GithubOptions: scanners.GithubOptions{
-			InstallLink:    "https://api.github.com/repos/projectdiscovery/nuclei/releases/latest",
-			InstallPattern: "nuclei_(.*)_linux_amd64.zip",
-		},
-		ExecutablePath:   "/usr/local/bin/nuclei",
-		Base_Command:     "nuclei -t cves/ -u",
-		InstallationType: client.InstallationTypeGithub,
-	}
+	config := scanners.CatalogConfig("nuclei")
+	config.Base_Command = "nuclei -jsonl -t http/cves/ -u"
+	config.Intrusive = true
+	// Older releases do not support the flags passed in Base_Command, such
+	// as -jsonl.
+	config.MinVersion = "3.0.0"
 	base := &scanners.BaseScanner{
 		Config: config,
 		InstallState: scanners.InstallationState{
@@ -42,27 +45,39 @@ func NewNucleiScanner() *NucleiScanner {
 
 func (s *NucleiScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
-	installArgs := []string{
-		s.Config.GithubOptions.InstallLink,
-		s.Config.GithubOptions.InstallPattern,
-		s.Config.Version,
-		s.Config.ExecutablePath,
+	if err := s.Install(); err != nil {
+		return err
 	}
 
-	var err error
-	s.installClient, err = client.ClientFactory(s.Config.InstallationType, installArgs, 5)
+	// Offline, nuclei cannot fetch its templates on first run, so they are
+	// installed from the mirror where nuclei looks for them.
+	mirror, err := client.MirrorFromEnv()
 	if err != nil {
-		return fmt.Errorf("failed to create install client: %w", err)
+		return err
 	}
-
-	if err := s.installClient.InstallTool(); err != nil {
-		return fmt.Errorf("failed to install nuclei: %w", err)
+	if mirror != nil {
+		if err := mirror.ExtractTemplates(templatesName, templatesDir()); err != nil {
+			return fmt.Errorf("failed to install nuclei templates: %w", err)
+		}
 	}
+	return nil
+}
 
-	return s.RegisterInstallationStats()
+// Bundle adds the nuclei templates to an offline bundle.
+func (s *NucleiScanner) Bundle(builder *client.BundleBuilder) error {
+	return builder.AddTemplates(templatesName, templatesURL)
+}
+
+// templatesDir is nuclei's default templates directory.
+func templatesDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return templatesName
+	}
+	return filepath.Join(home, templatesName)
 }
 
 func (s *NucleiScanner) Scan(target string) (scanners.ScannerResult, error) {
@@ -70,12 +85,19 @@ func (s *NucleiScanner) Scan(target string) (scanners.ScannerResult, error) {
 		return scanners.ScannerResult{}, fmt.Errorf("nuclei is not installed")
 	}
 
+	parsed, err := extractor.Parse(target)
+	if err == nil && parsed.Wildcard {
+		err = fmt.Errorf("nuclei scans hosts and networks, not %s", parsed)
+	}
+	if err != nil {
+		return scanners.ScannerResult{Data: []string{}, Errors: []string{err.Error()}}, err
+	}
+
 	cmdParts := strings.Fields(s.Config.Base_Command)
 
-	cmdParts = append(cmdParts, target)
+	cmdParts = append(cmdParts, parsed.String())
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, err := s.CombinedOutput(cmdParts)
 
 	result := scanners.ScannerResult{
 		Data:   []string{},
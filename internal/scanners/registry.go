@@ -3,18 +3,73 @@ package scanners
 import (
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 )
 
 type ScannerRegistry struct {
 	scanners map[string]Scanner
+	// locks serialize the installs of each scanner with its scans, since
+	// Setup changes the installation state and configuration scans read.
+	locks map[string]*sync.RWMutex
+	// statuses are the last known statuses of the scanners, for callers
+	// that must not probe a tool while it may be installing.
+	statuses map[string]ScannerStatus
 	mu       sync.RWMutex
 }
 
+// ScannerStatus is a snapshot of a scanner's configuration and
+// installation state.
+type ScannerStatus struct {
+	Config    ScannerConfig
+	Installed bool
+	State     InstallationState
+}
+
 func NewScannerRegistry() *ScannerRegistry {
 	return &ScannerRegistry{
 		scanners: make(map[string]Scanner),
+		locks:    make(map[string]*sync.RWMutex),
+		statuses: make(map[string]ScannerStatus),
+	}
+}
+
+// snapshot returns the current status of scanner. It may probe the tool,
+// so the caller must hold the scanner's lock or own it exclusively.
+func snapshot(scanner Scanner) ScannerStatus {
+	installed := scanner.IsInstalled()
+	return ScannerStatus{
+		Config:    scanner.GetConfig(),
+		Installed: installed,
+		State:     scanner.GetInstallationState(),
+	}
+}
+
+// add stores scanner and its status. The caller must hold r.mu.
+func (r *ScannerRegistry) add(scanner Scanner) {
+	name := scanner.GetConfig().Name
+	r.scanners[name] = scanner
+	if _, exists := r.locks[name]; !exists {
+		r.locks[name] = &sync.RWMutex{}
 	}
+	r.statuses[name] = snapshot(scanner)
+}
+
+// entry returns the scanner with name and its lock.
+func (r *ScannerRegistry) entry(name string) (Scanner, *sync.RWMutex, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scanner, exists := r.scanners[name]
+	return scanner, r.locks[name], exists
+}
+
+// setStatus records the status of the scanner with name.
+func (r *ScannerRegistry) setStatus(name string, status ScannerStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statuses[name] = status
 }
 
 func (r *ScannerRegistry) Register(scanner Scanner) {
@@ -27,11 +82,20 @@ func (r *ScannerRegistry) Register(scanner Scanner) {
 		log.Fatalf("Failed to setup %s scanner: %v", config.Name, err)
 	}
 
-	r.scanners[config.Name] = scanner
+	r.add(scanner)
 	fmt.Printf("Scanner '%s' registered in registry\n", config.Name)
 
 }
 
+// Add stores the scanner in the registry without running its Setup, so
+// callers can list tools and their installation state before installing them.
+func (r *ScannerRegistry) Add(scanner Scanner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.add(scanner)
+}
+
 func (r *ScannerRegistry) Get(name string) (Scanner, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -51,3 +115,80 @@ func (r *ScannerRegistry) GetAll() map[string]Scanner {
 
 	return result
 }
+
+// Names returns the names of all registered scanners in sorted order.
+func (r *ScannerRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.scanners))
+	for name := range r.scanners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Status returns the last known status of the scanner with name, without
+// probing its tool, so it can be read while the scanner is installing.
+func (r *ScannerRegistry) Status(name string) (ScannerStatus, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status, exists := r.statuses[name]
+	return status, exists
+}
+
+// Statuses returns the last known status of every scanner, by name.
+func (r *ScannerRegistry) Statuses() map[string]ScannerStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]ScannerStatus, len(r.statuses))
+	for k, v := range r.statuses {
+		result[k] = v
+	}
+	return result
+}
+
+// Install runs the Setup of the scanner with name and returns its new
+// status. It waits for running scans of the scanner to finish, and holds
+// off new ones and other installs of it until it is done.
+func (r *ScannerRegistry) Install(name string) (ScannerStatus, error) {
+	scanner, lock, exists := r.entry(name)
+	if !exists {
+		return ScannerStatus{}, fmt.Errorf("unknown scanner: %s", name)
+	}
+
+	lock.Lock()
+	err := scanner.Setup()
+	status := snapshot(scanner)
+	lock.Unlock()
+
+	r.setStatus(name, status)
+	return status, err
+}
+
+// Acquire returns the scanner with name for a scan, with whether its tool
+// is installed, and holds off installs of it until release is called. The
+// scanner is nil, and release a no-op, if there is no scanner with name.
+func (r *ScannerRegistry) Acquire(name string) (scanner Scanner, installed bool, release func()) {
+	scanner, lock, exists := r.entry(name)
+	if !exists {
+		return nil, false, func() {}
+	}
+
+	// Checking the installation may register it, so it is done under the
+	// write lock; scans then share the read lock.
+	lock.Lock()
+	status := snapshot(scanner)
+	lock.Unlock()
+	r.setStatus(name, status)
+	if !status.Installed {
+		return scanner, false, func() {}
+	}
+
+	lock.RLock()
+	return scanner, true, lock.RUnlock
+}
@@ -1,15 +1,18 @@
 package scanners
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
-	"regexp"
-	"strings"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/IxBahy/ASM/pkg/client"
 	"github.com/IxBahy/ASM/pkg/interfaces"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 type Scanner interface {
@@ -20,18 +23,171 @@ type Scanner interface {
 	RegisterInstallationStats() error
 }
 
+// Runnable is implemented by scanners whose Scan method reports its output
+// as a ScannerResult.
+type Runnable interface {
+	Scan(target string) (ScannerResult, error)
+}
+
+// ResultScanner is implemented by scanners whose Scan method returns a typed
+// result, and which can also report that result as a ScannerResult.
+type ResultScanner interface {
+	ScanResult(target string) (ScannerResult, error)
+}
+
+// OptionsScanner is implemented by scanners that take options with a scan
+// request, as a JSON object of their own.
+type OptionsScanner interface {
+	// ValidateOptions checks options without scanning, so a request with
+	// bad options is rejected before it is queued.
+	ValidateOptions(options json.RawMessage) error
+	ScanResultWithOptions(target string, options json.RawMessage) (ScannerResult, error)
+}
+
 type ScannerConfig struct {
 	Name             string
 	Version          string
 	GithubOptions    GithubOptions
+	GoOptions        GoOptions
+	PythonOptions    PythonOptions
 	ExecutablePath   string
 	Base_Command     string
 	InstallationType client.InstallationType
+	// Intrusive scanners actively probe or attack their targets, as opposed
+	// to passive lookups; running them through the API requires admin.
+	Intrusive bool
+	// VersionProbe tells how to read the installed version of the tool.
+	VersionProbe VersionProbe
+	// MinVersion is the oldest version of the tool that supports the flags
+	// the scanner passes. Setup refuses older versions.
+	MinVersion string
+	// Timeout bounds a scan run, after which the tool is killed.
+	// DefaultScanTimeout if zero.
+	Timeout time.Duration
+}
+
+// DefaultScanTimeout bounds the scan runs of scanners without a Timeout.
+const DefaultScanTimeout = time.Hour
+
+// scanKillDelay is how long a tool has to exit after being asked to stop
+// at its deadline before it is killed.
+const scanKillDelay = 10 * time.Second
+
+// VersionProbe runs the tool to read its version.
+type VersionProbe struct {
+	// Args are the arguments printing the version, "--version" by default.
+	Args []string
+	// Pattern is a regular expression matching the version in the output;
+	// its first group, if any, is the version. By default the first version
+	// number in the output is used.
+	Pattern string
 }
 type GithubOptions struct {
-	InstallLink    string
+	InstallLink string
+	// InstallPattern is a regular expression matching the release asset to
+	// install. The {{os}} and {{arch}} placeholders are resolved for the
+	// running platform, so one pattern covers every supported runner.
 	InstallPattern string
+	// ChecksumPattern matches the release's checksums file; empty uses
+	// client.DefaultChecksumPattern.
+	ChecksumPattern string
+	// SHA256 pins the digest of the asset instead of using the checksums file.
+	SHA256 string
+	// MinisignPublicKey and CosignPublicKey pin the keys the checksums file
+	// (or the asset) must be signed with.
+	MinisignPublicKey string
+	CosignPublicKey   string
+	// AllowUnverified permits installing from releases without a checksums
+	// file.
+	AllowUnverified bool
+	// Prerelease considers pre-releases when looking for the latest release
+	// or the one tagged with the configured Version.
+	Prerelease bool
+	// ArchiveMember is the path of the executable in the release archive,
+	// or a glob such as "*/bin/tool". By default the member named after the
+	// executable is used.
+	ArchiveMember string
+}
+
+// InstallArgs returns the arguments for a GitHub install client that
+// downloads version to destPath and verifies it as configured.
+func (o GithubOptions) InstallArgs(version, destPath string) []string {
+	args := []string{o.InstallLink, o.InstallPattern, version, destPath}
+	if o.ChecksumPattern != "" {
+		args = append(args, "checksum-pattern="+o.ChecksumPattern)
+	}
+	if o.SHA256 != "" {
+		args = append(args, "sha256="+o.SHA256)
+	}
+	if o.MinisignPublicKey != "" {
+		args = append(args, "minisign-key="+o.MinisignPublicKey)
+	}
+	if o.CosignPublicKey != "" {
+		args = append(args, "cosign-key="+o.CosignPublicKey)
+	}
+	if o.AllowUnverified {
+		args = append(args, "allow-unverified=true")
+	}
+	if o.Prerelease {
+		args = append(args, "prerelease=true")
+	}
+	if o.ArchiveMember != "" {
+		args = append(args, "member="+o.ArchiveMember)
+	}
+	return args
+}
+
+// Verification returns the verification settings of a GitHub install.
+func (o GithubOptions) Verification() client.Verification {
+	return client.Verification{
+		ChecksumPattern:   o.ChecksumPattern,
+		SHA256:            o.SHA256,
+		MinisignPublicKey: o.MinisignPublicKey,
+		CosignPublicKey:   o.CosignPublicKey,
+		AllowUnverified:   o.AllowUnverified,
+	}
+}
+
+// GoOptions configures scanners installed with client.InstallationTypeInternal,
+// which are built from a Go package at the configured Version.
+type GoOptions struct {
+	// Package is the path of the main package, such as
+	// "github.com/projectdiscovery/httpx/cmd/httpx".
+	Package string
+	// Offline builds only from the local module cache and toolchain.
+	Offline bool
+}
+
+// InstallArgs returns the arguments for an internal install client that
+// builds version into dir.
+func (o GoOptions) InstallArgs(version, dir string) []string {
+	args := []string{o.Package, version, dir}
+	if o.Offline {
+		args = append(args, "offline=true")
+	}
+	return args
 }
+
+// PythonOptions configures scanners installed with
+// client.InstallationTypePython.
+type PythonOptions struct {
+	// Package is the name of the package on PyPI.
+	Package string
+	// Executable is the command the package installs, when it differs from
+	// the package name.
+	Executable string
+}
+
+// InstallArgs returns the arguments for a Python install client that
+// installs version of the package.
+func (o PythonOptions) InstallArgs(version string) []string {
+	args := []string{o.Package, version}
+	if o.Executable != "" {
+		args = append(args, "executable="+o.Executable)
+	}
+	return args
+}
+
 type ScannerResult struct {
 	Data   []string
 	Errors []string
@@ -44,16 +200,116 @@ type InstallationState struct {
 type BaseScanner struct {
 	Config       ScannerConfig
 	InstallState InstallationState
+	// Installer is the client that last installed the scanner, if any.
+	Installer client.ToolInstaller
+	// reinstall makes IsInstalled report false until the scanner has been
+	// installed again.
+	reinstall bool
+}
+
+// RunScan runs scanner against target and returns its output as a
+// ScannerResult, whichever of Runnable or ResultScanner it implements.
+func RunScan(scanner Scanner, target string) (ScannerResult, error) {
+	switch s := scanner.(type) {
+	case ResultScanner:
+		return s.ScanResult(target)
+	case Runnable:
+		return s.Scan(target)
+	default:
+		return ScannerResult{}, fmt.Errorf("scanner %s does not support running scans", scanner.GetConfig().Name)
+	}
+}
+
+// GroupSubdomains groups the subdomains found for domain by registrable
+// domain, for subdomain scanners that report one result per organization.
+// The group of domain itself always comes first, even when nothing was
+// found under it.
+func GroupSubdomains(domain string, subdomains []string) []extractor.DomainGroup {
+	registrable, err := extractor.RegistrableDomain(domain)
+	if err != nil {
+		registrable = domain
+	}
+	own := extractor.DomainGroup{Domain: registrable, Names: []string{}}
+	var others []extractor.DomainGroup
+	for _, group := range extractor.GroupByRegistrableDomain(subdomains) {
+		if group.Domain == registrable {
+			own = group
+		} else {
+			others = append(others, group)
+		}
+	}
+	return append([]extractor.DomainGroup{own}, others...)
+}
+
+// ValidateOptions checks options meant for scanner. Empty options are
+// always valid; others need a scanner that implements OptionsScanner.
+func ValidateOptions(scanner Scanner, options json.RawMessage) error {
+	if len(options) == 0 {
+		return nil
+	}
+	s, ok := scanner.(OptionsScanner)
+	if !ok {
+		return fmt.Errorf("scanner %s does not take options", scanner.GetConfig().Name)
+	}
+	if err := s.ValidateOptions(options); err != nil {
+		return fmt.Errorf("invalid %s options: %w", scanner.GetConfig().Name, err)
+	}
+	return nil
+}
+
+// RunScanWithOptions is RunScan with options for the scanner. Without
+// options it is RunScan.
+func RunScanWithOptions(scanner Scanner, target string, options json.RawMessage) (ScannerResult, error) {
+	if len(options) == 0 {
+		return RunScan(scanner, target)
+	}
+	if err := ValidateOptions(scanner, options); err != nil {
+		return ScannerResult{}, err
+	}
+	return scanner.(OptionsScanner).ScanResultWithOptions(target, options)
+}
+
+// CombinedOutput runs the command in cmdParts and returns its combined
+// standard output and error. The command is stopped once the scanner's
+// Timeout has passed, and the error then wraps context.DeadlineExceeded,
+// keeping whatever the tool printed until then.
+func (s *BaseScanner) CombinedOutput(cmdParts []string) ([]byte, error) {
+	timeout := s.Config.Timeout
+	if timeout == 0 {
+		timeout = DefaultScanTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := client.CommandContext(ctx, cmdParts[0], cmdParts[1:]...)
+	// The tool runs in its own process group so that the processes it
+	// starts are stopped with it and do not hold its output open. SIGTERM
+	// comes first, which sudo passes on to the tool it runs, where a
+	// SIGKILL would leave the tool running.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = scanKillDelay
+
+	output, err := cmd.CombinedOutput()
+	if err != nil && ctx.Err() != nil {
+		return output, fmt.Errorf("scan stopped after %s: %w", timeout, ctx.Err())
+	}
+	return output, err
 }
 
 func (s *BaseScanner) Scan(target string) (ScannerResult, error) {
 	return ScannerResult{}, fmt.Errorf("Scan method not implemented for %s", s.Config.Name)
 }
 func (s *BaseScanner) IsInstalled() bool {
+	if s.reinstall {
+		return false
+	}
 	if !s.InstallState.Installed {
 		if _, err := os.Stat(s.Config.ExecutablePath); err == nil {
 			s.RegisterInstallationStats()
-		} else if _, err := exec.LookPath(s.Config.Name); err == nil {
+		} else if _, err := client.LookPath(s.Config.Name); err == nil {
 			s.RegisterInstallationStats()
 		}
 	}
@@ -63,30 +319,168 @@ func (s *BaseScanner) IsInstalled() bool {
 func (b *BaseScanner) GetConfig() ScannerConfig {
 	return b.Config
 }
+
+// SetConfig replaces the scanner's configuration, e.g. to pin the version
+// recorded in a lockfile before installing it.
+func (b *BaseScanner) SetConfig(config ScannerConfig) {
+	b.Config = config
+}
+
+// Resolution reports what the scanner's Installer installed, if the
+// scanner was installed in this process by an installer that can tell.
+func (b *BaseScanner) Resolution() (client.Resolution, bool) {
+	resolver, ok := b.Installer.(client.Resolver)
+	if !ok {
+		return client.Resolution{}, false
+	}
+	resolution := resolver.Resolution()
+	return resolution, resolution != client.Resolution{}
+}
+
+// Reinstall makes the next Setup install the scanner again, over the
+// installed version, which the installers keep for rolling back.
+func (b *BaseScanner) Reinstall() {
+	b.reinstall = true
+}
+
+// ResetInstallation forgets the scanner's installation state after it was
+// uninstalled, so a following Setup installs it again.
+func (b *BaseScanner) ResetInstallation() {
+	b.InstallState = InstallationState{}
+	b.Installer = nil
+	b.reinstall = false
+}
+
+// NewInstaller creates an install client with client.ClientFactory and
+// remembers it as the scanner's Installer, so what it installed can be
+// recorded afterwards.
+func (b *BaseScanner) NewInstaller(installType client.InstallationType, installArgs []string, timeout time.Duration) (client.ToolInstaller, error) {
+	installer, err := client.ClientFactory(installType, installArgs, timeout)
+	if err != nil {
+		return nil, err
+	}
+	b.Installer = installer
+	return installer, nil
+}
+
+// RegisterInstallationStats marks the scanner as installed and reads the
+// installed version with its VersionProbe, falling back to the version its
+// installer reported. It returns a VersionTooOldError if that version is
+// older than MinVersion.
 func (s *BaseScanner) RegisterInstallationStats() error {
 	s.InstallState.Installed = true
+	s.InstallState.Version = ""
+	s.reinstall = false
 
-	cmd := exec.Command(s.Config.Name, "--version")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("Error executing command: %s\n", err)
+	executable := s.Config.Name
+	if _, err := os.Stat(s.Config.ExecutablePath); err == nil {
+		executable = s.Config.ExecutablePath
+	}
+	version, err := s.Config.VersionProbe.Run(executable)
+	if err == nil {
+		s.InstallState.Version = version.String()
+	} else if reported := s.reportedVersion(); reported != "" {
+		s.InstallState.Version = reported
+	} else {
+		log.Printf("Failed to read the version of %s: %v\n", s.Config.Name, err)
 	}
 
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if regexp.MustCompile(`(?i)version`).MatchString(line) {
-			s.InstallState.Version = strings.TrimSpace(line)
-			break
-		}
+	shown := s.InstallState.Version
+	if shown == "" {
+		shown = "unknown"
+	}
+	fmt.Printf("%s registered as installed, version: %s\n", s.Config.Name, shown)
+	return s.CheckVersion()
+}
+
+// reportedVersion returns the version the scanner's installer reports. The
+// Python installer reads it from the virtualenv, so it also knows the
+// version of a package installed before this process started.
+func (s *BaseScanner) reportedVersion() string {
+	installer := s.Installer
+	if installer == nil && s.Config.InstallationType == client.InstallationTypePython {
+		installer, _ = installerFor(s.Config)
 	}
-	if s.InstallState.Version == "" && len(lines) > 0 && err == nil {
-		s.InstallState.Version = strings.TrimSpace(string(output))
+	if reporter, ok := installer.(client.VersionReporter); ok {
+		return reporter.InstalledVersion()
 	}
+	return ""
+}
 
-	fmt.Printf("WPScan registered as installed, version: %s\n", s.InstallState.Version)
+// CheckVersion returns a VersionTooOldError if the installed version of
+// the scanner is older than its MinVersion. A version that could not be
+// read passes, since it cannot be compared.
+func (s *BaseScanner) CheckVersion() error {
+	if s.Config.MinVersion == "" || !s.InstallState.Installed {
+		return nil
+	}
+	minimum, err := ParseVersion(s.Config.MinVersion)
+	if err != nil {
+		return fmt.Errorf("invalid minimum version of %s: %w", s.Config.Name, err)
+	}
+	installed, err := ParseVersion(s.InstallState.Version)
+	if err != nil {
+		log.Printf("Cannot check %s against its minimum version %s: its version is unknown\n", s.Config.Name, s.Config.MinVersion)
+		return nil
+	}
+	if installed.LessThan(minimum) {
+		return &VersionTooOldError{
+			Name:       s.Config.Name,
+			Installed:  installed.String(),
+			Minimum:    minimum.String(),
+			Upgradable: s.Config.InstallationType != client.InstallationTypeShell && s.Config.InstallationType != "",
+		}
+	}
 	return nil
 }
 
+// Install installs the scanner with the install client for its
+// InstallationType, configured from its GithubOptions, PythonOptions or
+// GoOptions, and registers the installed version.
+func (s *BaseScanner) Install() error {
+	if s.Config.InstallationType == client.InstallationTypeInternal {
+		return s.InstallGoModule()
+	}
+
+	installArgs, err := installArgs(s.Config)
+	if err != nil {
+		return err
+	}
+	installer, err := s.NewInstaller(s.Config.InstallationType, installArgs, 5*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to create install client: %w", err)
+	}
+	if err := installer.InstallTool(); err != nil {
+		return fmt.Errorf("failed to install %s: %w", s.Config.Name, err)
+	}
+	return s.RegisterInstallationStats()
+}
+
+// InstallGoModule builds the scanner's Go package into the managed bin
+// directory, points ExecutablePath at the result and records the module
+// version that was built. Like Install, it returns a VersionTooOldError if
+// that version is older than MinVersion.
+func (s *BaseScanner) InstallGoModule() error {
+	binDir := client.BinDir()
+	installArgs := s.Config.GoOptions.InstallArgs(s.Config.Version, binDir)
+
+	installer, err := s.NewInstaller(client.InstallationTypeInternal, installArgs, 15*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to create install client: %w", err)
+	}
+	if err := installer.InstallTool(); err != nil {
+		return fmt.Errorf("failed to install %s: %w", s.Config.Name, err)
+	}
+
+	s.Config.ExecutablePath = filepath.Join(binDir, client.GoBinaryName(s.Config.GoOptions.Package))
+	s.InstallState.Installed = true
+	s.reinstall = false
+	if reporter, ok := installer.(client.VersionReporter); ok {
+		s.InstallState.Version = reporter.InstalledVersion()
+	}
+	return s.CheckVersion()
+}
+
 func (s *BaseScanner) GetInstallationState() InstallationState {
 	return s.InstallState
 }
@@ -0,0 +1,33 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCombinedOutputStopsAtTimeout(t *testing.T) {
+	s := &BaseScanner{Config: ScannerConfig{Timeout: 100 * time.Millisecond}}
+
+	start := time.Now()
+	output, err := s.CombinedOutput([]string{"sh", "-c", "echo started; sleep 10"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CombinedOutput error = %v, want it to wrap context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("CombinedOutput took %s, want it stopped at the timeout", elapsed)
+	}
+	if string(output) != "started\n" {
+		t.Errorf("CombinedOutput output = %q, want what was printed before the timeout", output)
+	}
+}
+
+func TestCombinedOutputWithinTimeout(t *testing.T) {
+	s := &BaseScanner{Config: ScannerConfig{Timeout: 10 * time.Second}}
+
+	output, err := s.CombinedOutput([]string{"sh", "-c", "echo done"})
+	if err != nil || string(output) != "done\n" {
+		t.Errorf("CombinedOutput = %q, %v, want \"done\\n\", nil", output, err)
+	}
+}
@@ -1,10 +1,12 @@
 package semgrep
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/IxBahy/ASM/internal/scanners"
 	"github.com/IxBahy/ASM/pkg/client"
@@ -17,9 +19,12 @@ type SemgrepScanner struct {
 
 func NewSemgrepScanner() *SemgrepScanner {
 	config := scanners.ScannerConfig{
-		Name:             "semgrep",
-		Version:          "latest",
-		ExecutablePath:   "/usr/local/bin/semgrep",
+		Name:    "semgrep",
+		Version: "latest",
+		PythonOptions: scanners.PythonOptions{
+			Package: "semgrep",
+		},
+		ExecutablePath:   client.BinPath("semgrep"),
 		Base_Command:     "semgrep scan",
 		InstallationType: client.InstallationTypePython,
 	}
@@ -41,13 +46,13 @@ func NewSemgrepScanner() *SemgrepScanner {
 
 func (s *SemgrepScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
-	installArgs := []string{"semgrep", s.Config.Version}
+	installArgs := s.Config.PythonOptions.InstallArgs(s.Config.Version)
 
 	var err error
-	s.installClient, err = client.ClientFactory(client.InstallationTypePython, installArgs, 5)
+	s.installClient, err = s.NewInstaller(client.InstallationTypePython, installArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client: %w", err)
 	}
@@ -56,7 +61,7 @@ func (s *SemgrepScanner) Setup() error {
 		return fmt.Errorf("failed to install semgrep: %w", err)
 	}
 
-	if path, err := exec.LookPath("semgrep"); err == nil {
+	if path, err := client.LookPath("semgrep"); err == nil {
 		s.Config.ExecutablePath = path
 	}
 
@@ -96,8 +101,7 @@ func (s *SemgrepScanner) Scan(target string) (scanners.ScannerResult, error) {
 		cmdParts = append(cmdParts, "--exclude=node_modules,dist,build,vendor")
 	}
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, err := s.CombinedOutput(cmdParts)
 
 	for _, line := range strings.Split(string(output), "\n") {
 		if trimmed := strings.TrimSpace(line); trimmed != "" {
@@ -105,7 +109,7 @@ func (s *SemgrepScanner) Scan(target string) (scanners.ScannerResult, error) {
 		}
 	}
 
-	if err != nil && len(result.Data) == 0 {
+	if err != nil && (len(result.Data) == 0 || errors.Is(err, context.DeadlineExceeded)) {
 		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", err))
 		return result, err
 	}
@@ -3,12 +3,13 @@ package sqlmap
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/IxBahy/ASM/internal/scanners"
 	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 type SQLMapScanner struct {
@@ -18,10 +19,14 @@ type SQLMapScanner struct {
 
 func NewSQLMapScanner() *SQLMapScanner {
 	config := scanners.ScannerConfig{
-		Name:             "sqlmap",
-		Version:          "latest",
-		ExecutablePath:   "/usr/local/bin/sqlmap",
+		Name:    "sqlmap",
+		Version: "latest",
+		PythonOptions: scanners.PythonOptions{
+			Package: "sqlmap",
+		},
+		ExecutablePath:   client.BinPath("sqlmap"),
 		Base_Command:     "sqlmap -u",
+		Intrusive:        true,
 		InstallationType: client.InstallationTypePython,
 	}
 
@@ -42,13 +47,13 @@ func NewSQLMapScanner() *SQLMapScanner {
 
 func (s *SQLMapScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
-	installArgs := []string{"sqlmap", s.Config.Version}
+	installArgs := s.Config.PythonOptions.InstallArgs(s.Config.Version)
 
 	var err error
-	s.installClient, err = client.ClientFactory(s.Config.InstallationType, installArgs, 5)
+	s.installClient, err = s.NewInstaller(s.Config.InstallationType, installArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client: %w", err)
 	}
@@ -57,7 +62,7 @@ func (s *SQLMapScanner) Setup() error {
 		return fmt.Errorf("failed to install sqlmap: %w", err)
 	}
 
-	sqlmapPath, lookErr := exec.LookPath("sqlmap")
+	sqlmapPath, lookErr := client.LookPath("sqlmap")
 	if lookErr == nil {
 		s.Config.ExecutablePath = sqlmapPath
 	}
@@ -70,6 +75,14 @@ func (s *SQLMapScanner) Scan(target string) (scanners.ScannerResult, error) {
 		return scanners.ScannerResult{}, fmt.Errorf("sqlmap is not installed")
 	}
 
+	parsed, err := extractor.Parse(target)
+	if err == nil && !parsed.IsHost() {
+		err = fmt.Errorf("sqlmap tests a single site, not %s", parsed)
+	}
+	if err != nil {
+		return scanners.ScannerResult{Data: []string{}, Errors: []string{err.Error()}}, err
+	}
+
 	tmpDir, err := os.MkdirTemp("", "sqlmap-output-*")
 	if err != nil {
 		return scanners.ScannerResult{}, fmt.Errorf("failed to create temp directory: %w", err)
@@ -77,7 +90,7 @@ func (s *SQLMapScanner) Scan(target string) (scanners.ScannerResult, error) {
 	defer os.RemoveAll(tmpDir)
 
 	cmdParts := strings.Fields(s.Config.Base_Command)
-	cmdParts = append(cmdParts, target)
+	cmdParts = append(cmdParts, parsed.URL("http"))
 
 	cmdParts = append(cmdParts,
 		"--batch",
@@ -89,8 +102,7 @@ func (s *SQLMapScanner) Scan(target string) (scanners.ScannerResult, error) {
 		"--json-output", filepath.Join(tmpDir, "results.json"),
 	)
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, err := s.CombinedOutput(cmdParts)
 
 	result := scanners.ScannerResult{
 		Data:   []string{},
@@ -1,20 +1,19 @@
 package subfinder
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/IxBahy/ASM/internal/scanners"
-	"github.com/IxBahy/ASM/pkg/client"
 	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 type SubfinderScanner struct {
 	*scanners.BaseScanner
-	installClient client.ToolInstaller
 }
 
 type SubdomainResult struct {
@@ -24,17 +23,8 @@ type SubdomainResult struct {
 }
 
 func NewSubfinderScanner() *SubfinderScanner {
-	config := scanners.ScannerConfig{
-		Name:             "subfinder",
-		Version:          "latest",
-		ExecutablePath:   "/usr/local/bin/subfinder",
-		Base_Command:     "subfinder -d",
-		InstallationType: client.InstallationTypeGithub,
-		GithubOptions: scanners.GithubOptions{
-			InstallLink:    "https://api.github.com/repos/projectdiscovery/subfinder/releases/latest",
-			InstallPattern: "subfinder_.*_linux_amd64.zip",
-		},
-	}
+	config := scanners.CatalogConfig("subfinder")
+	config.Base_Command = "subfinder -d"
 
 	base := &scanners.BaseScanner{
 		Config: config,
@@ -53,28 +43,10 @@ func NewSubfinderScanner() *SubfinderScanner {
 
 func (s *SubfinderScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
-	}
-
-	// Install from GitHub release
-	installArgs := []string{
-		s.Config.GithubOptions.InstallLink,
-		s.Config.GithubOptions.InstallPattern,
-		s.Config.Version,
-		s.Config.ExecutablePath,
+		return s.CheckVersion()
 	}
 
-	var err error
-	s.installClient, err = client.ClientFactory(client.InstallationTypeGithub, installArgs, 5)
-	if err != nil {
-		return fmt.Errorf("failed to create install client: %w", err)
-	}
-
-	if err := s.installClient.InstallTool(); err != nil {
-		return fmt.Errorf("failed to install subfinder: %w", err)
-	}
-
-	return s.RegisterInstallationStats()
+	return s.Install()
 }
 
 func (s *SubfinderScanner) Scan(domain string) (scanners.ScannerResult, error) {
@@ -87,17 +59,24 @@ func (s *SubfinderScanner) Scan(domain string) (scanners.ScannerResult, error) {
 		Errors: []string{},
 	}
 
-	domain = extractor.ExtractDomain(domain)
+	parsed, err := extractor.Parse(domain)
+	if err == nil && parsed.Kind != extractor.KindDomain {
+		err = fmt.Errorf("subfinder needs a domain, not %s", parsed)
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	domain = parsed.Host
 
 	cmdParts := strings.Fields(s.Config.Base_Command)
 	cmdParts = append(cmdParts, domain, "-silent", "-json")
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, runErr := s.CombinedOutput(cmdParts)
 
-	if err != nil && len(output) == 0 {
-		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", err))
-		return result, err
+	if runErr != nil && len(output) == 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", runErr))
+		return result, runErr
 	}
 
 	subdomains := []string{}
@@ -116,18 +95,28 @@ func (s *SubfinderScanner) Scan(domain string) (scanners.ScannerResult, error) {
 		}
 	}
 
-	subdomainResult := SubdomainResult{
-		Domain:     domain,
-		Subdomains: subdomains,
-		TimeStamp:  time.Now().Format(time.RFC3339),
-	}
+	// Sources report names outside the domain asked for, under other
+	// organizations' domains too, so results are grouped by registrable
+	// domain, one per organization.
+	timestamp := time.Now().Format(time.RFC3339)
+	for _, group := range scanners.GroupSubdomains(domain, subdomains) {
+		subdomainResult := SubdomainResult{
+			Domain:     group.Domain,
+			Subdomains: group.Names,
+			TimeStamp:  timestamp,
+		}
 
-	resultJSON, err := json.MarshalIndent(subdomainResult, "", "  ")
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("json encoding error: %v", err))
-		return result, err
+		resultJSON, err := json.MarshalIndent(subdomainResult, "", "  ")
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("json encoding error: %v", err))
+			return result, err
+		}
+		result.Data = append(result.Data, string(resultJSON))
+	}
+	// A scan stopped at its deadline still reports what it found.
+	if errors.Is(runErr, context.DeadlineExceeded) {
+		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", runErr))
+		return result, runErr
 	}
-
-	result.Data = append(result.Data, string(resultJSON))
 	return result, nil
 }
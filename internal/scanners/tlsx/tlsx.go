@@ -72,15 +72,21 @@ func (s *TLSXScanner) Scan(target string) (scanners.ScannerResult, error) {
 		Errors: []string{},
 	}
 
-	host, port := extractor.ExtractHostAndPort(target)
-	if port == "" {
-		port = "443"
+	parsed, err := extractor.Parse(target)
+	if err == nil && !parsed.IsHost() {
+		err = fmt.Errorf("tlsx scans a single host, not %s", parsed)
 	}
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	address := parsed.HostPort("443")
+	host, port, _ := net.SplitHostPort(address)
 
 	conn, err := tls.DialWithDialer(
 		&net.Dialer{Timeout: 10 * time.Second},
 		"tcp",
-		fmt.Sprintf("%s:%s", host, port),
+		address,
 		&tls.Config{
 			InsecureSkipVerify: true,
 		},
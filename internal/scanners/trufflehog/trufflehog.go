@@ -1,31 +1,22 @@
 package trufflehog
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/IxBahy/ASM/internal/scanners"
-	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 type TruffleHogScanner struct {
 	*scanners.BaseScanner
-	installClient client.ToolInstaller
 }
 
 func NewTruffleHogScanner() *TruffleHogScanner {
-	config := scanners.ScannerConfig{
-		Name:             "trufflehog",
-		Version:          "latest",
-		ExecutablePath:   "/usr/local/bin/trufflehog",
-		Base_Command:     "trufflehog",
-		InstallationType: client.InstallationTypeGithub,
-		GithubOptions: scanners.GithubOptions{
-			InstallLink:    "https://api.github.com/repos/trufflesecurity/trufflehog/releases/latest",
-			InstallPattern: "trufflehog_.*_linux_amd64.tar.gz",
-		},
-	}
+	config := scanners.CatalogConfig("trufflehog")
+	config.Base_Command = "trufflehog"
 
 	base := &scanners.BaseScanner{
 		Config: config,
@@ -44,33 +35,12 @@ func NewTruffleHogScanner() *TruffleHogScanner {
 
 func (s *TruffleHogScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
 	fmt.Println("Installing TruffleHog from GitHub releases...")
 
-	installArgs := []string{
-		s.Config.GithubOptions.InstallLink,
-		s.Config.GithubOptions.InstallPattern,
-		s.Config.Version,
-		s.Config.ExecutablePath,
-	}
-
-	var err error
-	s.installClient, err = client.ClientFactory(client.InstallationTypeGithub, installArgs, 5)
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
-	}
-
-	if err := s.installClient.InstallTool(); err != nil {
-		return fmt.Errorf("failed to install trufflehog: %w", err)
-	}
-
-	if path, err := exec.LookPath("trufflehog"); err == nil {
-		s.Config.ExecutablePath = path
-	}
-
-	return s.RegisterInstallationStats()
+	return s.Install()
 }
 
 func (s *TruffleHogScanner) Scan(target string) (scanners.ScannerResult, error) {
@@ -83,7 +53,9 @@ func (s *TruffleHogScanner) Scan(target string) (scanners.ScannerResult, error)
 		Errors: []string{},
 	}
 
-	isURL := strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+	// Repositories are given as URLs, anything else is a path on disk.
+	parsed, err := extractor.Parse(target)
+	isURL := err == nil && parsed.Scheme != "" && parsed.Scheme != "file"
 
 	cmdParts := strings.Fields(s.Config.Base_Command)
 
@@ -99,8 +71,7 @@ func (s *TruffleHogScanner) Scan(target string) (scanners.ScannerResult, error)
 		cmdParts = append(cmdParts, target)
 	}
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, err := s.CombinedOutput(cmdParts)
 
 	for _, line := range strings.Split(string(output), "\n") {
 		if trimmed := strings.TrimSpace(line); trimmed != "" {
@@ -108,7 +79,7 @@ func (s *TruffleHogScanner) Scan(target string) (scanners.ScannerResult, error)
 		}
 	}
 
-	if err != nil && len(result.Data) == 0 {
+	if err != nil && (len(result.Data) == 0 || errors.Is(err, context.DeadlineExceeded)) {
 		result.Errors = append(result.Errors, fmt.Sprintf("command error: %v", err))
 		result.Errors = append(result.Errors, string(output))
 		return result, err
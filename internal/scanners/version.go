@@ -0,0 +1,111 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/Masterminds/semver/v3"
+)
+
+// versionProbeTimeout bounds how long a version probe may run.
+const versionProbeTimeout = 30 * time.Second
+
+// versionPattern matches the first version number in a string: two or three
+// numeric components with an optional pre-release or build suffix, such as
+// "v3.1.0", "1.8.2#stable" or "7.94SVN".
+var versionPattern = regexp.MustCompile(`v?([0-9]+)\.([0-9]+)(?:\.([0-9]+))?(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?`)
+
+// ErrVersionTooOld is returned, wrapped in a VersionTooOldError, when the
+// installed version of a scanner is older than its MinVersion.
+var ErrVersionTooOld = errors.New("installed version is too old")
+
+// VersionTooOldError reports a scanner whose installed version is older
+// than its ScannerConfig.MinVersion.
+type VersionTooOldError struct {
+	Name      string
+	Installed string
+	Minimum   string
+	// Upgradable is set when ASM can install a newer version itself, rather
+	// than the system package manager.
+	Upgradable bool
+}
+
+func (e *VersionTooOldError) Error() string {
+	hint := "upgrade it with the system package manager"
+	if e.Upgradable {
+		hint = "run \"tools upgrade " + e.Name + "\""
+	}
+	return fmt.Sprintf("%s %s is too old, at least %s is required; %s", e.Name, e.Installed, e.Minimum, hint)
+}
+
+func (e *VersionTooOldError) Unwrap() error {
+	return ErrVersionTooOld
+}
+
+// CheckVersion returns a VersionTooOldError if the installed version of
+// scanner is older than its MinVersion.
+func CheckVersion(scanner Scanner) error {
+	if checker, ok := scanner.(interface{ CheckVersion() error }); ok {
+		return checker.CheckVersion()
+	}
+	return nil
+}
+
+// ParseVersion parses the first version number in s as a semantic version.
+// Missing components are zero, so "7.94SVN" is 7.94.0.
+func ParseVersion(s string) (*semver.Version, error) {
+	match := versionPattern.FindStringSubmatch(s)
+	if match == nil {
+		return nil, fmt.Errorf("no version number in %q", s)
+	}
+	patch := match[3]
+	if patch == "" {
+		patch = "0"
+	}
+	return semver.StrictNewVersion(match[1] + "." + match[2] + "." + patch + match[4] + match[5])
+}
+
+// Run runs the probe against executable and returns the version it
+// printed, parsed as a semantic version.
+func (p VersionProbe) Run(executable string) (*semver.Version, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+
+	args := p.Args
+	if len(args) == 0 {
+		args = []string{"--version"}
+	}
+	cmd := client.CommandContext(ctx, executable, args...)
+	// Tools print their version to stdout or stderr, and some exit with an
+	// error after printing it, so the output is checked either way.
+	output, runErr := cmd.CombinedOutput()
+
+	text := string(output)
+	if p.Pattern != "" {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version pattern: %w", err)
+		}
+		match := re.FindStringSubmatch(text)
+		if match == nil {
+			if runErr != nil {
+				return nil, fmt.Errorf("failed to run %s: %w", executable, runErr)
+			}
+			return nil, fmt.Errorf("no version found in the output of %s", executable)
+		}
+		text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+	}
+
+	version, err := ParseVersion(text)
+	if err != nil && runErr != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", executable, runErr)
+	}
+	return version, err
+}
@@ -1,13 +1,15 @@
 package whois
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/IxBahy/ASM/internal/scanners"
 	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 type WhoisScanner struct {
@@ -22,6 +24,10 @@ func NewWhoisScanner() *WhoisScanner {
 		ExecutablePath:   "/usr/bin/whois",
 		Base_Command:     "whois",
 		InstallationType: client.InstallationTypeShell,
+		// Only the Debian whois prints a version; other implementations
+		// have no such flag, and their version stays unknown.
+		VersionProbe: scanners.VersionProbe{Pattern: `(?i)version\s+([0-9][^\s]*)`},
+		Timeout:      2 * time.Minute,
 	}
 
 	base := &scanners.BaseScanner{
@@ -40,12 +46,12 @@ func NewWhoisScanner() *WhoisScanner {
 
 func (s *WhoisScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
 	installArgs := []string{"whois", "-y"}
 	var err error
-	s.installClient, err = client.ClientFactory(s.Config.InstallationType, installArgs, 5)
+	s.installClient, err = s.NewInstaller(s.Config.InstallationType, installArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client: %w", err)
 	}
@@ -62,17 +68,20 @@ func (s *WhoisScanner) Scan(target string) (scanners.ScannerResult, error) {
 		return scanners.ScannerResult{}, fmt.Errorf("whois is not installed")
 	}
 
-	re := regexp.MustCompile(`^(?:https?://)?(?:[^@\n]+@)?(?:www\.)?([^:/\n?]+)`)
-	matches := re.FindStringSubmatch(target)
-	if len(matches) > 1 {
-		target = matches[1]
+	parsed, err := extractor.Parse(target)
+	if err == nil && parsed.IsNetwork() {
+		err = fmt.Errorf("whois looks up a domain or address, not %s", parsed)
 	}
+	if err != nil {
+		return scanners.ScannerResult{Data: []string{}, Errors: []string{err.Error()}}, err
+	}
+	// Registries know the domain, not its www host.
+	target = strings.TrimPrefix(parsed.Host, "www.")
 
 	cmdParts := strings.Fields(s.Config.Base_Command)
 	cmdParts = append(cmdParts, target)
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, err := s.CombinedOutput(cmdParts)
 
 	result := scanners.ScannerResult{
 		Data:   []string{},
@@ -91,5 +100,8 @@ func (s *WhoisScanner) Scan(target string) (scanners.ScannerResult, error) {
 		}
 	}
 
+	if errors.Is(err, context.DeadlineExceeded) {
+		return result, err
+	}
 	return result, nil
 }
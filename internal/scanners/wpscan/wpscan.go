@@ -2,11 +2,17 @@ package wpscan
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/IxBahy/ASM/internal/scanners"
 	"github.com/IxBahy/ASM/pkg/client"
+	"github.com/IxBahy/ASM/pkg/client/utils"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
 )
 
 type WPScanScanner struct {
@@ -18,9 +24,11 @@ func NewWPScanScanner() *WPScanScanner {
 	config := scanners.ScannerConfig{
 		Name:             "wpscan",
 		Version:          "latest",
-		ExecutablePath:   "/usr/local/bin/wpscan",
+		ExecutablePath:   client.BinPath("wpscan"),
 		Base_Command:     "wpscan --url",
+		Intrusive:        true,
 		InstallationType: client.InstallationTypeShell,
+		VersionProbe:     scanners.VersionProbe{Pattern: `(?i)current version:\s*([0-9][^\s]*)`},
 	}
 
 	base := &scanners.BaseScanner{
@@ -39,7 +47,7 @@ func NewWPScanScanner() *WPScanScanner {
 
 func (s *WPScanScanner) Setup() error {
 	if s.IsInstalled() {
-		return nil
+		return s.CheckVersion()
 	}
 
 	depsArgs := []string{
@@ -55,14 +63,16 @@ func (s *WPScanScanner) Setup() error {
 		"gcc",
 		"-y",
 		"&&",
-		"sudo",
 		"gem",
 		"install",
+		"--install-dir", gemHome(),
+		"--bindir", filepath.Join(gemHome(), "bin"),
+		"--no-document",
 		"wpscan",
 	}
 	var err error
 
-	s.installClient, err = client.ClientFactory(client.InstallationTypeShell, depsArgs, 5)
+	s.installClient, err = s.NewInstaller(client.InstallationTypeShell, depsArgs, 5*time.Minute)
 	if err != nil {
 		return fmt.Errorf("failed to create install client for dependencies: %w", err)
 	}
@@ -71,21 +81,141 @@ func (s *WPScanScanner) Setup() error {
 	if err := s.installClient.InstallTool(); err != nil {
 		return fmt.Errorf("failed to install wpscan with it dependencies: %w", err)
 	}
+	if err := installShim(); err != nil {
+		return err
+	}
 
 	return s.RegisterInstallationStats()
 }
 
+// Rollback restores the shim the last install replaced, which runs the
+// wpscan gem version installed before. RubyGems keeps every installed
+// version, so the gem itself is still there.
+func (s *WPScanScanner) Rollback() error {
+	if err := utils.Rollback(client.BinPath("wpscan")); err != nil {
+		return err
+	}
+	fmt.Println("wpscan rolled back")
+	return nil
+}
+
+// gemHome is the directory the wpscan gem and its dependencies are
+// installed into, so installing them does not need root.
+func gemHome() string {
+	return filepath.Join(client.ToolsDir(), "gems")
+}
+
+// installShim installs the managed wpscan executable, which runs the gem's
+// executable with GEM_HOME pointing at gemHome. It pins the gem version just
+// installed, so a failed or partial gem install never changes what the shim
+// runs, and the shim is only put in place once it runs. Nothing is written
+// if the gem was not installed, e.g. in a dry run.
+func installShim() error {
+	target := filepath.Join(gemHome(), "bin", "wpscan")
+	if _, err := os.Stat(target); err != nil {
+		return nil
+	}
+	version, err := gemVersion()
+	if err != nil {
+		return err
+	}
+
+	shim := client.BinPath("wpscan")
+	staged, cleanup, err := utils.Stage(shim)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// RubyGems executables run the version given as _<version>_.
+	script := fmt.Sprintf("#!/bin/sh\nGEM_HOME=%s exec %s %s \"$@\"\n", shellQuote(gemHome()), shellQuote(target), shellQuote("_"+version+"_"))
+	if err := os.WriteFile(staged, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", shim, err)
+	}
+	if err := utils.Commit(staged, shim); err != nil {
+		return fmt.Errorf("failed to install wpscan: %w", err)
+	}
+	return nil
+}
+
+var gemVersionPattern = regexp.MustCompile(`(?m)^wpscan \(([^,)\s]+)`)
+
+// gemVersion returns the newest version of the wpscan gem in gemHome.
+func gemVersion() (string, error) {
+	cmd := exec.Command("gem", "list", "--local", "--exact", "wpscan")
+	cmd.Env = append(os.Environ(), "GEM_HOME="+gemHome())
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list installed wpscan versions: %w", err)
+	}
+	match := gemVersionPattern.FindSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("wpscan gem not found in %s", gemHome())
+	}
+	return string(match[1]), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Uninstall removes the wpscan gem. Its build dependencies are left in
+// place, since other software may use them. A gem installed system-wide by
+// an earlier version of ASM is removed with sudo, if allowed.
+func (s *WPScanScanner) Uninstall() error {
+	command := []string{"gem", "uninstall", "--all", "--executables", "--install-dir", gemHome(), "--bindir", filepath.Join(gemHome(), "bin"), "wpscan"}
+	managed := true
+	if _, err := os.Stat(filepath.Join(gemHome(), "bin", "wpscan")); err != nil {
+		managed = false
+		elevated, err := client.Elevate([]string{"gem", "uninstall", "--executables", "wpscan"})
+		if err != nil {
+			return err
+		}
+		command = elevated
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	if managed {
+		cmd.Env = append(os.Environ(), "GEM_HOME="+gemHome())
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to uninstall wpscan: %w", err)
+	}
+	if err := os.Remove(client.BinPath("wpscan")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", client.BinPath("wpscan"), err)
+	}
+	return utils.RemovePrevious(client.BinPath("wpscan"))
+}
+
+// Resolution reports the wpscan gem, rather than the system packages it
+// was installed with.
+func (s *WPScanScanner) Resolution() (client.Resolution, bool) {
+	if s.Installer == nil {
+		return client.Resolution{}, false
+	}
+	return client.Resolution{Version: s.InstallState.Version, Source: "gem:wpscan"}, true
+}
+
 func (s *WPScanScanner) Scan(target string) (scanners.ScannerResult, error) {
 	if !s.IsInstalled() {
 		return scanners.ScannerResult{}, fmt.Errorf("wpscan is not installed")
 	}
 
+	parsed, err := extractor.Parse(target)
+	if err == nil && !parsed.IsHost() {
+		err = fmt.Errorf("wpscan scans a single site, not %s", parsed)
+	}
+	if err != nil {
+		return scanners.ScannerResult{Data: []string{}, Errors: []string{err.Error()}}, err
+	}
+
 	cmdParts := strings.Fields(s.Config.Base_Command)
-	cmdParts = append(cmdParts, target)
+	cmdParts = append(cmdParts, parsed.URL("https"))
 	cmdParts = append(cmdParts, "--format", "json")
 
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	output, err := cmd.CombinedOutput()
+	output, err := s.CombinedOutput(cmdParts)
 
 	result := scanners.ScannerResult{
 		Data:   []string{},
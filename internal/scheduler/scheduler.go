@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/jobs"
+	"github.com/IxBahy/ASM/internal/workspace"
+)
+
+// RequestedBy identifies jobs submitted by the scheduler in the audit trail.
+const RequestedBy = "scheduler"
+
+// Scheduler submits a job for every target of a workspace each time one of
+// the workspace's schedules comes due.
+type Scheduler struct {
+	workspaces *workspace.Manager
+	jobs       *jobs.Manager
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+}
+
+func New(workspaces *workspace.Manager, manager *jobs.Manager) *Scheduler {
+	return &Scheduler{
+		workspaces: workspaces,
+		jobs:       manager,
+		lastRun:    make(map[string]time.Time),
+	}
+}
+
+// Run checks the schedules every interval until ctx is cancelled. Schedules
+// first run one full interval after they are seen, so restarting the server
+// does not start every scan at once.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.tick(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, ws := range s.workspaces.List() {
+		for _, schedule := range ws.Schedules {
+			key := ws.Name + "/" + schedule.Name
+			seen[key] = true
+
+			last, exists := s.lastRun[key]
+			if !exists {
+				s.lastRun[key] = now
+				continue
+			}
+			if now.Sub(last) < schedule.Interval.Duration {
+				continue
+			}
+
+			s.lastRun[key] = now
+			s.submit(ws, schedule)
+		}
+	}
+
+	for key := range s.lastRun {
+		if !seen[key] {
+			delete(s.lastRun, key)
+		}
+	}
+}
+
+func (s *Scheduler) submit(ws workspace.Workspace, schedule workspace.Schedule) {
+	for _, target := range ws.Targets {
+		if !ws.Scope.Allows(target) {
+			log.Printf("scheduler: skipping %s in workspace %s: outside scope", target, ws.Name)
+			continue
+		}
+
+		job, err := s.jobs.Submit(jobs.Request{
+			Workspace:   ws.Name,
+			Target:      target,
+			Scanners:    schedule.Scanners,
+			RequestedBy: RequestedBy,
+		})
+		if err != nil {
+			log.Printf("scheduler: failed to submit %s for %s in workspace %s: %v", schedule.Name, target, ws.Name, err)
+			continue
+		}
+		log.Printf("scheduler: submitted job %s (%s) for %s in workspace %s", job.ID, schedule.Name, target, ws.Name)
+	}
+}
@@ -0,0 +1,372 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/inventory"
+)
+
+const (
+	configFile    = "workspace.json"
+	inventoryFile = "inventory.json"
+	exportVersion = 1
+)
+
+// Export is the file format of an exported workspace.
+type Export struct {
+	Version   int                `json:"version"`
+	Workspace Workspace          `json:"workspace"`
+	Inventory inventory.Snapshot `json:"inventory"`
+}
+
+type entry struct {
+	workspace Workspace
+	inventory *inventory.Store
+}
+
+// Manager keeps every workspace and its inventory under a directory, one
+// subdirectory per workspace.
+type Manager struct {
+	dir       string
+	mu        sync.RWMutex
+	entries   map[string]*entry
+	listeners []func(string, inventory.Change)
+}
+
+// Open loads the workspaces stored in dir, creating the default workspace
+// if there are none.
+func Open(dir string) (*Manager, error) {
+	m := &Manager{
+		dir:     dir,
+		entries: make(map[string]*entry),
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace directory: %w", err)
+	}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		e, err := m.load(dirEntry.Name())
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.entries[e.workspace.Name] = e
+	}
+
+	if len(m.entries) == 0 {
+		if _, err := m.Create(Workspace{Name: Default}); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// List returns all workspaces ordered by name.
+func (m *Manager) List() []Workspace {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]Workspace, 0, len(m.entries))
+	for _, e := range m.entries {
+		result = append(result, e.workspace)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (m *Manager) Get(name string) (Workspace, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, exists := m.entries[name]
+	if !exists {
+		return Workspace{}, false
+	}
+	return e.workspace, true
+}
+
+// Inventory returns the asset inventory of the named workspace.
+func (m *Manager) Inventory(name string) (*inventory.Store, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, exists := m.entries[name]
+	if !exists {
+		return nil, false
+	}
+	return e.inventory, true
+}
+
+// InventorySize returns the number of assets across all workspaces.
+func (m *Manager) InventorySize() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	size := 0
+	for _, e := range m.entries {
+		size += e.inventory.Size()
+	}
+	return size
+}
+
+// OnInventoryChange registers fn to be called with the workspace name for
+// every change to any workspace's inventory. See inventory.Store.OnChange.
+func (m *Manager) OnInventoryChange(fn func(workspace string, change inventory.Change)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listeners = append(m.listeners, fn)
+	for name, e := range m.entries {
+		m.watch(name, e.inventory, fn)
+	}
+}
+
+func (m *Manager) Create(w Workspace) (Workspace, error) {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now().UTC()
+	}
+	normalize(&w)
+	if err := w.Validate(); err != nil {
+		return Workspace{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entries[w.Name]; exists {
+		return Workspace{}, ErrAlreadyExists
+	}
+
+	e := &entry{workspace: w, inventory: inventory.NewStore()}
+	if err := m.save(e); err != nil {
+		return Workspace{}, err
+	}
+	m.add(e)
+	return w, nil
+}
+
+// Update replaces the configuration of an existing workspace. The inventory
+// is left untouched.
+func (m *Manager) Update(w Workspace) (Workspace, error) {
+	normalize(&w)
+	if err := w.Validate(); err != nil {
+		return Workspace{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, exists := m.entries[w.Name]
+	if !exists {
+		return Workspace{}, ErrNotFound
+	}
+
+	w.CreatedAt = e.workspace.CreatedAt
+	previous := e.workspace
+	e.workspace = w
+	if err := m.saveConfig(e); err != nil {
+		e.workspace = previous
+		return Workspace{}, err
+	}
+	return w, nil
+}
+
+// Delete removes the workspace and everything stored for it.
+func (m *Manager) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entries[name]; !exists {
+		return ErrNotFound
+	}
+	if err := os.RemoveAll(filepath.Join(m.dir, name)); err != nil {
+		return fmt.Errorf("failed to delete workspace %s: %w", name, err)
+	}
+	delete(m.entries, name)
+	return nil
+}
+
+// SaveInventory writes the workspace's inventory to disk.
+func (m *Manager) SaveInventory(name string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, exists := m.entries[name]
+	if !exists {
+		return ErrNotFound
+	}
+	return writeJSON(filepath.Join(m.dir, name, inventoryFile), e.inventory.Snapshot())
+}
+
+// Export writes the workspace configuration and inventory to w.
+func (m *Manager) Export(name string, w io.Writer) error {
+	m.mu.RLock()
+	e, exists := m.entries[name]
+	m.mu.RUnlock()
+	if !exists {
+		return ErrNotFound
+	}
+
+	export := Export{
+		Version:   exportVersion,
+		Workspace: e.workspace,
+		Inventory: e.inventory.Snapshot(),
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// Import creates a workspace from an export. When name is not empty the
+// workspace is imported under that name instead of its original one.
+func (m *Manager) Import(r io.Reader, name string) (Workspace, error) {
+	var export Export
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return Workspace{}, fmt.Errorf("failed to parse workspace export: %w", err)
+	}
+	if export.Version != exportVersion {
+		return Workspace{}, fmt.Errorf("unsupported workspace export version %d", export.Version)
+	}
+
+	w := export.Workspace
+	if name != "" {
+		w.Name = name
+	}
+	normalize(&w)
+	if err := w.Validate(); err != nil {
+		return Workspace{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entries[w.Name]; exists {
+		return Workspace{}, ErrAlreadyExists
+	}
+
+	e := &entry{workspace: w, inventory: inventory.NewStoreFromSnapshot(export.Inventory)}
+	if err := m.save(e); err != nil {
+		return Workspace{}, err
+	}
+	m.add(e)
+	return w, nil
+}
+
+// add stores e and attaches the registered inventory listeners to it. The
+// caller must hold the write lock.
+func (m *Manager) add(e *entry) {
+	m.entries[e.workspace.Name] = e
+	for _, fn := range m.listeners {
+		m.watch(e.workspace.Name, e.inventory, fn)
+	}
+}
+
+func (m *Manager) watch(name string, store *inventory.Store, fn func(string, inventory.Change)) {
+	store.OnChange(func(change inventory.Change) {
+		fn(name, change)
+	})
+}
+
+func (m *Manager) load(name string) (*entry, error) {
+	var w Workspace
+	data, err := os.ReadFile(filepath.Join(m.dir, name, configFile))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace %s: %w", name, err)
+	}
+	if w.Name != name {
+		return nil, fmt.Errorf("workspace %s is stored under directory %s", w.Name, name)
+	}
+	normalize(&w)
+
+	var snapshot inventory.Snapshot
+	data, err = os.ReadFile(filepath.Join(m.dir, name, inventoryFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse inventory of workspace %s: %w", name, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read inventory of workspace %s: %w", name, err)
+	}
+
+	return &entry{workspace: w, inventory: inventory.NewStoreFromSnapshot(snapshot)}, nil
+}
+
+func (m *Manager) save(e *entry) error {
+	if err := m.saveConfig(e); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(m.dir, e.workspace.Name, inventoryFile), e.inventory.Snapshot())
+}
+
+func (m *Manager) saveConfig(e *entry) error {
+	return writeJSON(filepath.Join(m.dir, e.workspace.Name, configFile), e.workspace)
+}
+
+// normalize replaces nil slices so that workspaces always encode lists as
+// arrays rather than null.
+func normalize(w *Workspace) {
+	if w.Scope.Include == nil {
+		w.Scope.Include = []string{}
+	}
+	if w.Scope.Exclude == nil {
+		w.Scope.Exclude = []string{}
+	}
+	if w.Targets == nil {
+		w.Targets = []string{}
+	}
+	if w.Schedules == nil {
+		w.Schedules = []Schedule{}
+	}
+	if w.Notifications.Webhooks == nil {
+		w.Notifications.Webhooks = []Webhook{}
+	}
+}
+
+// writeJSON writes v to a temporary file next to path and renames it into
+// place, so a failed write never leaves a truncated file behind.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
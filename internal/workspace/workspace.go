@@ -0,0 +1,181 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/IxBahy/ASM/internal/inventory"
+	"github.com/IxBahy/ASM/pkg/utils/extractor"
+)
+
+// Default is the workspace used when none is given.
+const Default = "default"
+
+var (
+	ErrNotFound      = errors.New("workspace not found")
+	ErrAlreadyExists = errors.New("workspace already exists")
+
+	namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+)
+
+// Workspace separates the targets, schedules, inventory, findings and
+// notifications of one client or business unit from every other.
+type Workspace struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description,omitempty"`
+	Scope         Scope         `json:"scope"`
+	Targets       []string      `json:"targets"`
+	Schedules     []Schedule    `json:"schedules"`
+	Notifications Notifications `json:"notifications"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// Scope limits the targets that may be scanned in a workspace. Entries are
+// domains, which also match their subdomains, IP addresses, CIDR networks
+// or address ranges such as 10.0.0.1-10.0.0.20.
+// An empty Include list allows every target that is not excluded. Public
+// suffixes such as co.uk or github.io cannot be included, since their
+// subdomains belong to unrelated organizations.
+type Scope struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// Schedule runs scanners against every target of the workspace at a fixed
+// interval.
+type Schedule struct {
+	Name     string   `json:"name"`
+	Scanners []string `json:"scanners"`
+	Interval Duration `json:"interval"`
+}
+
+// Notifications configures where new findings of a workspace are sent.
+type Notifications struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// Webhook receives a JSON POST for each new finding at or above MinSeverity.
+type Webhook struct {
+	URL         string             `json:"url"`
+	MinSeverity inventory.Severity `json:"min_severity,omitempty"`
+}
+
+// Duration is a time.Duration that is encoded in JSON as a string such as
+// "24h".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"24h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Validate checks the workspace configuration for mistakes that would only
+// show up when a schedule or notification runs.
+func (w Workspace) Validate() error {
+	if err := ValidateName(w.Name); err != nil {
+		return err
+	}
+	for _, entry := range append(append([]string{}, w.Scope.Include...), w.Scope.Exclude...) {
+		if _, err := extractor.Parse(entry); err != nil {
+			return fmt.Errorf("invalid scope entry: %w", err)
+		}
+	}
+	for _, entry := range w.Scope.Include {
+		if extractor.IsPublicSuffix(entry) {
+			return fmt.Errorf("scope entry %s is a public suffix; include the registrable domains under it instead", entry)
+		}
+	}
+	for _, target := range w.Targets {
+		if !w.Scope.Allows(target) {
+			return fmt.Errorf("target %s is outside the workspace scope", target)
+		}
+	}
+	for _, schedule := range w.Schedules {
+		if schedule.Name == "" {
+			return fmt.Errorf("schedule name is required")
+		}
+		if len(schedule.Scanners) == 0 {
+			return fmt.Errorf("schedule %s has no scanners", schedule.Name)
+		}
+		if schedule.Interval.Duration < time.Minute {
+			return fmt.Errorf("schedule %s interval must be at least one minute", schedule.Name)
+		}
+	}
+	for _, webhook := range w.Notifications.Webhooks {
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL: %s", webhook.URL)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether target falls inside the scope. A network, range
+// or wildcard target is only allowed if all of it is included and none of
+// it is excluded, and a wildcard never spans a public suffix such as
+// *.co.uk. Targets that are not hosts or networks, such as paths, are only
+// allowed by an empty Include list.
+func (s Scope) Allows(target string) bool {
+	parsed, err := extractor.Parse(target)
+	if err != nil {
+		return len(s.Include) == 0
+	}
+	if parsed.Wildcard && extractor.IsPublicSuffix(parsed.Host) {
+		return false
+	}
+
+	for _, entry := range s.Exclude {
+		excluded, ok := parseScopeEntry(entry)
+		if !ok {
+			continue
+		}
+		// A domain is only excluded by itself or a parent domain, but a
+		// wildcard or network as soon as part of it is.
+		if parsed.IsHost() && excluded.Contains(parsed) || !parsed.IsHost() && excluded.Overlaps(parsed) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, entry := range s.Include {
+		if extractor.IsPublicSuffix(entry) {
+			continue
+		}
+		if included, ok := parseScopeEntry(entry); ok && included.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScopeEntry parses a scope entry; only its host or network counts.
+func parseScopeEntry(entry string) (extractor.Target, bool) {
+	parsed, err := extractor.Parse(entry)
+	return parsed, err == nil
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Placeholders that asset patterns may use in place of a hard-coded
+// platform, e.g. `subfinder_.*_{{os}}_{{arch}}\.zip$`.
+const (
+	PlaceholderOS   = "{{os}}"
+	PlaceholderArch = "{{arch}}"
+)
+
+// osAliases lists, for each GOOS, the names release assets use for it in
+// order of preference.
+var osAliases = map[string][]string{
+	"linux":   {"linux"},
+	"darwin":  {"darwin", "macos", "mac", "osx", "apple"},
+	"windows": {"windows", "win"},
+	"freebsd": {"freebsd"},
+}
+
+// archAliases lists, for each GOARCH, the names release assets use for it in
+// order of preference.
+var archAliases = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x64", "64bit", "64-bit"},
+	"arm64": {"arm64", "aarch64", "armv8"},
+	"386":   {"386", "i386", "i686", "x86", "32bit", "32-bit"},
+	"arm":   {"armv7", "armv6", "arm"},
+}
+
+// nonBinarySuffixes are release files published next to the binaries, which
+// a loose pattern such as `tool_.*_linux_amd64` would otherwise also match.
+var nonBinarySuffixes = []string{".sig", ".minisig", ".asc", ".pem", ".sbom", ".sbom.json", ".sha256", ".sha512", ".intoto.jsonl"}
+
+// Platform identifies the operating system and architecture an asset is
+// selected for, using GOOS and GOARCH values.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// CurrentPlatform returns the platform the binary is running on.
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// SelectAsset returns the release asset matching pattern for the platform.
+//
+// The {{os}} and {{arch}} placeholders in pattern are replaced with each
+// alias of the platform's OS and architecture in turn, matched without
+// regard to case, and the first combination that matches any asset wins.
+// When several assets match the same combination the one with the
+// lexically smallest name is chosen, so the result does not depend on the
+// order GitHub lists assets in. Signatures, certificates and SBOMs are never
+// selected.
+func SelectAsset(assets []Asset, pattern string, platform Platform) (Asset, error) {
+	candidates := make([]Asset, 0, len(assets))
+	for _, asset := range assets {
+		if !isNonBinary(asset.Name) {
+			candidates = append(candidates, asset)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name < candidates[j].Name })
+
+	patterns, err := expandPattern(pattern, platform)
+	if err != nil {
+		return Asset{}, err
+	}
+	for _, re := range patterns {
+		for _, asset := range candidates {
+			if re.MatchString(asset.Name) {
+				return asset, nil
+			}
+		}
+	}
+
+	names := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		names = append(names, asset.Name)
+	}
+	sort.Strings(names)
+	return Asset{}, fmt.Errorf("no release asset matches %q for %s; available assets: %s", pattern, platform, strings.Join(names, ", "))
+}
+
+// expandPattern compiles pattern once for each combination of platform
+// aliases, most preferred first.
+func expandPattern(pattern string, platform Platform) ([]*regexp.Regexp, error) {
+	osNames := []string{""}
+	if strings.Contains(pattern, PlaceholderOS) {
+		osNames = aliases(osAliases, platform.OS)
+	}
+	archNames := []string{""}
+	if strings.Contains(pattern, PlaceholderArch) {
+		archNames = aliases(archAliases, platform.Arch)
+	}
+
+	var patterns []*regexp.Regexp
+	for _, osName := range osNames {
+		for _, archName := range archNames {
+			expanded := strings.ReplaceAll(pattern, PlaceholderOS, "(?i:"+regexp.QuoteMeta(osName)+")")
+			expanded = strings.ReplaceAll(expanded, PlaceholderArch, "(?i:"+regexp.QuoteMeta(archName)+")")
+
+			re, err := regexp.Compile(expanded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid asset pattern %q: %w", pattern, err)
+			}
+			patterns = append(patterns, re)
+		}
+	}
+	return patterns, nil
+}
+
+func aliases(table map[string][]string, name string) []string {
+	if names, ok := table[name]; ok {
+		return names
+	}
+	return []string{name}
+}
+
+func isNonBinary(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range nonBinarySuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,517 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aead.dev/minisign"
+)
+
+// BundleBuilder collects installation artifacts into a staging directory
+// laid out like a Mirror, and writes them out as a signed offline bundle.
+type BundleBuilder struct {
+	dir        string
+	platforms  []Platform
+	httpClient *http.Client
+	tools      []BundleTool
+}
+
+// NewBundleBuilder creates a builder collecting GitHub release assets for
+// each of platforms. Call Close to remove the staging directory.
+func NewBundleBuilder(platforms []Platform) (*BundleBuilder, error) {
+	if len(platforms) == 0 {
+		platforms = []Platform{CurrentPlatform()}
+	}
+	dir, err := os.MkdirTemp("", "asm-bundle-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return &BundleBuilder{
+		dir:        dir,
+		platforms:  platforms,
+		httpClient: &http.Client{Timeout: 15 * time.Minute},
+	}, nil
+}
+
+// Close removes the staging directory.
+func (b *BundleBuilder) Close() error {
+	// Module cache files are read-only.
+	filepath.WalkDir(b.dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(path, 0755)
+		}
+		return nil
+	})
+	return os.RemoveAll(b.dir)
+}
+
+// Tools returns the artifacts collected so far.
+func (b *BundleBuilder) Tools() []BundleTool {
+	return b.tools
+}
+
+// AddGithubRelease downloads the assets matching pattern for every platform
+// from the release behind apiURL, verifies them like an install would, and
+// stores them with the release's checksums file and signatures.
+func (b *BundleBuilder) AddGithubRelease(name, apiURL, pattern string, verification Verification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	ref, ok := parseReleaseURL(apiURL)
+	if !ok {
+		return fmt.Errorf("%s is not a GitHub release API URL", apiURL)
+	}
+	c := &GithubClient{httpClient: b.httpClient, verification: verification, api: newGithubAPI(b.httpClient)}
+	release, err := c.fetchRelease(ref)
+	if err != nil {
+		return err
+	}
+	c.release = release
+
+	dir := filepath.Join(b.dir, "github", filepath.FromSlash(ref.repo))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	var included []Asset
+	include := func(asset *Asset) error {
+		if asset == nil {
+			return nil
+		}
+		for _, existing := range included {
+			if existing.Name == asset.Name {
+				return nil
+			}
+		}
+		if err := b.download(ctx, c, *asset, filepath.Join(dir, asset.Name)); err != nil {
+			return err
+		}
+		included = append(included, Asset{Name: asset.Name, BrowserDownloadURL: asset.Name})
+		return nil
+	}
+
+	checksums, err := c.findChecksumAsset()
+	if err != nil {
+		return err
+	}
+	if checksums != nil {
+		if err := include(checksums); err != nil {
+			return err
+		}
+		for _, extension := range []string{".minisig", ".sig"} {
+			if err := include(c.findAsset(checksums.Name + extension)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, platform := range b.platforms {
+		asset, err := SelectAsset(release.Assets, pattern, platform)
+		if err != nil {
+			return fmt.Errorf("release %s: %w", release.TagName, err)
+		}
+		if err := include(&asset); err != nil {
+			return err
+		}
+		for _, extension := range []string{".minisig", ".sig"} {
+			if err := include(c.findAsset(asset.Name + extension)); err != nil {
+				return err
+			}
+		}
+		if err := c.verify(ctx, asset.Name, filepath.Join(dir, asset.Name)); err != nil {
+			return fmt.Errorf("failed to verify %s: %w", asset.Name, err)
+		}
+	}
+
+	if err := writeJSONFile(filepath.Join(dir, "release.json"), GithubRelease{TagName: release.TagName, Assets: included}); err != nil {
+		return err
+	}
+
+	b.tools = append(b.tools, BundleTool{
+		Name:    name,
+		Type:    string(InstallationTypeGithub),
+		Version: strings.TrimPrefix(release.TagName, "v"),
+		Path:    "github/" + ref.repo,
+	})
+	fmt.Printf("Bundled %s %s (%d files)\n", name, release.TagName, len(included))
+	return nil
+}
+
+// AddPythonPackage downloads the package and its dependencies for every
+// platform with "pip download". Packages for the build host may fall back
+// to source distributions; for other platforms only wheels can be
+// selected, so a dependency without a wheel for one of them fails the
+// bundle.
+func (b *BundleBuilder) AddPythonPackage(name, packageName, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	python, _, err := findPython3(ctx)
+	if err != nil {
+		return err
+	}
+
+	requirement := packageName
+	if version != "" && version != "latest" {
+		requirement = fmt.Sprintf("%s==%s", packageName, version)
+	}
+	dir := filepath.Join(b.dir, "python", packageName)
+
+	for _, platform := range b.platforms {
+		args := []string{"-m", "pip", "download", "--dest", dir}
+		if platform != CurrentPlatform() {
+			tags, err := pythonPlatformTags(platform)
+			if err != nil {
+				return fmt.Errorf("cannot bundle %s: %w", requirement, err)
+			}
+			for _, tag := range tags {
+				args = append(args, "--platform", tag)
+			}
+			args = append(args, "--only-binary=:all:")
+		}
+		args = append(args, requirement)
+
+		fmt.Printf("Downloading %s and its dependencies for %s ...\n", requirement, platform)
+		cmd := exec.CommandContext(ctx, python, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to download %s for %s: %w", requirement, platform, err)
+		}
+	}
+
+	b.tools = append(b.tools, BundleTool{
+		Name:    name,
+		Type:    string(InstallationTypePython),
+		Version: downloadedPythonVersion(dir, packageName, version),
+		Path:    "python/" + packageName,
+	})
+	fmt.Printf("Bundled %s\n", requirement)
+	return nil
+}
+
+// pythonArchs maps GOARCH values to the machine names in wheel platform
+// tags.
+var pythonArchs = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+	"386":   "i686",
+	"arm":   "armv7l",
+}
+
+// pythonPlatformTags returns the wheel platform tags pip accepts for
+// platform. pip extends manylinux2014 to the older manylinux tags and a
+// macOS version to the older ones, but does not list the glibc versions of
+// the newer manylinux tags, so those are listed up to glibc 2.28.
+func pythonPlatformTags(platform Platform) ([]string, error) {
+	switch platform.OS {
+	case "linux":
+		arch, ok := pythonArchs[platform.Arch]
+		if !ok {
+			break
+		}
+		var tags []string
+		for minor := 28; minor >= 17; minor-- {
+			tags = append(tags, fmt.Sprintf("manylinux_2_%d_%s", minor, arch))
+		}
+		return append(tags, "manylinux2014_"+arch), nil
+	case "darwin":
+		switch platform.Arch {
+		case "amd64":
+			return []string{"macosx_14_0_x86_64"}, nil
+		case "arm64":
+			return []string{"macosx_14_0_arm64"}, nil
+		}
+	case "windows":
+		switch platform.Arch {
+		case "amd64":
+			return []string{"win_amd64"}, nil
+		case "arm64":
+			return []string{"win_arm64"}, nil
+		case "386":
+			return []string{"win32"}, nil
+		}
+	}
+	return nil, fmt.Errorf("no Python wheels are published for %s", platform)
+}
+
+// AddGoModule downloads the sources of every module needed to build the
+// package at version, in the layout of a module proxy.
+func (b *BundleBuilder) AddGoModule(name, packagePath, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("go toolchain not found in PATH: %w", err)
+	}
+	if version == "" {
+		version = "latest"
+	}
+
+	buildDir, err := os.MkdirTemp("", "asm-go-bundle-*")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %w", err)
+	}
+	modCache := filepath.Join(buildDir, "modcache")
+	env := append(os.Environ(), "GOFLAGS="+goFlags(goCmd), "GOWORK=off", "GOMODCACHE="+modCache)
+	defer func() {
+		clean := exec.Command(goCmd, "clean", "-modcache")
+		clean.Env = env
+		clean.Run()
+		os.RemoveAll(buildDir)
+	}()
+
+	run := func(args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, goCmd, args...)
+		cmd.Dir = buildDir
+		cmd.Env = env
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go %s: %w", strings.Join(args, " "), err)
+		}
+		return output, nil
+	}
+
+	target := packagePath + "@" + version
+	fmt.Printf("Downloading the modules needed to build %s ...\n", target)
+	if _, err := run("mod", "init", "asm-tool-build"); err != nil {
+		return fmt.Errorf("failed to download %s: %w", target, err)
+	}
+	if _, err := run("get", target); err != nil {
+		return fmt.Errorf("failed to download %s: %w", target, err)
+	}
+	output, err := run("list", "-f", "{{.Module.Version}}", packagePath)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", target, err)
+	}
+
+	if err := copyTree(filepath.Join(modCache, "cache", "download"), filepath.Join(b.dir, "go")); err != nil {
+		return fmt.Errorf("failed to copy module sources: %w", err)
+	}
+
+	resolved := strings.TrimSpace(string(output))
+	b.tools = append(b.tools, BundleTool{
+		Name:    name,
+		Type:    string(InstallationTypeInternal),
+		Version: resolved,
+		Path:    "go",
+	})
+	fmt.Printf("Bundled %s@%s\n", packagePath, resolved)
+	return nil
+}
+
+// AddTemplates downloads a gzipped tarball of templates, such as a
+// repository archive, and stores it as templates/<name>.tar.gz.
+func (b *BundleBuilder) AddTemplates(name, url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	path := filepath.Join(b.dir, "templates", name+".tar.gz")
+	c := &GithubClient{httpClient: b.httpClient, api: newGithubAPI(b.httpClient)}
+	if err := b.download(ctx, c, Asset{Name: name, BrowserDownloadURL: url}, path); err != nil {
+		return err
+	}
+
+	b.tools = append(b.tools, BundleTool{
+		Name:    name,
+		Type:    "templates",
+		Version: time.Now().UTC().Format("2006-01-02"),
+		Path:    "templates/" + name + ".tar.gz",
+	})
+	fmt.Printf("Bundled %s\n", name)
+	return nil
+}
+
+// Write signs a manifest of the collected files with key and writes the
+// bundle to w as a gzipped tarball.
+func (b *BundleBuilder) Write(w io.Writer, key minisign.PrivateKey) error {
+	manifest := BundleManifest{
+		Version:   bundleFormatVersion,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Tools:     b.tools,
+		Files:     make(map[string]string),
+	}
+	for _, platform := range b.platforms {
+		manifest.Platforms = append(manifest.Platforms, platform.String())
+	}
+
+	err := filepath.WalkDir(b.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil {
+			return err
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files[filepath.ToSlash(rel)] = digest
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to hash bundle contents: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	signature := minisign.SignWithComments(key, data, "asm tools bundle "+manifest.CreatedAt.Format(time.RFC3339), "")
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	files := []struct {
+		name string
+		data []byte
+	}{{bundleManifestName, data}, {bundleSignatureName, signature}}
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data)), ModTime: manifest.CreatedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+
+	err = filepath.WalkDir(b.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{Name: filepath.ToSlash(rel), Mode: 0644, Size: info.Size(), ModTime: manifest.CreatedAt}
+		if info.Mode()&0111 != 0 {
+			header.Mode = 0755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return gzw.Close()
+}
+
+func (b *BundleBuilder) download(ctx context.Context, c *GithubClient, asset Asset, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+
+	// Bundles are built from fresh downloads, so the cache is not used.
+	downloads := &downloader{httpClient: b.httpClient, authorize: c.api.authorize}
+	fmt.Printf("Downloading %s ...\n", asset.Name)
+	if _, err := downloads.fetch(ctx, asset.BrowserDownloadURL, "", asset.Name, path); err != nil {
+		return fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	return nil
+}
+
+// downloadedPythonVersion finds the version of packageName among the
+// distributions pip downloaded into dir, falling back to version.
+func downloadedPythonVersion(dir, packageName, version string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return version
+	}
+	// Distribution file names normalize "-" and "." in the project name.
+	prefix := strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(packageName)) + "-"
+	for _, entry := range entries {
+		name := strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(entry.Name()))
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// <name>-<version>-<tags>.whl or <name>-<version>.tar.gz
+		fields := strings.Split(entry.Name()[len(prefix):], "-")
+		return strings.TrimSuffix(strings.TrimSuffix(fields[0], ".tar.gz"), ".zip")
+	}
+	return version
+}
+
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		// Lock files and partial downloads are not part of the proxy.
+		if strings.HasSuffix(path, ".lock") || strings.HasSuffix(path, ".partial") || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
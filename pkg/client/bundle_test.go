@@ -0,0 +1,40 @@
+package client
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPythonPlatformTags(t *testing.T) {
+	tests := []struct {
+		platform Platform
+		want     []string
+		wantErr  bool
+	}{
+		{platform: Platform{"linux", "amd64"}, want: []string{"manylinux_2_28_x86_64", "manylinux_2_17_x86_64", "manylinux2014_x86_64"}},
+		{platform: Platform{"linux", "arm64"}, want: []string{"manylinux_2_28_aarch64", "manylinux2014_aarch64"}},
+		{platform: Platform{"darwin", "arm64"}, want: []string{"macosx_14_0_arm64"}},
+		{platform: Platform{"windows", "amd64"}, want: []string{"win_amd64"}},
+		{platform: Platform{"freebsd", "amd64"}, wantErr: true},
+		{platform: Platform{"linux", "riscv64"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform.String(), func(t *testing.T) {
+			tags, err := pythonPlatformTags(tt.platform)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("pythonPlatformTags = %v, want an error", tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pythonPlatformTags: %v", err)
+			}
+			for _, tag := range tt.want {
+				if !slices.Contains(tags, tag) {
+					t.Errorf("pythonPlatformTags = %v, want it to contain %s", tags, tag)
+				}
+			}
+		})
+	}
+}
@@ -3,3 +3,52 @@ package client
 type ToolInstaller interface {
 	InstallTool() error
 }
+
+// VersionReporter is implemented by installers that know the exact version
+// they installed, such as the module version of a Go build.
+type VersionReporter interface {
+	InstalledVersion() string
+}
+
+// Upgrader is implemented by installers that can upgrade an installed tool
+// in place.
+type Upgrader interface {
+	UpgradeTool() error
+}
+
+// Uninstaller is implemented by installers that can remove the tools they
+// installed.
+type Uninstaller interface {
+	UninstallTool() error
+}
+
+// Rollbacker is implemented by installers that keep the version an install
+// replaced, and can restore it.
+type Rollbacker interface {
+	RollbackTool() error
+}
+
+// Resolution describes exactly what an installer installed, for recording
+// in a lockfile.
+type Resolution struct {
+	// Version is the resolved version, never "latest".
+	Version string
+	// Tag is the GitHub release tag the version came from.
+	Tag string
+	// Source is the package, module or download URL installed from.
+	Source string
+	// SHA256 is the digest of the downloaded artifact, if there was one.
+	SHA256 string
+}
+
+// Resolver is implemented by installers that can report what they
+// installed.
+type Resolver interface {
+	Resolution() Resolution
+}
+
+// VersionChecker is implemented by installers that can look up the newest
+// version available upstream.
+type VersionChecker interface {
+	LatestVersion() (string, error)
+}
@@ -0,0 +1,462 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/client/utils"
+)
+
+const (
+	// DownloadCacheEnv overrides the download cache directory; "off"
+	// disables the cache.
+	DownloadCacheEnv = "ASM_DOWNLOAD_CACHE"
+
+	// downloadAttempts is how often a download is attempted, resuming
+	// where the previous attempt stopped.
+	downloadAttempts = 5
+	// progressInterval throttles progress events.
+	progressInterval = 250 * time.Millisecond
+)
+
+// DownloadProgress is a progress event of a download.
+type DownloadProgress struct {
+	// Name is the file being downloaded.
+	Name string
+	// Downloaded counts the bytes received so far, including those of
+	// earlier, interrupted attempts.
+	Downloaded int64
+	// Total is the size of the file, or -1 if the server did not send it.
+	Total int64
+	// Rate is the average speed of the current attempt in bytes per second.
+	Rate float64
+	// Cached is set if the file was served from the download cache.
+	Cached bool
+	// Done is set on the last event of a download.
+	Done bool
+}
+
+// ProgressFunc receives download progress events. It may be called from
+// several goroutines at once, for different files.
+type ProgressFunc func(DownloadProgress)
+
+var (
+	progressMu sync.RWMutex
+	progress   ProgressFunc
+)
+
+// SetProgressFunc registers f to receive the progress of every download.
+// Pass nil to stop reporting progress.
+func SetProgressFunc(f ProgressFunc) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progress = f
+}
+
+func reportProgress(event DownloadProgress) {
+	progressMu.RLock()
+	f := progress
+	progressMu.RUnlock()
+	if f != nil {
+		f(event)
+	}
+}
+
+// DownloadCacheDir returns the download cache directory,
+// $XDG_CACHE_HOME/asm/downloads by default, or "" if the cache is disabled.
+func DownloadCacheDir() string {
+	if dir := os.Getenv(DownloadCacheEnv); dir != "" {
+		if dir == "off" {
+			return ""
+		}
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "asm", "downloads")
+	}
+	return filepath.Join(".asm", "cache", "downloads")
+}
+
+// DownloadCache holds verified downloads, addressed by their SHA-256:
+//
+//	blobs/<sha256>        file contents
+//	urls/<sha256 of URL>  SHA-256 of the contents last stored for the URL
+//	partial/<sha256 of URL>[.etag]  interrupted downloads, to be resumed
+//
+// A download is looked up by its expected digest when one is known, such
+// as a digest pinned in a lockfile, and by its URL otherwise. Only files
+// that passed verification are stored, and files are hashed again when
+// they are read back.
+type DownloadCache struct {
+	Dir string
+}
+
+// OpenDownloadCache returns the cache in DownloadCacheDir, or nil if the
+// cache is disabled.
+func OpenDownloadCache() *DownloadCache {
+	dir := DownloadCacheDir()
+	if dir == "" {
+		return nil
+	}
+	return &DownloadCache{Dir: dir}
+}
+
+func urlKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns the path of the cached contents of url, or of the file
+// with the expected digest if it is set.
+func (dc *DownloadCache) lookup(url, digest string) (string, bool) {
+	if dc == nil {
+		return "", false
+	}
+	if digest == "" {
+		data, err := os.ReadFile(filepath.Join(dc.Dir, "urls", urlKey(url)))
+		if err != nil {
+			return "", false
+		}
+		digest = strings.TrimSpace(string(data))
+	}
+	path := filepath.Join(dc.Dir, "blobs", strings.ToLower(digest))
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// partialPath returns where an interrupted download of url is kept.
+func (dc *DownloadCache) partialPath(url string) string {
+	return filepath.Join(dc.Dir, "partial", urlKey(url))
+}
+
+// Store adds the verified file at path, downloaded from url, to the cache.
+func (dc *DownloadCache) Store(url, path, digest string) error {
+	if dc == nil {
+		return nil
+	}
+	blob := filepath.Join(dc.Dir, "blobs", digest)
+	if _, err := os.Stat(blob); err != nil {
+		if err := writeFileAtomic(blob, func(w io.Writer) error {
+			src, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			_, err = io.Copy(w, src)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to cache %s: %w", url, err)
+		}
+	}
+	return writeFileAtomic(filepath.Join(dc.Dir, "urls", urlKey(url)), func(w io.Writer) error {
+		_, err := io.WriteString(w, digest+"\n")
+		return err
+	})
+}
+
+// Clear removes every cached and partial download.
+func (dc *DownloadCache) Clear() error {
+	for _, sub := range []string{"blobs", "urls", "partial"} {
+		if err := os.RemoveAll(filepath.Join(dc.Dir, sub)); err != nil {
+			return fmt.Errorf("failed to clear download cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// Size returns the number of cached files and their total size.
+func (dc *DownloadCache) Size() (int, int64, error) {
+	entries, err := os.ReadDir(filepath.Join(dc.Dir, "blobs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read download cache: %w", err)
+	}
+	var size int64
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+	}
+	return len(entries), size, nil
+}
+
+// writeFileAtomic writes path through a temporary file in the same
+// directory, so readers never see a partial file.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// downloader fetches files over HTTP, resuming interrupted downloads with
+// range requests and reporting progress.
+type downloader struct {
+	httpClient *http.Client
+	cache      *DownloadCache
+	// authorize adds credentials to requests, if set.
+	authorize func(*http.Request)
+}
+
+// fetch downloads url into dest, from the cache if it holds the file with
+// the expected digest or, without one, the file last stored for url.
+// It reports whether the file came from the cache; the caller stores it
+// there once it has been verified.
+func (d *downloader) fetch(ctx context.Context, url, digest, name, dest string) (bool, error) {
+	if path, found := strings.CutPrefix(url, "file://"); found {
+		// Files in an offline mirror are already local.
+		return false, utils.CopyFile(filepath.FromSlash(path), dest)
+	}
+	if cached, ok := d.cache.lookup(url, digest); ok {
+		if err := copyVerified(cached, dest); err == nil {
+			reportProgress(DownloadProgress{Name: name, Downloaded: fileSize(dest), Total: fileSize(dest), Cached: true, Done: true})
+			fmt.Printf("Using cached %s\n", name)
+			return true, nil
+		}
+		// A corrupted entry is downloaded again and replaced.
+		os.Remove(cached)
+	}
+
+	partial := dest + ".partial"
+	if d.cache != nil {
+		partial = d.cache.partialPath(url)
+		if err := os.MkdirAll(filepath.Dir(partial), 0755); err != nil {
+			return false, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(partial), err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := d.attempt(ctx, url, name, partial)
+		if err == nil {
+			break
+		}
+		var status *httpStatusError
+		if ctx.Err() != nil || attempt == downloadAttempts || (errors.As(err, &status) && !status.retryable()) {
+			// Keep what was downloaded for the next run to resume, unless
+			// the server refused the file.
+			if d.cache == nil || errors.As(err, &status) {
+				os.Remove(partial)
+				os.Remove(partial + ".etag")
+			}
+			return false, err
+		}
+		fmt.Printf("Download of %s interrupted (%v), resuming (attempt %d of %d)\n", name, err, attempt+1, downloadAttempts)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	os.Remove(partial + ".etag")
+	if err := os.Rename(partial, dest); err != nil {
+		// The cache may be on another filesystem.
+		if err := utils.CopyFile(partial, dest); err != nil {
+			return false, err
+		}
+		os.Remove(partial)
+	}
+	return false, nil
+}
+
+// attempt downloads url into partial, continuing after the bytes partial
+// already holds if the server supports range requests.
+func (d *downloader) attempt(ctx context.Context, url, name, partial string) error {
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", partial, err)
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// Only resume if the file has not changed since.
+		if etag, err := os.ReadFile(partial + ".etag"); err == nil {
+			req.Header.Set("If-Range", string(etag))
+		}
+	}
+	if d.authorize != nil {
+		d.authorize(req)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server sent the whole file.
+		offset = 0
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			file.Truncate(0)
+			return fmt.Errorf("server resumed at the wrong offset: %s", resp.Header.Get("Content-Range"))
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is complete if the server says it has its size.
+		if resp.Header.Get("Content-Range") == fmt.Sprintf("bytes */%d", offset) {
+			return nil
+		}
+		file.Truncate(0)
+		return fmt.Errorf("server rejected resuming at byte %d", offset)
+	default:
+		return &httpStatusError{code: resp.StatusCode}
+	}
+
+	// Weak ETags do not guarantee byte-identical content.
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		os.WriteFile(partial+".etag", []byte(etag), 0644)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	writer := &progressWriter{
+		w:       file,
+		event:   DownloadProgress{Name: name, Downloaded: offset, Total: total},
+		started: time.Now(),
+		offset:  offset,
+	}
+	if _, err := io.Copy(writer, resp.Body); err != nil {
+		return err
+	}
+	if total >= 0 && writer.event.Downloaded != total {
+		return fmt.Errorf("connection closed after %d of %d bytes", writer.event.Downloaded, total)
+	}
+	writer.event.Done = true
+	writer.report()
+	return nil
+}
+
+// progressWriter reports the progress of the writes to w.
+type progressWriter struct {
+	w        io.Writer
+	event    DownloadProgress
+	started  time.Time
+	offset   int64
+	reported time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.event.Downloaded += int64(n)
+	if time.Since(p.reported) >= progressInterval {
+		p.report()
+	}
+	return n, err
+}
+
+func (p *progressWriter) report() {
+	if elapsed := time.Since(p.started).Seconds(); elapsed > 0 {
+		p.event.Rate = float64(p.event.Downloaded-p.offset) / elapsed
+	}
+	p.reported = time.Now()
+	reportProgress(p.event)
+}
+
+// httpStatusError is an unexpected HTTP response status.
+type httpStatusError struct {
+	code int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.code)
+}
+
+// retryable reports whether the request may succeed if it is repeated.
+func (e *httpStatusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// copyVerified copies the cached file src, which is named after its
+// SHA-256, to dest, failing if its contents no longer match the name.
+func copyVerified(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), in); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != filepath.Base(src) {
+		return fmt.Errorf("cached file %s is corrupted", src)
+	}
+	return out.Close()
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// FormatBytes formats a byte count for humans, e.g. "12.3 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
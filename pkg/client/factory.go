@@ -14,6 +14,8 @@ const (
 	InstallationTypeInternal InstallationType = "internal"
 )
 
+// ClientFactory creates the install client for installType. timeout bounds
+// each step of an install, such as a download or a package manager run.
 func ClientFactory(installType InstallationType, install_args []string, timeout time.Duration) (ToolInstaller, error) {
 	var client ToolInstaller
 	var err error
@@ -26,10 +28,7 @@ func ClientFactory(installType InstallationType, install_args []string, timeout
 		client, err = NewShellClient(install_args, timeout)
 
 	case InstallationTypeInternal:
-		// TODO: Implement internal installation logic
-		// For now, we return an error
-		client = nil
-		err = fmt.Errorf("internal installation type is not implemented yet")
+		client, err = NewGoClient(install_args, timeout)
 
 	case InstallationTypePython:
 		client, err = NewPythonClient(install_args, timeout)
@@ -2,13 +2,12 @@ package client
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
@@ -16,8 +15,10 @@ import (
 )
 
 type GithubRelease struct {
-	TagName string  `json:"tag_name"`
-	Assets  []Asset `json:"assets"`
+	TagName    string  `json:"tag_name"`
+	Draft      bool    `json:"draft,omitempty"`
+	Prerelease bool    `json:"prerelease,omitempty"`
+	Assets     []Asset `json:"assets"`
 }
 
 type Asset struct {
@@ -31,11 +32,39 @@ type GithubClient struct {
 	DownloadUrl  string
 	destPath     string
 	assetPattern string
+	verification Verification
+	// prerelease allows installing pre-releases.
+	prerelease bool
+	// member selects the executable in the release archive.
+	member    string
+	api       *githubAPI
+	downloads *downloader
+	// release is the release the asset was selected from, if the download
+	// URL was resolved through the GitHub API.
+	release *GithubRelease
+	// mirror, when set, replaces GitHub as the source of releases.
+	mirror     *Mirror
+	resolution Resolution
 }
 
+// NewGithubClient creates a client from positional install arguments,
+// optionally followed by key=value options:
+//
+//	checksum-pattern=<regexp>  name of the release's checksums file
+//	sha256=<hex>               pinned digest of the asset
+//	minisign-key=<key>         required minisign public key
+//	cosign-key=<pem>           required cosign ECDSA public key
+//	allow-unverified=true      install when no checksums file is published
+//	prerelease=true            consider pre-releases
+//	member=<path or glob>      path of the executable in the release archive
+//
+// The URL is either a direct download URL or a GitHub API release URL,
+// ".../releases/latest" or ".../releases/tags/<tag>". With the latter and a
+// version other than "latest", the release tagged with the version is
+// installed.
 func NewGithubClient(install_args []string, timeout time.Duration) (*GithubClient, error) {
 	if len(install_args) < 4 {
-		return nil, fmt.Errorf("usage: github <url> <version> <dest_path> <asset_pattern>")
+		return nil, fmt.Errorf("usage: github <url> <asset_pattern> <version> <dest_path> [key=value...]")
 	}
 
 	url := install_args[0]
@@ -43,9 +72,27 @@ func NewGithubClient(install_args []string, timeout time.Duration) (*GithubClien
 	version := install_args[2]
 	destPath := install_args[3]
 
+	options, prerelease, member := splitInstallOptions(install_args[4:])
+	verification, err := parseVerification(options)
+	if err != nil {
+		return nil, err
+	}
+	// Reject a bad pattern now rather than after the release was fetched.
+	if _, err := expandPattern(assetPattern, CurrentPlatform()); err != nil {
+		return nil, err
+	}
+
+	mirror, err := MirrorFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout bounds each request; an interrupted download resumes
+	// where it stopped.
 	httpClient := &http.Client{
-		Timeout: timeout * time.Minute,
+		Timeout: timeout,
 	}
+	api := newGithubAPI(httpClient)
 
 	return &GithubClient{
 		DownloadUrl:  url,
@@ -53,9 +100,59 @@ func NewGithubClient(install_args []string, timeout time.Duration) (*GithubClien
 		destPath:     destPath,
 		httpClient:   httpClient,
 		assetPattern: assetPattern,
+		verification: verification,
+		prerelease:   prerelease,
+		member:       member,
+		api:          api,
+		downloads:    &downloader{httpClient: httpClient, cache: OpenDownloadCache(), authorize: api.authorize},
+		mirror:       mirror,
 	}, nil
 }
 
+// splitInstallOptions removes the prerelease and member options from
+// options, which are otherwise verification options.
+func splitInstallOptions(options []string) ([]string, bool, string) {
+	var rest []string
+	prerelease, member := false, ""
+	for _, option := range options {
+		if value, found := strings.CutPrefix(option, "prerelease="); found {
+			prerelease = value == "true"
+			continue
+		}
+		if value, found := strings.CutPrefix(option, "member="); found {
+			member = value
+			continue
+		}
+		rest = append(rest, option)
+	}
+	return rest, prerelease, member
+}
+
+func parseVerification(options []string) (Verification, error) {
+	var verification Verification
+	for _, option := range options {
+		key, value, found := strings.Cut(option, "=")
+		if !found {
+			return Verification{}, fmt.Errorf("invalid github install option %q: expected key=value", option)
+		}
+		switch key {
+		case "checksum-pattern":
+			verification.ChecksumPattern = value
+		case "sha256":
+			verification.SHA256 = value
+		case "minisign-key":
+			verification.MinisignPublicKey = value
+		case "cosign-key":
+			verification.CosignPublicKey = value
+		case "allow-unverified":
+			verification.AllowUnverified = value == "true"
+		default:
+			return Verification{}, fmt.Errorf("unknown github install option %q", key)
+		}
+	}
+	return verification, nil
+}
+
 func (c *GithubClient) InstallTool() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
@@ -78,32 +175,108 @@ func (c *GithubClient) InstallTool() error {
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
+	tempFile.Close()
 	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
 
 	fmt.Printf("Downloading %s, version :: %s ...\n", toolName, version)
-	if err := c.downloadFile(ctx, downloadURL, tempFile); err != nil {
+	cached, err := c.downloads.fetch(ctx, downloadURL, c.verification.SHA256, archiveName, tempFile.Name())
+	if err != nil {
 		return fmt.Errorf("failed to download %s: %w", toolName, err)
 	}
 
-	if utils.IsArchive(downloadURL) {
-		if err := utils.ExtractExecutable(tempFile.Name(), c.destPath, toolName, archiveName); err != nil {
+	if err := c.verify(ctx, archiveName, tempFile.Name()); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", archiveName, err)
+	}
+	digest, err := fileSHA256(tempFile.Name())
+	if err != nil {
+		return err
+	}
+	if !cached && c.mirror == nil {
+		if err := c.downloads.cache.Store(downloadURL, tempFile.Name(), digest); err != nil {
+			fmt.Printf("WARNING: %v\n", err)
+		}
+	}
+
+	staged, cleanup, err := utils.Stage(c.destPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if utils.IsArchive(archiveName) {
+		options := utils.ExtractOptions{ToolName: toolName, Member: c.member}
+		if err := utils.ExtractExecutable(tempFile.Name(), archiveName, staged, options); err != nil {
 			return fmt.Errorf("failed to extract executable: %w", err)
 		}
 	} else {
-		if err := utils.CopyFile(tempFile.Name(), c.destPath); err != nil {
+		if err := utils.CheckExecutable(tempFile.Name()); err != nil {
+			return fmt.Errorf("failed to install %s: %w", archiveName, err)
+		}
+		if err := utils.CopyFile(tempFile.Name(), staged); err != nil {
 			return fmt.Errorf("failed to copy executable: %w", err)
 		}
 	}
 
-	if err := os.Chmod(c.destPath, 0755); err != nil {
-		return fmt.Errorf("failed to make %s executable: %w", c.destPath, err)
+	if err := utils.Commit(staged, c.destPath); err != nil {
+		return err
+	}
+
+	c.resolution = Resolution{Version: version, Source: downloadURL, SHA256: digest}
+	if c.release != nil {
+		c.resolution.Tag = c.release.TagName
 	}
 
 	fmt.Printf("%s %s installed successfully at %s\n", toolName, version, c.destPath)
 	return nil
 }
 
+// Resolution reports the release and asset the last InstallTool installed.
+func (c *GithubClient) Resolution() Resolution {
+	return c.resolution
+}
+
+// LatestVersion returns the version of the newest release, from the mirror
+// in offline mode.
+func (c *GithubClient) LatestVersion() (string, error) {
+	var release *GithubRelease
+	var err error
+	if c.mirror != nil {
+		release, err = c.mirror.GithubRelease(c.DownloadUrl)
+	} else {
+		ref, ok := parseReleaseURL(c.DownloadUrl)
+		if !ok {
+			return "", fmt.Errorf("%s is not a GitHub release API URL", c.DownloadUrl)
+		}
+		ref.tag = ""
+		release, err = c.fetchRelease(ref)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(release.TagName, "v"), nil
+}
+
+// UninstallTool removes the installed executable.
+func (c *GithubClient) UninstallTool() error {
+	if err := os.Remove(c.destPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", c.destPath, err)
+	}
+	if err := utils.RemovePrevious(c.destPath); err != nil {
+		return err
+	}
+	fmt.Printf("%s uninstalled\n", filepath.Base(c.destPath))
+	return nil
+}
+
+// RollbackTool restores the executable the last install replaced.
+func (c *GithubClient) RollbackTool() error {
+	if err := utils.Rollback(c.destPath); err != nil {
+		return err
+	}
+	fmt.Printf("%s rolled back\n", filepath.Base(c.destPath))
+	return nil
+}
+
 // ensureDownloadableUrl ensures that the client has a direct downloadable URL.
 // If the current URL is a GitHub API URL pointing to releases, it resolves it
 // to a direct download URL using getReleaseDownloadURL.
@@ -115,36 +288,41 @@ func (c *GithubClient) InstallTool() error {
 //   - URL that will be processed as GitHub API URL: "https://api.github.com/repos/owner/repo/releases/latest"
 //   - URL that will be treated as direct download URL: "https://github.com/owner/repo/releases/download/v1.0/tool.tar.gz"
 //
+// A pinned version selects the release tagged with it, even if the URL
+// names the latest release.
+//
 // Returns:
 //   - The direct download URL
 //   - The resolved version string
 //   - Any error encountered during URL resolution
 func (c *GithubClient) ensureDownloadableUrl() (string, string, error) {
+	if c.mirror != nil {
+		return c.mirrorDownloadURL()
+	}
 
-	if !strings.Contains(c.DownloadUrl, "api.github.com") || !strings.Contains(c.DownloadUrl, "/releases/") {
+	ref, ok := parseReleaseURL(c.DownloadUrl)
+	if !ok {
 		return c.DownloadUrl, c.version, nil
 	}
+	if ref.tag == "" && c.version != "" && c.version != "latest" {
+		ref.tag = c.version
+	}
 
-	downloadURL, version, err := c.getReleaseDownloadURL(c.DownloadUrl, c.assetPattern)
+	downloadURL, version, err := c.getReleaseDownloadURL(ref, c.assetPattern)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to get download URL: %w", err)
 	}
 
-	resolvedVersion := c.version
-	if c.version == "latest" {
-		resolvedVersion = version
-	}
-
-	return downloadURL, resolvedVersion, nil
+	return downloadURL, version, nil
 }
 
-// getReleaseDownloadURL fetches the download URL for a specific GitHub release
-// getReleaseDownloadURL fetches release information from a GitHub repository API URL
-// and returns the download URL for an asset that matches the provided regex pattern.
+// getReleaseDownloadURL fetches release information from the GitHub API
+// and returns the download URL for the asset matching the provided pattern on
+// the current platform. See SelectAsset for how the pattern is matched.
 //
 // Parameters:
-//   - repoAPIURL: GitHub API URL for the repository release (e.g., "https://api.github.com/repos/owner/repo/releases/latest")
-//   - assetPattern: Regular expression pattern to match against asset names
+//   - ref: the repository and the tag of the release, or no tag for the latest release
+//   - assetPattern: Regular expression matched against asset names, optionally using the {{os}} and {{arch}} placeholders
 //
 // Returns:
 //   - The browser download URL of the matching asset
@@ -152,32 +330,60 @@ func (c *GithubClient) ensureDownloadableUrl() (string, string, error) {
 //   - An error if the request fails, JSON parsing fails, or no matching asset is found
 //
 // Errors:
-//   - When the HTTP request to GitHub API fails
+//   - When the HTTP request to GitHub API fails, or is rate limited for longer than allowed
+//   - When no release has the tag, or it is a pre-release and those are not allowed
 //   - When JSON decoding of response fails
-//   - When no asset matching the provided pattern is found
-func (c *GithubClient) getReleaseDownloadURL(repoAPIURL, assetPattern string) (string, string, error) {
-	resp, err := c.httpClient.Get(repoAPIURL)
+//   - When the pattern is invalid or no asset matches it; the error lists the release's assets
+func (c *GithubClient) getReleaseDownloadURL(ref releaseRef, assetPattern string) (string, string, error) {
+	release, err := c.fetchRelease(ref)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to fetch releases: %w", err)
+		return "", "", err
 	}
-	defer resp.Body.Close()
 
-	var release GithubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", "", fmt.Errorf("failed to parse release data: %w", err)
+	c.release = release
+	version := strings.TrimPrefix(release.TagName, "v")
+
+	asset, err := SelectAsset(release.Assets, assetPattern, CurrentPlatform())
+	if err != nil {
+		return "", "", fmt.Errorf("release %s: %w", release.TagName, err)
+	}
+
+	return asset.BrowserDownloadURL, version, nil
+}
+
+// fetchRelease fetches the metadata of the release ref names.
+func (c *GithubClient) fetchRelease(ref releaseRef) (*GithubRelease, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	release, err := c.api.release(ctx, ref, c.prerelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release of %s: %w", ref.repo, err)
 	}
+	return release, nil
+}
 
-	version := strings.TrimPrefix(release.TagName, "v")
+// mirrorDownloadURL resolves the asset from the release held by the offline
+// mirror. The release stands in for the one GitHub would return, so the
+// asset is verified against the checksums and signatures bundled with it.
+func (c *GithubClient) mirrorDownloadURL() (string, string, error) {
+	release, err := c.mirror.GithubRelease(c.DownloadUrl)
+	if err != nil {
+		return "", "", err
+	}
 
-	pattern := regexp.MustCompile(assetPattern)
+	version := strings.TrimPrefix(release.TagName, "v")
+	if c.version != "latest" && strings.TrimPrefix(c.version, "v") != version {
+		return "", "", fmt.Errorf("mirror holds release %s, not %s", release.TagName, c.version)
+	}
 
-	for _, asset := range release.Assets {
-		if pattern.MatchString(asset.Name) {
-			return asset.BrowserDownloadURL, version, nil
-		}
+	c.release = release
+	asset, err := SelectAsset(release.Assets, c.assetPattern, CurrentPlatform())
+	if err != nil {
+		return "", "", fmt.Errorf("release %s in mirror: %w", release.TagName, err)
 	}
 
-	return "", "", fmt.Errorf("could not find matching release asset with pattern: %s", assetPattern)
+	return asset.BrowserDownloadURL, version, nil
 }
 
 // downloadFile downloads content from the specified URL and writes it to the provided writer.
@@ -192,11 +398,24 @@ func (c *GithubClient) getReleaseDownloadURL(repoAPIURL, assetPattern string) (s
 //     the server returns a non-200 status code, or writing to the output fails
 //
 // The method uses the client's configured httpClient to perform the request.
+// file:// URLs, which point into an offline mirror, are read from disk.
 func (c *GithubClient) downloadFile(ctx context.Context, url string, w io.Writer) error {
+	if path, found := strings.CutPrefix(url, "file://"); found {
+		file, err := os.Open(filepath.FromSlash(path))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(w, file)
+		return err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
+	c.api.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// GithubAPIEnv sets the base URL of the GitHub API, such as
+	// "https://github.example.com/api/v3" for GitHub Enterprise Server.
+	// Release URLs under api.github.com are resolved against it.
+	GithubAPIEnv = "ASM_GITHUB_API_URL"
+	// GithubRateLimitWaitEnv sets how long to wait for the rate limit to
+	// reset, as a Go duration such as "5m", before failing instead.
+	GithubRateLimitWaitEnv = "ASM_GITHUB_RATE_LIMIT_WAIT"
+
+	defaultGithubAPI           = "https://api.github.com"
+	defaultGithubRateLimitWait = time.Minute
+	// releasesPerPage and maxReleasePages bound the search for a tag to the
+	// 1000 newest releases.
+	releasesPerPage = 100
+	maxReleasePages = 10
+	// maxRateLimitRetries is how often a rate-limited request is retried.
+	maxRateLimitRetries = 3
+)
+
+// GithubAPIURL returns the base URL of the GitHub API in use.
+func GithubAPIURL() string {
+	if base := strings.TrimRight(os.Getenv(GithubAPIEnv), "/"); base != "" {
+		return base
+	}
+	return defaultGithubAPI
+}
+
+// githubToken returns the token to authenticate to the GitHub API with,
+// from GITHUB_TOKEN or, as set by the gh CLI, GH_TOKEN.
+func githubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// releaseRef names a release of a repository. An empty tag stands for the
+// latest release.
+type releaseRef struct {
+	repo string
+	tag  string
+}
+
+// parseReleaseURL recognizes GitHub API release URLs,
+// "<base>/repos/<owner>/<repo>/releases/latest" and
+// "<base>/repos/<owner>/<repo>/releases/tags/<tag>", where base is
+// api.github.com or the configured GithubAPIURL.
+func parseReleaseURL(rawURL string) (releaseRef, bool) {
+	for _, base := range []string{GithubAPIURL(), defaultGithubAPI} {
+		rest, found := strings.CutPrefix(rawURL, base+"/repos/")
+		if !found {
+			continue
+		}
+		parts := strings.Split(rest, "/")
+		if len(parts) < 4 || parts[0] == "" || parts[1] == "" || parts[2] != "releases" {
+			return releaseRef{}, false
+		}
+		ref := releaseRef{repo: parts[0] + "/" + parts[1]}
+		switch {
+		case len(parts) == 4 && parts[3] == "latest":
+			return ref, true
+		case len(parts) == 5 && parts[3] == "tags" && parts[4] != "":
+			tag, err := url.PathUnescape(parts[4])
+			if err != nil {
+				return releaseRef{}, false
+			}
+			ref.tag = tag
+			return ref, true
+		}
+		return releaseRef{}, false
+	}
+	return releaseRef{}, false
+}
+
+// githubAPI looks up releases through the GitHub REST API, authenticating
+// with a token when one is set and waiting out short rate limits.
+type githubAPI struct {
+	httpClient *http.Client
+	base       string
+	token      string
+	maxWait    time.Duration
+}
+
+func newGithubAPI(httpClient *http.Client) *githubAPI {
+	maxWait := defaultGithubRateLimitWait
+	if value := os.Getenv(GithubRateLimitWaitEnv); value != "" {
+		if wait, err := time.ParseDuration(value); err == nil {
+			maxWait = wait
+		} else {
+			fmt.Printf("WARNING: ignoring invalid %s %q: %v\n", GithubRateLimitWaitEnv, value, err)
+		}
+	}
+	return &githubAPI{
+		httpClient: httpClient,
+		base:       GithubAPIURL(),
+		token:      githubToken(),
+		maxWait:    maxWait,
+	}
+}
+
+// release returns the release ref names. Drafts are never returned, and
+// pre-releases only if prerelease is set.
+//
+// A tag is found by listing the repository's releases, so a version given
+// without the "v" prefix of the tag, or the other way around, still
+// matches.
+func (a *githubAPI) release(ctx context.Context, ref releaseRef, prerelease bool) (*GithubRelease, error) {
+	if ref.tag == "" && !prerelease {
+		// The latest endpoint skips drafts and pre-releases itself.
+		var release GithubRelease
+		if _, err := a.get(ctx, a.base+"/repos/"+ref.repo+"/releases/latest", &release); err != nil {
+			return nil, err
+		}
+		return &release, nil
+	}
+
+	next := fmt.Sprintf("%s/repos/%s/releases?per_page=%d", a.base, ref.repo, releasesPerPage)
+	for page := 0; next != "" && page < maxReleasePages; page++ {
+		var releases []GithubRelease
+		link, err := a.get(ctx, next, &releases)
+		if err != nil {
+			return nil, err
+		}
+		for i := range releases {
+			release := &releases[i]
+			if release.Draft {
+				continue
+			}
+			if ref.tag == "" {
+				// Releases are listed newest first.
+				return release, nil
+			}
+			if !sameTag(release.TagName, ref.tag) {
+				continue
+			}
+			if release.Prerelease && !prerelease {
+				return nil, fmt.Errorf("release %s of %s is a pre-release; enable pre-releases to install it", release.TagName, ref.repo)
+			}
+			return release, nil
+		}
+		next = link
+	}
+
+	if ref.tag == "" {
+		return nil, fmt.Errorf("%s has no published releases", ref.repo)
+	}
+	return nil, fmt.Errorf("%s has no release tagged %s", ref.repo, ref.tag)
+}
+
+func sameTag(tag, want string) bool {
+	return tag == want || strings.TrimPrefix(tag, "v") == strings.TrimPrefix(want, "v")
+}
+
+// get fetches an API URL into v and returns the URL of the next page, if
+// the response is paginated.
+func (a *githubAPI) get(ctx context.Context, apiURL string, v any) (string, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		a.authorize(req)
+
+		resp, err := a.httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %w", apiURL, err)
+		}
+
+		if wait, limited := rateLimitWait(resp); limited {
+			resp.Body.Close()
+			if attempt >= maxRateLimitRetries || wait > a.maxWait {
+				return "", a.rateLimitError(wait)
+			}
+			fmt.Printf("GitHub API rate limit reached, retrying in %s\n", wait.Round(time.Second))
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", apiError(resp, apiURL)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return "", fmt.Errorf("failed to parse response of %s: %w", apiURL, err)
+		}
+		return nextLink(resp.Header.Get("Link")), nil
+	}
+}
+
+// authorize adds the token to requests to the API host. Downloads from
+// other hosts, such as github.com and its CDN, do not need it.
+func (a *githubAPI) authorize(req *http.Request) {
+	if a == nil || a.token == "" {
+		return
+	}
+	if base, err := url.Parse(a.base); err == nil && base.Host == req.URL.Host {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+}
+
+func (a *githubAPI) rateLimitError(wait time.Duration) error {
+	err := fmt.Errorf("GitHub API rate limit exceeded, it resets in %s", wait.Round(time.Second))
+	if a.token == "" {
+		return fmt.Errorf("%w; set GITHUB_TOKEN to raise the limit", err)
+	}
+	return fmt.Errorf("%w; set %s to wait longer", err, GithubRateLimitWaitEnv)
+}
+
+// rateLimitWait reports whether resp is a rate limit response and how long
+// to wait before retrying. Secondary rate limits send Retry-After; the
+// primary limit sends the reset time once no requests remain.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return defaultGithubRateLimitWait, true
+	}
+	// Allow for clock skew between the host and GitHub.
+	return max(time.Until(time.Unix(reset, 0))+time.Second, 0), true
+}
+
+// apiError describes a failed API response with the message GitHub sent.
+func apiError(resp *http.Response, apiURL string) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	err := fmt.Errorf("GitHub API returned %s for %s", resp.Status, apiURL)
+	if body.Message != "" {
+		err = fmt.Errorf("%w: %s", err, body.Message)
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w; check GITHUB_TOKEN", err)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w; private repositories need GITHUB_TOKEN", err)
+	}
+	return err
+}
+
+// nextLink returns the "next" URL of a Link header, as sent with paginated
+// responses.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, found := strings.Cut(link, ";")
+		if !found {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
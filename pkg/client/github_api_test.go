@@ -0,0 +1,300 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestGithubAPI returns a client of the API served by handler.
+func newTestGithubAPI(t *testing.T, token string, handler http.Handler) (*githubAPI, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &githubAPI{
+		httpClient: server.Client(),
+		base:       server.URL,
+		token:      token,
+		maxWait:    time.Minute,
+	}, server
+}
+
+func writeReleases(t *testing.T, w http.ResponseWriter, releases ...GithubRelease) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(releases); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		limited bool
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"ok", http.StatusOK, nil, false, 0, 0},
+		{"forbidden", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "12"}, false, 0, 0},
+		{"retry after on 403", http.StatusForbidden, map[string]string{"Retry-After": "7"}, true, 7 * time.Second, 7 * time.Second},
+		{"retry after on 429", http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}, true, 30 * time.Second, 30 * time.Second},
+		{"reset", http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(90*time.Second).Unix(), 10),
+		}, true, 85 * time.Second, 92 * time.Second},
+		{"reset passed", http.StatusTooManyRequests, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10),
+		}, true, 0, 0},
+		{"reset missing", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, true, defaultGithubRateLimitWait, defaultGithubRateLimitWait},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.header {
+				resp.Header.Set(key, value)
+			}
+			wait, limited := rateLimitWait(resp)
+			if limited != tt.limited {
+				t.Fatalf("limited = %v, want %v", limited, tt.limited)
+			}
+			if wait < tt.min || wait > tt.max {
+				t.Errorf("wait = %s, want between %s and %s", wait, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestGithubAPIRetriesAfterRateLimit(t *testing.T) {
+	for _, header := range []map[string]string{
+		{"Retry-After": "0"},
+		{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)},
+	} {
+		var requests atomic.Int32
+		api, _ := newTestGithubAPI(t, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				for key, value := range header {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			json.NewEncoder(w).Encode(GithubRelease{TagName: "v1.0.0"})
+		}))
+
+		release, err := api.release(context.Background(), releaseRef{repo: "owner/tool"}, false)
+		if err != nil {
+			t.Fatalf("%v: %v", header, err)
+		}
+		if release.TagName != "v1.0.0" || requests.Load() != 2 {
+			t.Errorf("%v: got %s after %d requests, want v1.0.0 after 2", header, release.TagName, requests.Load())
+		}
+	}
+}
+
+func TestGithubAPIRateLimitTooLong(t *testing.T) {
+	for _, token := range []string{"", "secret"} {
+		var requests atomic.Int32
+		api, _ := newTestGithubAPI(t, token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		}))
+
+		_, err := api.release(context.Background(), releaseRef{repo: "owner/tool"}, false)
+		if err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+			t.Fatalf("token %q: err = %v, want a rate limit error", token, err)
+		}
+		hint := "GITHUB_TOKEN"
+		if token != "" {
+			hint = GithubRateLimitWaitEnv
+		}
+		if !strings.Contains(err.Error(), hint) {
+			t.Errorf("token %q: err = %v, want a hint about %s", token, err, hint)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("token %q: %d requests, want 1", token, requests.Load())
+		}
+	}
+}
+
+func TestGithubAPITagOnSecondPage(t *testing.T) {
+	var server *httptest.Server
+	api, server := newTestGithubAPI(t, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/tool/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") != "2" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/tool/releases?per_page=100&page=2>; rel="next", <%[1]s/repos/owner/tool/releases?per_page=100&page=2>; rel="last"`, server.URL))
+			writeReleases(t, w, GithubRelease{TagName: "v3.0.0"}, GithubRelease{TagName: "v2.0.0"})
+			return
+		}
+		writeReleases(t, w, GithubRelease{TagName: "v1.2.0"}, GithubRelease{TagName: "v1.1.0"})
+	}))
+
+	// The version matches the tag without its "v" prefix.
+	release, err := api.release(context.Background(), releaseRef{repo: "owner/tool", tag: "1.2.0"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.TagName != "v1.2.0" {
+		t.Errorf("got %s, want v1.2.0", release.TagName)
+	}
+
+	_, err = api.release(context.Background(), releaseRef{repo: "owner/tool", tag: "v0.9.0"}, false)
+	if err == nil || !strings.Contains(err.Error(), "no release tagged v0.9.0") {
+		t.Errorf("err = %v, want no release tagged v0.9.0", err)
+	}
+}
+
+func TestGithubAPIPrereleases(t *testing.T) {
+	api, _ := newTestGithubAPI(t, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/tool/releases/latest":
+			json.NewEncoder(w).Encode(GithubRelease{TagName: "v1.9.0"})
+		case "/repos/owner/tool/releases":
+			writeReleases(t, w,
+				GithubRelease{TagName: "v2.1.0", Draft: true},
+				GithubRelease{TagName: "v2.0.0-rc1", Prerelease: true},
+				GithubRelease{TagName: "v1.9.0"},
+			)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	tests := []struct {
+		tag        string
+		prerelease bool
+		want       string
+		err        string
+	}{
+		{"", false, "v1.9.0", ""},
+		{"", true, "v2.0.0-rc1", ""},
+		{"v2.0.0-rc1", true, "v2.0.0-rc1", ""},
+		{"v2.0.0-rc1", false, "", "is a pre-release"},
+		{"v2.1.0", true, "", "no release tagged v2.1.0"},
+		{"1.9.0", false, "v1.9.0", ""},
+	}
+	for _, tt := range tests {
+		release, err := api.release(context.Background(), releaseRef{repo: "owner/tool", tag: tt.tag}, tt.prerelease)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("tag %q, prerelease %v: err = %v, want %q", tt.tag, tt.prerelease, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("tag %q, prerelease %v: %v", tt.tag, tt.prerelease, err)
+			continue
+		}
+		if release.TagName != tt.want {
+			t.Errorf("tag %q, prerelease %v: got %s, want %s", tt.tag, tt.prerelease, release.TagName, tt.want)
+		}
+	}
+}
+
+func TestGithubTokenOnlySentToAPIHost(t *testing.T) {
+	t.Setenv(MirrorEnv, "")
+	t.Setenv(DownloadCacheEnv, "off")
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	var assetAuthorization atomic.Value
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assetAuthorization.Store(r.Header.Get("Authorization"))
+		fmt.Fprint(w, "binary")
+	}))
+	defer assets.Close()
+
+	var apiAuthorization atomic.Value
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiAuthorization.Store(r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(GithubRelease{
+			TagName: "v1.0.0",
+			Assets:  []Asset{{Name: "tool_linux_amd64", BrowserDownloadURL: assets.URL + "/tool_linux_amd64"}},
+		})
+	}))
+	defer api.Close()
+	t.Setenv(GithubAPIEnv, api.URL)
+
+	dest := filepath.Join(t.TempDir(), "tool")
+	c, err := NewGithubClient([]string{api.URL + "/repos/owner/tool/releases/latest", "tool_{{os}}_{{arch}}", "latest", dest}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := c.fetchRelease(releaseRef{repo: "owner/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := apiAuthorization.Load(); got != "Bearer secret" {
+		t.Errorf("API request Authorization = %q, want the token", got)
+	}
+
+	if _, err := c.downloads.fetch(context.Background(), release.Assets[0].BrowserDownloadURL, "", "tool", dest); err != nil {
+		t.Fatal(err)
+	}
+	if got := assetAuthorization.Load(); got != "" {
+		t.Errorf("asset download Authorization = %q, want none", got)
+	}
+	assetAuthorization.Store("unset")
+	var b strings.Builder
+	if err := c.downloadFile(context.Background(), assets.URL+"/checksums.txt", &b); err != nil {
+		t.Fatal(err)
+	}
+	if got := assetAuthorization.Load(); got != "" {
+		t.Errorf("checksums download Authorization = %q, want none", got)
+	}
+}
+
+func TestParseReleaseURLWithEnterpriseBase(t *testing.T) {
+	t.Setenv(GithubAPIEnv, "https://github.example.com/api/v3/")
+
+	tests := []struct {
+		url  string
+		ok   bool
+		repo string
+		tag  string
+	}{
+		{"https://github.example.com/api/v3/repos/owner/tool/releases/latest", true, "owner/tool", ""},
+		{"https://github.example.com/api/v3/repos/owner/tool/releases/tags/v1.0.0", true, "owner/tool", "v1.0.0"},
+		{"https://api.github.com/repos/owner/tool/releases/tags/release%2F2", true, "owner/tool", "release/2"},
+		{"https://github.example.com/api/v3/repos/owner/tool/releases", false, "", ""},
+		{"https://github.example.com/api/v3/repos/owner/tool/releases/tags/", false, "", ""},
+		{"https://github.com/owner/tool/releases/download/v1.0.0/tool.tar.gz", false, "", ""},
+	}
+	for _, tt := range tests {
+		ref, ok := parseReleaseURL(tt.url)
+		if ok != tt.ok || ref.repo != tt.repo || ref.tag != tt.tag {
+			t.Errorf("parseReleaseURL(%q) = %+v, %v; want {%s %s}, %v", tt.url, ref, ok, tt.repo, tt.tag, tt.ok)
+		}
+	}
+	if got := GithubAPIURL(); got != "https://github.example.com/api/v3" {
+		t.Errorf("GithubAPIURL() = %q", got)
+	}
+}
+
+func TestNewGithubClientRejectsBadPattern(t *testing.T) {
+	t.Setenv(MirrorEnv, "")
+
+	_, err := NewGithubClient([]string{"https://api.github.com/repos/owner/tool/releases/latest", "tool_(", "latest", filepath.Join(t.TempDir(), "tool")}, time.Minute)
+	if err == nil {
+		t.Fatal("NewGithubClient accepted an invalid asset pattern")
+	}
+
+	_, err = SelectAsset([]Asset{{Name: "tool_linux_amd64"}}, "tool_[", CurrentPlatform())
+	if err == nil {
+		t.Error("SelectAsset accepted an invalid asset pattern")
+	}
+}
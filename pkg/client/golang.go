@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/client/utils"
+)
+
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// GoClient builds a tool from a Go package path with the local Go toolchain
+// and installs the binary into a managed tools directory.
+type GoClient struct {
+	packagePath string
+	version     string
+	toolsDir    string
+	offline     bool
+	goProxy     string
+	// mirrorSigned is set when goProxy is a mirror with a signed manifest.
+	mirrorSigned     bool
+	timeout          time.Duration
+	installedVersion string
+}
+
+// NewGoClient creates a client from the install arguments
+// <package> <version> <tools_dir> [offline=true]. With offline=true the
+// build only uses the local module cache (GOPROXY=off) and toolchain; with an
+// offline mirror configured (see MirrorEnv) it uses the mirror's modules.
+func NewGoClient(install_args []string, timeout time.Duration) (*GoClient, error) {
+	if len(install_args) < 3 {
+		return nil, fmt.Errorf("usage: internal <package> <version> <tools_dir> [offline=true]")
+	}
+
+	version := install_args[1]
+	if version == "" {
+		version = "latest"
+	}
+
+	c := &GoClient{
+		packagePath: install_args[0],
+		version:     version,
+		toolsDir:    install_args[2],
+		timeout:     timeout,
+	}
+
+	for _, option := range install_args[3:] {
+		key, value, found := strings.Cut(option, "=")
+		if !found || key != "offline" {
+			return nil, fmt.Errorf("unknown go install option %q", option)
+		}
+		c.offline = value == "true"
+	}
+
+	mirror, err := MirrorFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if mirror != nil {
+		c.goProxy = mirror.GoProxy()
+		c.mirrorSigned = mirror.Signed
+	}
+	return c, nil
+}
+
+// InstallTool builds the package inside a throwaway module and installs the
+// binary into the tools directory, then records the version of the module
+// providing the package that was built. "go install pkg@version" is not
+// used because it looks up module deprecations, which needs network access
+// even when everything else is in the module cache.
+func (c *GoClient) InstallTool() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("go toolchain not found in PATH: %w", err)
+	}
+
+	if err := os.MkdirAll(c.toolsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", c.toolsDir, err)
+	}
+
+	buildDir, err := os.MkdirTemp("", "asm-go-build-*")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	target := c.packagePath + "@" + c.version
+	binary := filepath.Join(c.toolsDir, GoBinaryName(c.packagePath))
+	staged, cleanup, err := utils.Stage(binary)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	fmt.Printf("Building %s with %s ...\n", target, goCmd)
+
+	steps := [][]string{
+		{"mod", "init", "asm-tool-build"},
+		{"get", target},
+		{"build", "-trimpath", "-o", staged, c.packagePath},
+	}
+	for _, args := range steps {
+		if err := c.runGo(ctx, goCmd, buildDir, args...); err != nil {
+			return fmt.Errorf("failed to build %s: %w", target, err)
+		}
+	}
+
+	version, err := c.moduleVersion(ctx, goCmd, buildDir)
+	if err != nil {
+		return fmt.Errorf("failed to read the version of %s: %w", target, err)
+	}
+	if err := utils.Commit(staged, binary); err != nil {
+		return fmt.Errorf("failed to install %s: %w", target, err)
+	}
+	c.installedVersion = version
+
+	fmt.Printf("%s %s installed successfully at %s\n", c.packagePath, version, binary)
+	return nil
+}
+
+func (c *GoClient) runGo(ctx context.Context, goCmd, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, goCmd, args...)
+	cmd.Dir = dir
+	cmd.Env = c.environ(goCmd)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func (c *GoClient) environ(goCmd string) []string {
+	env := append(os.Environ(), "GOFLAGS="+goFlags(goCmd), "GOWORK=off")
+	if c.offline {
+		// Modules in the cache were checked against the checksum database
+		// when they were downloaded, so it is safe to skip it here.
+		env = append(env, "GOPROXY=off", "GOSUMDB=off", "GOTOOLCHAIN=local")
+	} else if c.goProxy != "" {
+		env = append(env, "GOPROXY="+c.goProxy, "GOTOOLCHAIN=local")
+		if c.mirrorSigned {
+			// The signed manifest lists the digest of every module file,
+			// which stands in for the checksum database. Modules from an
+			// unsigned mirror are still checked against it.
+			env = append(env, "GOSUMDB=off")
+		}
+	}
+	return env
+}
+
+// goFlags returns the user's GOFLAGS, set in the environment or with
+// "go env -w", with -mod=mod in place of any -mod flag: the throwaway
+// module is resolved by the build, so its go.mod must be writable.
+func goFlags(goCmd string) string {
+	flags := os.Getenv("GOFLAGS")
+	if output, err := exec.Command(goCmd, "env", "GOFLAGS").Output(); err == nil {
+		flags = strings.TrimSpace(string(output))
+	}
+	return withModMod(flags)
+}
+
+// withModMod replaces the -mod flag in flags, a GOFLAGS value, with
+// -mod=mod, keeping the other flags.
+func withModMod(flags string) string {
+	var kept []string
+	for _, flag := range strings.Fields(flags) {
+		if flag != "-mod" && !strings.HasPrefix(flag, "-mod=") && flag != "--mod" && !strings.HasPrefix(flag, "--mod=") {
+			kept = append(kept, flag)
+		}
+	}
+	return strings.Join(append(kept, "-mod=mod"), " ")
+}
+
+// InstalledVersion returns the version of the module that was built.
+func (c *GoClient) InstalledVersion() string {
+	return c.installedVersion
+}
+
+// Resolution reports the module version that was built.
+func (c *GoClient) Resolution() Resolution {
+	return Resolution{Version: c.installedVersion, Source: c.packagePath}
+}
+
+// LatestVersion resolves the newest version of the module providing the
+// package, through the module proxy or the offline mirror.
+func (c *GoClient) LatestVersion() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		return "", fmt.Errorf("go toolchain not found in PATH: %w", err)
+	}
+	buildDir, err := os.MkdirTemp("", "asm-go-build-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	for _, args := range [][]string{{"mod", "init", "asm-tool-build"}, {"get", c.packagePath + "@latest"}} {
+		if err := c.runGo(ctx, goCmd, buildDir, args...); err != nil {
+			return "", fmt.Errorf("failed to resolve the latest version of %s: %w", c.packagePath, err)
+		}
+	}
+
+	version, err := c.moduleVersion(ctx, goCmd, buildDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the latest version of %s: %w", c.packagePath, err)
+	}
+	return version, nil
+}
+
+// moduleVersion returns the version of the module providing the package in
+// the throwaway module in buildDir. The build information of the binary
+// cannot tell, since its main module is the throwaway one.
+func (c *GoClient) moduleVersion(ctx context.Context, goCmd, buildDir string) (string, error) {
+	cmd := exec.CommandContext(ctx, goCmd, "list", "-f", "{{.Module.Version}}", c.packagePath)
+	cmd.Dir = buildDir
+	cmd.Env = c.environ(goCmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list %s: %w", c.packagePath, err)
+	}
+	version := strings.TrimSpace(string(output))
+	if version == "" {
+		return "", fmt.Errorf("no module version for %s", c.packagePath)
+	}
+	return version, nil
+}
+
+// UninstallTool removes the built binary.
+func (c *GoClient) UninstallTool() error {
+	binary := filepath.Join(c.toolsDir, GoBinaryName(c.packagePath))
+	if err := os.Remove(binary); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", binary, err)
+	}
+	if err := utils.RemovePrevious(binary); err != nil {
+		return err
+	}
+	fmt.Printf("%s uninstalled\n", binary)
+	return nil
+}
+
+// RollbackTool restores the binary the last build replaced.
+func (c *GoClient) RollbackTool() error {
+	binary := filepath.Join(c.toolsDir, GoBinaryName(c.packagePath))
+	if err := utils.Rollback(binary); err != nil {
+		return err
+	}
+	fmt.Printf("%s rolled back\n", binary)
+	return nil
+}
+
+// GoBinaryName returns the name "go install" gives the binary built from
+// packagePath: its last element, skipping a major version suffix such as
+// "/v2".
+func GoBinaryName(packagePath string) string {
+	name := path.Base(packagePath)
+	if majorVersionSuffix.MatchString(name) {
+		name = path.Base(path.Dir(packagePath))
+	}
+	return name
+}
@@ -0,0 +1,22 @@
+package client
+
+import "testing"
+
+func TestWithModMod(t *testing.T) {
+	tests := []struct {
+		flags string
+		want  string
+	}{
+		{"", "-mod=mod"},
+		{"-trimpath", "-trimpath -mod=mod"},
+		{"-trimpath -tags=netgo", "-trimpath -tags=netgo -mod=mod"},
+		{"-mod=vendor -trimpath", "-trimpath -mod=mod"},
+		{"--mod=readonly", "-mod=mod"},
+		{"  -buildvcs=false   -modcacherw ", "-buildvcs=false -modcacherw -mod=mod"},
+	}
+	for _, tt := range tests {
+		if got := withModMod(tt.flags); got != tt.want {
+			t.Errorf("withModMod(%q) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IxBahy/ASM/pkg/client/utils"
+)
+
+// Environment variables that switch the installers to offline mode.
+const (
+	// MirrorEnv names an offline bundle written by "tools bundle", or a
+	// directory with the same layout, that installers read from instead of
+	// the network.
+	MirrorEnv = "ASM_TOOLS_MIRROR"
+	// MirrorKeyEnv is the minisign public key the bundle's manifest must be
+	// signed with, or the path of a minisign public key file.
+	MirrorKeyEnv = "ASM_TOOLS_MIRROR_KEY"
+)
+
+const (
+	bundleFormatVersion = 1
+	bundleManifestName  = "manifest.json"
+	bundleSignatureName = "manifest.json.minisig"
+)
+
+// BundleManifest describes the contents of an offline bundle. It is signed,
+// and lists the digest of every other file, so verifying the signature
+// verifies the whole bundle.
+type BundleManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Platforms []string     `json:"platforms"`
+	Tools     []BundleTool `json:"tools"`
+	// Files maps the path of every file in the bundle, relative to its
+	// root and slash separated, to its SHA-256 digest in hex.
+	Files map[string]string `json:"files"`
+}
+
+// BundleTool is an artifact collected into a bundle.
+type BundleTool struct {
+	Name string `json:"name"`
+	// Type is the installation type the artifact is for, or "templates".
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	// Path is the file or directory in the bundle holding the artifact.
+	Path string `json:"path"`
+}
+
+// Mirror is a verified, unpacked offline bundle or a local mirror
+// directory. Its layout is:
+//
+//	github/<owner>/<repo>/release.json  release metadata, assets alongside
+//	python/<package>/                    wheels and sdists for pip
+//	go/                                  a GOPROXY of module sources
+//	templates/<name>.tar.gz              scanner templates
+type Mirror struct {
+	Dir      string
+	Manifest *BundleManifest
+	// Signed is set when Manifest was verified against a public key, so
+	// the digests it lists can be trusted in place of other checks.
+	Signed bool
+}
+
+var (
+	mirrorsMu sync.Mutex
+	mirrors   = make(map[string]*Mirror)
+)
+
+// MirrorFromEnv opens the mirror named by MirrorEnv, or returns nil when
+// it is not set. Mirrors are opened once per process.
+func MirrorFromEnv() (*Mirror, error) {
+	path := os.Getenv(MirrorEnv)
+	if path == "" {
+		return nil, nil
+	}
+
+	mirrorsMu.Lock()
+	defer mirrorsMu.Unlock()
+
+	if mirror, ok := mirrors[path]; ok {
+		return mirror, nil
+	}
+	mirror, err := OpenMirror(path, os.Getenv(MirrorKeyEnv))
+	if err != nil {
+		return nil, err
+	}
+	mirrors[path] = mirror
+	return mirror, nil
+}
+
+// OpenMirror opens a bundle or mirror directory. A bundle is unpacked into
+// the tools directory and must be signed with publicKey. A directory is
+// checked against its manifest if it has one, and its signature is checked
+// when publicKey is set; a directory without a manifest is trusted as is.
+func OpenMirror(path, publicKey string) (*Mirror, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mirror: %w", err)
+	}
+
+	if info.IsDir() {
+		manifest, err := verifyMirrorDir(path, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &Mirror{Dir: path, Manifest: manifest, Signed: manifest != nil && publicKey != ""}, nil
+	}
+
+	if publicKey == "" {
+		return nil, fmt.Errorf("bundle %s must be verified: set %s to the public key it was signed with", path, MirrorKeyEnv)
+	}
+
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(ToolsDir(), "mirrors", digest[:16])
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if err := unpackBundle(path, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := verifyMirrorDir(dir, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("bundle %s: %w", path, err)
+	}
+	fmt.Printf("Using offline bundle %s (created %s)\n", path, manifest.CreatedAt.Format(time.RFC3339))
+	return &Mirror{Dir: dir, Manifest: manifest, Signed: true}, nil
+}
+
+// VerifyBundle checks the signature and contents of the bundle at path
+// without installing anything, and returns its manifest.
+func VerifyBundle(path, publicKey string) (*BundleManifest, error) {
+	dir, err := os.MkdirTemp("", "asm-bundle-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "bundle")
+	if err := unpackBundle(path, target); err != nil {
+		return nil, err
+	}
+	return verifyMirrorDir(target, publicKey)
+}
+
+// GithubRelease returns the release of the repository behind apiURL that
+// the mirror holds, with download URLs pointing into the mirror.
+func (m *Mirror) GithubRelease(apiURL string) (*GithubRelease, error) {
+	repo, err := githubRepo(apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(m.Dir, "github", filepath.FromSlash(repo))
+	data, err := os.ReadFile(filepath.Join(dir, "release.json"))
+	if err != nil {
+		return nil, fmt.Errorf("mirror has no release of %s: %w", repo, err)
+	}
+
+	var release GithubRelease
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release of %s in mirror: %w", repo, err)
+	}
+	for i := range release.Assets {
+		path := filepath.Join(dir, filepath.Base(release.Assets[i].BrowserDownloadURL))
+		release.Assets[i].BrowserDownloadURL = fileURL(path)
+	}
+	return &release, nil
+}
+
+// PythonDir returns the directory holding the distributions of package,
+// for pip's --find-links.
+func (m *Mirror) PythonDir(packageName string) string {
+	return filepath.Join(m.Dir, "python", packageName)
+}
+
+// GoProxy returns a GOPROXY value serving the module sources in the mirror.
+func (m *Mirror) GoProxy() string {
+	return fileURL(filepath.Join(m.Dir, "go"))
+}
+
+// ExtractTemplates unpacks the templates archive name into dest, dropping
+// the archive's top-level directory.
+func (m *Mirror) ExtractTemplates(name, dest string) error {
+	path := filepath.Join(m.Dir, "templates", name+".tar.gz")
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("mirror has no %s: %w", name, err)
+	}
+	defer file.Close()
+
+	if err := utils.ExtractTarGzDir(file, dest, 1); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	fmt.Printf("%s extracted to %s\n", name, dest)
+	return nil
+}
+
+func unpackBundle(path, dir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	// Unpack next to the destination and rename, so an interrupted unpack
+	// is never mistaken for a complete one.
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(dir), err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".unpack-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := utils.ExtractTarGzDir(file, tmp, 0); err != nil {
+		return fmt.Errorf("failed to unpack bundle %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to unpack bundle %s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyMirrorDir checks the manifest signature, if publicKey is set, and
+// that the directory holds exactly the files the manifest lists, unchanged.
+func verifyMirrorDir(dir, publicKey string) (*BundleManifest, error) {
+	publicKey, err := loadPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, bundleManifestName))
+	if errors.Is(err, os.ErrNotExist) && publicKey == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if publicKey != "" {
+		signature, err := os.ReadFile(filepath.Join(dir, bundleSignatureName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest signature: %w", err)
+		}
+		if err := verifyMinisign(publicKey, data, signature); err != nil {
+			return nil, fmt.Errorf("manifest signature is invalid: %w", err)
+		}
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version != bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	seen := 0
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == bundleManifestName || rel == bundleSignatureName {
+			return nil
+		}
+
+		expected, ok := manifest.Files[rel]
+		if !ok {
+			return fmt.Errorf("%s is not listed in the manifest", rel)
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		if digest != expected {
+			return fmt.Errorf("SHA-256 mismatch for %s", rel)
+		}
+		seen++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if seen != len(manifest.Files) {
+		return nil, fmt.Errorf("%d files listed in the manifest are missing", len(manifest.Files)-seen)
+	}
+	return &manifest, nil
+}
+
+// loadPublicKey returns the minisign public key in key, or in the file key
+// names. Either may start with an "untrusted comment:" line.
+func loadPublicKey(key string) (string, error) {
+	if key != "" && !strings.HasPrefix(key, "RW") && !strings.Contains(key, "\n") {
+		data, err := os.ReadFile(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read public key: %w", err)
+		}
+		key = string(data)
+	}
+	lines := strings.Split(strings.TrimSpace(key), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// githubRepo returns "owner/repo" from a GitHub API URL such as
+// https://api.github.com/repos/owner/repo/releases/latest.
+func githubRepo(apiURL string) (string, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, part := range parts {
+		if part == "repos" && i+2 < len(parts) {
+			return parts[i+1] + "/" + parts[i+2], nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a GitHub repository API URL", apiURL)
+}
+
+func fileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// PackageManager describes a system package manager and the commands that
+// install and remove packages with it non-interactively.
+type PackageManager struct {
+	Name       string
+	Executable string
+	InstallCmd []string
+	RemoveCmd  []string
+}
+
+// packageManagers lists the supported package managers in detection order.
+// dnf is checked before yum because Fedora and RHEL 8+ ship a yum shim that
+// points to dnf.
+var packageManagers = []PackageManager{
+	{Name: "apt", Executable: "apt-get", InstallCmd: []string{"apt-get", "install", "-y"}, RemoveCmd: []string{"apt-get", "remove", "-y"}},
+	{Name: "dnf", Executable: "dnf", InstallCmd: []string{"dnf", "install", "-y"}, RemoveCmd: []string{"dnf", "remove", "-y"}},
+	{Name: "yum", Executable: "yum", InstallCmd: []string{"yum", "install", "-y"}, RemoveCmd: []string{"yum", "remove", "-y"}},
+	{Name: "apk", Executable: "apk", InstallCmd: []string{"apk", "add", "--no-cache"}, RemoveCmd: []string{"apk", "del"}},
+	{Name: "pacman", Executable: "pacman", InstallCmd: []string{"pacman", "-S", "--noconfirm", "--needed"}, RemoveCmd: []string{"pacman", "-R", "--noconfirm"}},
+	{Name: "zypper", Executable: "zypper", InstallCmd: []string{"zypper", "--non-interactive", "install"}, RemoveCmd: []string{"zypper", "--non-interactive", "remove"}},
+}
+
+// packageNames maps a package, named as on Debian and Ubuntu, to its names
+// under the other package managers. Packages missing from the table, or
+// from a package manager's entry, have the same name everywhere. An empty
+// list means the package is not needed, e.g. because Arch ships headers
+// with the main package.
+var packageNames = map[string]map[string][]string{
+	"libpcap-dev": {
+		"dnf":    {"libpcap-devel"},
+		"yum":    {"libpcap-devel"},
+		"pacman": {"libpcap"},
+		"zypper": {"libpcap-devel"},
+	},
+	"ruby-dev": {
+		"dnf":    {"ruby-devel"},
+		"yum":    {"ruby-devel"},
+		"pacman": {},
+		"zypper": {"ruby-devel"},
+	},
+	"libcurl4-openssl-dev": {
+		"dnf":    {"libcurl-devel"},
+		"yum":    {"libcurl-devel"},
+		"apk":    {"curl-dev"},
+		"pacman": {"curl"},
+		"zypper": {"libcurl-devel"},
+	},
+	"zlib1g-dev": {
+		"dnf":    {"zlib-devel"},
+		"yum":    {"zlib-devel"},
+		"apk":    {"zlib-dev"},
+		"pacman": {"zlib"},
+		"zypper": {"zlib-devel"},
+	},
+	"g++": {
+		"dnf":    {"gcc-c++"},
+		"yum":    {"gcc-c++"},
+		"pacman": {"gcc"},
+		"zypper": {"gcc-c++"},
+	},
+	"make": {
+		"apk": {"make", "musl-dev"},
+	},
+}
+
+// DetectPackageManager returns the first supported package manager found in
+// PATH.
+func DetectPackageManager() (PackageManager, error) {
+	for _, pm := range packageManagers {
+		if _, err := exec.LookPath(pm.Executable); err == nil {
+			return pm, nil
+		}
+	}
+	return PackageManager{}, fmt.Errorf("no supported package manager found (apt, dnf, yum, apk, pacman, zypper)")
+}
+
+// LookupPackageManager returns the package manager with the given name.
+func LookupPackageManager(name string) (PackageManager, error) {
+	for _, pm := range packageManagers {
+		if pm.Name == name {
+			return pm, nil
+		}
+	}
+	return PackageManager{}, fmt.Errorf("unsupported package manager: %s", name)
+}
+
+// PackagesFor translates Debian package names to the package manager's,
+// keeping their order and dropping duplicates.
+func (pm PackageManager) PackagesFor(packages []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, pkg := range packages {
+		mapped := []string{pkg}
+		if byManager, ok := packageNames[pkg]; ok {
+			if alt, ok := byManager[pm.Name]; ok {
+				mapped = alt
+			}
+		}
+		for _, name := range mapped {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
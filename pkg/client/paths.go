@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ToolsDir returns the managed directory tools are installed into:
+// $XDG_DATA_HOME/asm/tools, falling back to ~/.local/share/asm/tools.
+func ToolsDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "asm", "tools")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", "asm", "tools")
+	}
+	return filepath.Join(".asm", "tools")
+}
+
+// BinDir is the directory the executables of managed tools are installed
+// or linked into. It is owned by the user, so installing into it never
+// needs root, and it is searched before PATH by LookPath.
+func BinDir() string {
+	return filepath.Join(ToolsDir(), "bin")
+}
+
+// BinPath returns the path of the managed executable name.
+func BinPath(name string) string {
+	return filepath.Join(BinDir(), name)
+}
+
+// AddBinDirToPath prepends BinDir to the PATH of the current process, and
+// so of every tool it runs, unless it is already there.
+func AddBinDirToPath() {
+	binDir := BinDir()
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == binDir {
+			return
+		}
+	}
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// LookPath finds the executable name in BinDir, then in PATH.
+func LookPath(name string) (string, error) {
+	if !strings.ContainsRune(name, filepath.Separator) {
+		path := BinPath(name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+	return exec.LookPath(name)
+}
+
+// Command returns an exec.Cmd running name, resolved with LookPath. For
+// commands run through sudo, the command sudo runs is resolved instead,
+// since sudo only searches its own secure path.
+func Command(name string, args ...string) *exec.Cmd {
+	return CommandContext(context.Background(), name, args...)
+}
+
+// CommandContext is like Command, but the command is killed when ctx is
+// done.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if name == "sudo" && len(args) > 0 {
+		if path, err := LookPath(args[0]); err == nil {
+			args = append([]string{path}, args[1:]...)
+		}
+	} else if path, err := LookPath(name); err == nil {
+		name = path
+	}
+	return exec.CommandContext(ctx, name, args...)
+}
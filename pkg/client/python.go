@@ -1,21 +1,45 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/IxBahy/ASM/pkg/client/utils"
 )
 
+// pypiURL is the base of PyPI's JSON API.
+const pypiURL = "https://pypi.org/pypi/"
+
+// PythonClient installs a Python package into its own virtualenv under the
+// managed tools directory and links its executable into BinDir, so tools
+// never share, or pollute, a site-packages directory. When pipx is available
+// it creates the virtualenv instead; installed packages are then replaced
+// with pip inside it, like any other.
 type PythonClient struct {
 	packageName string
 	version     string
+	executable  string
 	timeout     time.Duration
 	pipArgs     []string
+	usePipx     bool
+	mirror      *Mirror
 }
 
+// NewPythonClient creates a client from the install arguments
+// <package_name> [version] [extra_args...]. Extra arguments are passed to
+// pip, except "executable=<name>", which names the command the package
+// installs when it differs from the package name.
 func NewPythonClient(install_args []string, timeout time.Duration) (*PythonClient, error) {
 	if len(install_args) < 1 {
 		return nil, fmt.Errorf("usage: python <package_name> [version] [extra_args...]")
@@ -28,78 +52,413 @@ func NewPythonClient(install_args []string, timeout time.Duration) (*PythonClien
 		version = install_args[1]
 	}
 
-	pipArgs := []string{"install"}
-
-	if version != "latest" {
-		pipArgs = append(pipArgs, fmt.Sprintf("%s==%s", packageName, version))
-	} else {
-		pipArgs = append(pipArgs, packageName)
+	c := &PythonClient{
+		packageName: packageName,
+		version:     version,
+		executable:  packageName,
+		timeout:     timeout,
 	}
 
 	if len(install_args) > 2 {
-		pipArgs = append(pipArgs, install_args[2:]...)
+		for _, arg := range install_args[2:] {
+			if name, found := strings.CutPrefix(arg, "executable="); found {
+				c.executable = name
+				continue
+			}
+			c.pipArgs = append(c.pipArgs, arg)
+		}
 	}
 
-	return &PythonClient{
-		packageName: packageName,
-		version:     version,
-		timeout:     timeout * time.Minute,
-		pipArgs:     pipArgs,
-	}, nil
+	if _, err := exec.LookPath("pipx"); err == nil {
+		c.usePipx = true
+	}
+
+	mirror, err := MirrorFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	c.mirror = mirror
+	if mirror != nil {
+		// Install only from the distributions in the mirror.
+		c.pipArgs = append([]string{"--no-index", "--find-links", mirror.PythonDir(packageName)}, c.pipArgs...)
+	}
+
+	return c, nil
 }
 
 func (c *PythonClient) InstallTool() error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
 	defer cancel()
 
-	pythonCmd := ""
-	pipCmd := ""
+	if err := os.MkdirAll(BinDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", BinDir(), err)
+	}
+
+	fmt.Printf("Installing %s %s into an isolated environment...\n", c.packageName, c.version)
+	if err := c.keepPreviousVersion(); err != nil {
+		return err
+	}
+
+	if c.usePipx && !c.installed() {
+		args := append([]string{"install", c.requirement()}, pipxArgs(c.pipArgs)...)
+		if err := c.runPipx(ctx, args...); err != nil {
+			return fmt.Errorf("failed to install %s with pipx: %w", c.packageName, err)
+		}
+		if err := utils.ProbeExecutable(c.ShimPath()); err != nil {
+			c.runPipx(ctx, "uninstall", c.packageName)
+			return err
+		}
+	} else {
+		// An installed package, even one pipx manages, is replaced with
+		// pip inside its virtualenv, so a failed install leaves it in
+		// place; "pipx install --force" would remove the virtualenv first.
+		created := !c.installed()
+		previous := c.InstalledVersion()
+		if err := c.createVenv(ctx); err != nil {
+			return err
+		}
+		args := append([]string{"install", c.requirement()}, c.pipArgs...)
+		err := c.runPip(ctx, args...)
+		if err != nil {
+			err = fmt.Errorf("failed to install %s with pip: %w", c.packageName, err)
+		} else if err = c.linkShim(); err != nil {
+			c.restoreVersion(ctx, previous)
+		}
+		if err != nil {
+			// pip restores a package it failed to upgrade by itself, but
+			// a virtualenv created for a failed install is left behind.
+			if created {
+				os.RemoveAll(c.pipVenvDir())
+			}
+			return err
+		}
+	}
+
+	AddBinDirToPath()
+	fmt.Printf("%s installed successfully at %s\n", c.packageName, c.ShimPath())
+	return nil
+}
+
+// UpgradeTool upgrades the package to the newest release, or reinstalls the
+// pinned version.
+func (c *PythonClient) UpgradeTool() error {
+	if c.version != "latest" {
+		return c.InstallTool()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if !c.installed() {
+		return c.InstallTool()
+	}
+
+	fmt.Printf("Upgrading %s...\n", c.packageName)
+	if err := c.keepPreviousVersion(); err != nil {
+		return err
+	}
+	// Packages pipx manages are upgraded with pip too, so the new
+	// executable is probed, and the installed version restored if it does
+	// not run.
+	previous := c.InstalledVersion()
+	args := append([]string{"install", "--upgrade", c.packageName}, c.pipArgs...)
+	if err := c.runPip(ctx, args...); err != nil {
+		return fmt.Errorf("failed to upgrade %s with pip: %w", c.packageName, err)
+	}
+	if err := c.linkShim(); err != nil {
+		c.restoreVersion(ctx, previous)
+		return err
+	}
+	return nil
+}
+
+// restoreVersion reinstalls version of the package after the executable of
+// the version that replaced it failed to run. pip restores a package it
+// failed to install by itself, but not one that installed and is broken.
+func (c *PythonClient) restoreVersion(ctx context.Context, version string) {
+	if version == "" || version == c.InstalledVersion() {
+		return
+	}
+	fmt.Printf("Restoring %s %s\n", c.packageName, version)
+	args := append([]string{"install", fmt.Sprintf("%s==%s", c.packageName, version)}, c.pipArgs...)
+	if err := c.runPip(ctx, args...); err != nil {
+		fmt.Printf("Failed to restore %s %s: %v\n", c.packageName, version, err)
+	}
+}
 
-	if _, err := exec.LookPath("python3"); err == nil {
-		pythonCmd = "python"
+// UninstallTool removes the package's virtualenv and shim.
+func (c *PythonClient) UninstallTool() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
 
-		if _, err := exec.LookPath("pip"); err == nil {
-			pipCmd = "pip"
+	if c.usePipx {
+		if _, err := os.Stat(c.venvDir()); err == nil {
+			if err := c.runPipx(ctx, "uninstall", c.packageName); err != nil {
+				return fmt.Errorf("failed to uninstall %s with pipx: %w", c.packageName, err)
+			}
 		}
 	}
 
-	if pythonCmd == "" || pipCmd == "" {
-		return fmt.Errorf("python and pip installation not found. Please install them ")
+	if err := os.RemoveAll(c.pipVenvDir()); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", c.pipVenvDir(), err)
+	}
+	if err := os.Remove(c.ShimPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", c.ShimPath(), err)
+	}
+	if err := os.Remove(c.previousVersionPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", c.previousVersionPath(), err)
 	}
 
-	versionCmd := exec.CommandContext(ctx, pythonCmd, "--version")
-	versionOutput, err := versionCmd.CombinedOutput()
+	fmt.Printf("%s uninstalled\n", c.packageName)
+	return nil
+}
+
+// RollbackTool reinstalls the version of the package that the last install
+// or upgrade replaced. Virtualenvs are changed in place, so the version is
+// installed again rather than restored.
+func (c *PythonClient) RollbackTool() error {
+	data, err := os.ReadFile(c.previousVersionPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no previous version of %s to roll back to", c.packageName)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get Python version: %w", err)
+		return fmt.Errorf("failed to read the previous version of %s: %w", c.packageName, err)
 	}
 
-	if versionOutput == nil {
-		return fmt.Errorf("failed to get Python version: %w", err)
-	} else if strings.Contains(string(versionOutput), "Python 2") {
-		return fmt.Errorf("python 2 is not supported. Please install Python 3")
+	previous := *c
+	previous.version = strings.TrimSpace(string(data))
+	fmt.Printf("Rolling %s back to %s\n", c.packageName, previous.version)
+	if err := previous.InstallTool(); err != nil {
+		return err
 	}
+	if err := os.Remove(c.previousVersionPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", c.previousVersionPath(), err)
+	}
+	return nil
+}
+
+// previousVersionPath is where the version replaced by the last install or
+// upgrade is kept for RollbackTool.
+func (c *PythonClient) previousVersionPath() string {
+	return filepath.Join(ToolsDir(), "venvs", c.packageName+".previous")
+}
+
+// keepPreviousVersion records the installed version of the package, if
+// any, before it is replaced.
+func (c *PythonClient) keepPreviousVersion() error {
+	version := c.InstalledVersion()
+	if version == "" || version == c.version {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.previousVersionPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(c.previousVersionPath()), err)
+	}
+	if err := os.WriteFile(c.previousVersionPath(), []byte(version+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record the previous version of %s: %w", c.packageName, err)
+	}
+	return nil
+}
 
-	fmt.Printf("Using %s: %s\n", pythonCmd, strings.TrimSpace(string(versionOutput)))
+// InstalledVersion returns the version of the package in its virtualenv, as
+// reported by pip.
+func (c *PythonClient) InstalledVersion() string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
 
-	fmt.Printf("Installing %s %s using pip...\n", c.packageName, c.version)
+	output, err := exec.CommandContext(ctx, c.venvPython(), "-m", "pip", "show", c.packageName).Output()
+	if err != nil {
+		return ""
+	}
 
-	cmdArgs := append([]string{pipCmd}, c.pipArgs...)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if version, found := strings.CutPrefix(scanner.Text(), "Version:"); found {
+			return strings.TrimSpace(version)
+		}
+	}
+	return ""
+}
 
+// Resolution reports the installed version of the package.
+func (c *PythonClient) Resolution() Resolution {
+	return Resolution{Version: c.InstalledVersion(), Source: c.packageName}
+}
+
+// LatestVersion returns the newest version of the package on PyPI, or in
+// the mirror in offline mode.
+func (c *PythonClient) LatestVersion() (string, error) {
+	if c.mirror != nil {
+		version := downloadedPythonVersion(c.mirror.PythonDir(c.packageName), c.packageName, "")
+		if version == "" {
+			return "", fmt.Errorf("mirror has no distribution of %s", c.packageName)
+		}
+		return version, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pypiURL+c.packageName+"/json", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to query PyPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to query PyPI: unexpected status code: %d", resp.StatusCode)
+	}
+	var project struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return "", fmt.Errorf("failed to parse PyPI response: %w", err)
+	}
+	return project.Info.Version, nil
+}
+
+// ShimPath returns the path of the tool's executable on PATH.
+func (c *PythonClient) ShimPath() string {
+	return filepath.Join(BinDir(), c.executable)
+}
+
+func (c *PythonClient) requirement() string {
+	if c.version == "latest" {
+		return c.packageName
+	}
+	return fmt.Sprintf("%s==%s", c.packageName, c.version)
+}
+
+// pipxHome is where pipx keeps its virtualenvs for tools managed by ASM.
+func (c *PythonClient) pipxHome() string {
+	return filepath.Join(ToolsDir(), "pipx")
+}
+
+func (c *PythonClient) pipVenvDir() string {
+	return filepath.Join(ToolsDir(), "venvs", c.packageName)
+}
+
+// venvDir returns the package's virtualenv, whichever way it was installed.
+func (c *PythonClient) venvDir() string {
+	if c.usePipx {
+		return filepath.Join(c.pipxHome(), "venvs", c.packageName)
+	}
+	return c.pipVenvDir()
+}
+
+func (c *PythonClient) venvPython() string {
+	return filepath.Join(c.venvDir(), "bin", "python")
+}
+
+func (c *PythonClient) installed() bool {
+	_, err := os.Stat(c.venvPython())
+	return err == nil
+}
+
+func (c *PythonClient) createVenv(ctx context.Context) error {
+	if c.installed() {
+		return nil
+	}
+
+	python, version, err := findPython3(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using %s: %s\n", python, version)
+
+	cmd := exec.CommandContext(ctx, python, "-m", "venv", c.pipVenvDir())
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create virtualenv %s: %w", c.pipVenvDir(), err)
+	}
+	return nil
+}
+
+func (c *PythonClient) runPip(ctx context.Context, args ...string) error {
+	cmdArgs := append([]string{c.venvPython(), "-m", "pip"}, args...)
 	fmt.Printf("Running: %s\n", strings.Join(cmdArgs, " "))
 
 	cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to install %s with pip: %w", c.packageName, err)
-	}
+// pipxMu serializes pipx runs, which share the interpreter and libraries
+// pipx keeps in PIPX_HOME and create them on first use.
+var pipxMu sync.Mutex
 
-	_, err = exec.LookPath(c.packageName)
-	if err == nil {
-		fmt.Printf("Found %s executable in PATH\n", c.packageName)
-		return nil
+func (c *PythonClient) runPipx(ctx context.Context, args ...string) error {
+	pipxMu.Lock()
+	defer pipxMu.Unlock()
+
+	fmt.Printf("Running: pipx %s\n", strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, "pipx", args...)
+	cmd.Env = append(os.Environ(), "PIPX_HOME="+c.pipxHome(), "PIPX_BIN_DIR="+BinDir())
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// linkShim links the executable in the package's virtualenv into BinDir,
+// once it runs. Scripts in a virtualenv run its own interpreter, so a symlink is
+// all that is needed. The link is renamed into place, so an existing shim
+// is replaced atomically.
+func (c *PythonClient) linkShim() error {
+	target := filepath.Join(c.venvDir(), "bin", c.executable)
+	if _, err := os.Stat(target); err != nil {
+		return fmt.Errorf("%s did not install an executable named %s: %w", c.packageName, c.executable, err)
+	}
+	if err := utils.ProbeExecutable(target); err != nil {
+		return err
 	}
 
+	shim := c.ShimPath()
+	staged, cleanup, err := utils.Stage(shim)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := os.Symlink(target, staged); err != nil {
+		return fmt.Errorf("failed to link %s: %w", shim, err)
+	}
+	if err := os.Rename(staged, shim); err != nil {
+		return fmt.Errorf("failed to link %s: %w", shim, err)
+	}
 	return nil
 }
+
+// findPython3 returns the first Python 3 interpreter on PATH, preferring
+// python3 over python.
+func findPython3(ctx context.Context) (string, string, error) {
+	for _, name := range []string{"python3", "python"} {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+		if err != nil {
+			continue
+		}
+		version := strings.TrimSpace(string(output))
+		if strings.HasPrefix(version, "Python 3") {
+			return path, version, nil
+		}
+	}
+	return "", "", fmt.Errorf("python 3 installation not found. Please install it")
+}
+
+// pipxArgs passes pip arguments through pipx.
+func pipxArgs(pipArgs []string) []string {
+	if len(pipArgs) == 0 {
+		return nil
+	}
+	return []string{"--pip-args", strings.Join(pipArgs, " ")}
+}
@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aead.dev/minisign"
+	"github.com/IxBahy/ASM/pkg/client/utils"
+	"github.com/minio/selfupdate"
+)
+
+// Environment variables configuring "self-update".
+const (
+	// UpdateSourceEnv is the release source ASM updates itself from: a
+	// directory or an HTTP(S) URL holding release.json and the builds it
+	// lists.
+	UpdateSourceEnv = "ASM_UPDATE_SOURCE"
+	// UpdateKeyEnv is the minisign public key releases must be signed
+	// with, or the path of a minisign public key file.
+	UpdateKeyEnv = "ASM_UPDATE_KEY"
+)
+
+const (
+	releaseManifestName  = "release.json"
+	releaseSignatureName = "release.json.minisig"
+	// releaseManifestLimit bounds the size of release.json and its
+	// signature.
+	releaseManifestLimit = 1 << 20
+)
+
+// Release describes a build of ASM for every platform it was released for.
+// It is signed, and lists the digest of every build, so verifying the
+// signature verifies the builds.
+type Release struct {
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Builds maps a platform, as "os/arch", to its build.
+	Builds map[string]ReleaseBuild `json:"builds"`
+}
+
+// ReleaseBuild is the executable of a release for one platform.
+type ReleaseBuild struct {
+	// File is the path of the executable relative to the release source,
+	// slash separated.
+	File string `json:"file"`
+	// SHA256 is the digest of the executable in hex.
+	SHA256 string `json:"sha256"`
+}
+
+// UpdateSource is a directory or HTTP mirror that ASM releases are
+// published to. Its layout is:
+//
+//	release.json         the Release, signed
+//	release.json.minisig its minisign signature
+//	<file>               the executable of each build
+type UpdateSource struct {
+	location  string
+	publicKey string
+	local     bool
+	dl        *downloader
+}
+
+// NewUpdateSource returns the release source at location, a directory or
+// an HTTP(S) URL. Releases must be signed with publicKey, a minisign
+// public key or the path of a public key file.
+func NewUpdateSource(location, publicKey string) (*UpdateSource, error) {
+	if location == "" {
+		return nil, fmt.Errorf("no release source: pass -source or set %s", UpdateSourceEnv)
+	}
+	if publicKey == "" {
+		return nil, fmt.Errorf("releases must be verified: pass -key or set %s to the public key they are signed with", UpdateKeyEnv)
+	}
+	key, err := loadPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &UpdateSource{
+		location:  strings.TrimSuffix(location, "/"),
+		publicKey: key,
+		dl:        &downloader{httpClient: &http.Client{Timeout: 30 * time.Minute}},
+	}
+	if u, err := url.Parse(location); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open release source: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("release source %s is not a directory or HTTP URL", location)
+		}
+		source.local = true
+	}
+	return source, nil
+}
+
+// Latest reads the release published to the source and verifies its
+// signature.
+func (s *UpdateSource) Latest(ctx context.Context) (*Release, error) {
+	data, err := s.read(ctx, releaseManifestName)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.read(ctx, releaseSignatureName)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyMinisign(s.publicKey, data, signature); err != nil {
+		return nil, fmt.Errorf("failed to verify %s: %w", releaseManifestName, err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", releaseManifestName, err)
+	}
+	if release.Version == "" {
+		return nil, fmt.Errorf("%s has no version", releaseManifestName)
+	}
+	return &release, nil
+}
+
+// Apply replaces the executable at target with the build of release for
+// the current platform. The build is checked against the digest in the
+// signed release before it replaces target, and the replacement is a
+// rename, so target is never left half written. The replaced executable
+// is kept for RollbackExecutable. If the new executable does not report
+// the release's version when run with "version", the previous one is put
+// back and an error returned.
+func (s *UpdateSource) Apply(ctx context.Context, release *Release, target string) error {
+	platform := CurrentPlatform().String()
+	build, ok := release.Builds[platform]
+	if !ok {
+		return fmt.Errorf("release %s has no build for %s", release.Version, platform)
+	}
+	checksum, err := hex.DecodeString(build.SHA256)
+	if err != nil || len(checksum) != 32 {
+		return fmt.Errorf("release %s has an invalid SHA-256 for %s", release.Version, platform)
+	}
+
+	options := selfupdate.Options{
+		TargetPath:  target,
+		Checksum:    checksum,
+		OldSavePath: PreviousExecutablePath(target),
+	}
+	if err := options.CheckPermissions(); err != nil {
+		return fmt.Errorf("cannot replace %s, run the update as its owner: %w", target, err)
+	}
+
+	staged, cleanup, err := utils.Stage(target)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if _, err := s.dl.fetch(ctx, s.url(build.File), build.SHA256, filepath.Base(build.File), staged); err != nil {
+		return fmt.Errorf("failed to download %s: %w", build.File, err)
+	}
+	file, err := os.Open(staged)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", staged, err)
+	}
+	defer file.Close()
+
+	if err := selfupdate.Apply(file, options); err != nil {
+		if rollbackErr := selfupdate.RollbackError(err); rollbackErr != nil {
+			return fmt.Errorf("failed to update %s and to restore it, recover it from %s: %w", target, options.OldSavePath, rollbackErr)
+		}
+		return fmt.Errorf("failed to update %s: %w", target, err)
+	}
+
+	if err := checkExecutableVersion(ctx, target, release.Version); err != nil {
+		if rollbackErr := RollbackExecutable(target); rollbackErr != nil {
+			return fmt.Errorf("%w; failed to restore the previous version: %v", err, rollbackErr)
+		}
+		return fmt.Errorf("%w; the previous version was restored", err)
+	}
+	return nil
+}
+
+// PreviousExecutablePath returns where Apply keeps the executable it
+// replaced at target.
+func PreviousExecutablePath(target string) string {
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".previous")
+}
+
+// RollbackExecutable puts the executable replaced by the last update of
+// target back in its place.
+func RollbackExecutable(target string) error {
+	previous := PreviousExecutablePath(target)
+	if _, err := os.Stat(previous); err != nil {
+		return fmt.Errorf("no previous version of %s to roll back to", target)
+	}
+	if err := os.Rename(previous, target); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", target, err)
+	}
+	return nil
+}
+
+// read returns the file name from the source.
+func (s *UpdateSource) read(ctx context.Context, name string) ([]byte, error) {
+	if s.local {
+		file, err := os.Open(filepath.Join(s.location, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer file.Close()
+		return io.ReadAll(io.LimitReader(file, releaseManifestLimit))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.dl.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", req.URL, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, releaseManifestLimit))
+}
+
+// url returns the URL of the file name in the source.
+func (s *UpdateSource) url(name string) string {
+	if s.local {
+		return fileURL(filepath.Join(s.location, filepath.FromSlash(name)))
+	}
+	return s.location + "/" + strings.TrimPrefix(name, "/")
+}
+
+// checkExecutableVersion runs path with "version" and checks that it
+// reports version.
+func checkExecutableVersion(ctx context.Context, path, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		return fmt.Errorf("the updated %s does not run: %w", filepath.Base(path), err)
+	}
+	reported := strings.TrimSpace(string(output))
+	if strings.TrimPrefix(reported, "v") != strings.TrimPrefix(version, "v") {
+		return fmt.Errorf("the updated %s reports version %q instead of %s", filepath.Base(path), reported, version)
+	}
+	return nil
+}
+
+// PublishRelease copies the builds, keyed by platform, into dir with a
+// release.json for version signed with key, so dir can serve as an
+// UpdateSource directly or be copied to an HTTP mirror.
+func PublishRelease(dir, version string, builds map[Platform]string, key minisign.PrivateKey) (*Release, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	release := Release{
+		Version:   version,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Builds:    make(map[string]ReleaseBuild, len(builds)),
+	}
+	for platform, path := range builds {
+		name := fmt.Sprintf("attack-surface-monitor_%s_%s_%s", version, platform.OS, platform.Arch)
+		if platform.OS == "windows" {
+			name += ".exe"
+		}
+		if err := utils.CopyFile(path, filepath.Join(dir, name)); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", path, err)
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		release.Builds[platform.String()] = ReleaseBuild{File: name, SHA256: digest}
+	}
+
+	data, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode release: %w", err)
+	}
+	signature := minisign.SignWithComments(key, data, "asm release "+version, "")
+	if err := os.WriteFile(filepath.Join(dir, releaseSignatureName), signature, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", releaseSignatureName, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, releaseManifestName), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", releaseManifestName, err)
+	}
+	return &release, nil
+}
@@ -2,71 +2,261 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/IxBahy/ASM/pkg/client/utils"
 )
 
+// DryRunEnv makes every ShellClient print its commands instead of running
+// them when set to "1" or "true".
+const DryRunEnv = "ASM_INSTALL_DRY_RUN"
+
+// ErrDryRun is returned by the installs and uninstalls of a dry run, which
+// print their commands instead of running them and change nothing.
+var ErrDryRun = errors.New("dry run: nothing was changed")
+
+var dryRun atomic.Bool
+
+// EnableDryRun makes every ShellClient created after it print its commands
+// instead of running them, as DryRunEnv does.
+func EnableDryRun() {
+	dryRun.Store(true)
+}
+
+// DryRun reports whether installs are dry runs.
+func DryRun() bool {
+	if dryRun.Load() {
+		return true
+	}
+	value := os.Getenv(DryRunEnv)
+	return value == "1" || value == "true"
+}
+
+// packageManagerMu serializes package manager runs. apt, dnf and the others
+// lock their database and fail instead of waiting when another instance
+// holds it, so tools installed concurrently take turns here.
+var packageManagerMu sync.Mutex
+
 type ShellClient struct {
 	timeout  time.Duration
-	cmdArgs  []string
 	toolName string
+	packages []string
+	// postCommands run after the packages are installed, e.g.
+	// "gem install wpscan".
+	postCommands [][]string
+	manager      string
+	dryRun       bool
 }
 
+// NewShellClient creates a client from the install arguments
+// <tool_name> [packages...] [&& command...]. The tool name and packages are
+// Debian package names, translated for the detected package manager; any
+// "&&"-separated commands that follow run once they are installed. The
+// options "manager=<name>" and "dry-run=true" override package manager
+// detection and print the commands instead of running them.
 func NewShellClient(install_args []string, timeout time.Duration) (*ShellClient, error) {
 	if len(install_args) < 1 {
 
-		return nil, fmt.Errorf("usage: shell <tool_name> [<args>]")
+		return nil, fmt.Errorf("usage: shell <tool_name> [packages...] [&& command...]")
 	}
-	return &ShellClient{
+
+	c := &ShellClient{
 		toolName: install_args[0],
-		cmdArgs:  append([]string{"sudo", "apt", "install"}, install_args...),
-		timeout:  timeout * time.Minute,
-	}, nil
+		timeout:  timeout,
+		dryRun:   DryRun(),
+	}
+
+	var current []string
+	inPackages := true
+	for _, arg := range install_args {
+		switch {
+		case arg == "&&":
+			if !inPackages && len(current) > 0 {
+				c.postCommands = append(c.postCommands, current)
+			}
+			inPackages = false
+			current = nil
+		case inPackages && arg == "-y":
+			// Package managers always run non-interactively.
+		case strings.HasPrefix(arg, "manager="):
+			c.manager = strings.TrimPrefix(arg, "manager=")
+		case strings.HasPrefix(arg, "dry-run="):
+			c.dryRun = strings.TrimPrefix(arg, "dry-run=") == "true"
+		case inPackages:
+			c.packages = append(c.packages, arg)
+		default:
+			current = append(current, arg)
+		}
+	}
+	if !inPackages && len(current) > 0 {
+		c.postCommands = append(c.postCommands, current)
+	}
+
+	return c, nil
+}
+
+// Commands returns the exact commands InstallTool runs, in order, with
+// elevation applied.
+func (c *ShellClient) Commands() ([][]string, error) {
+	pm, err := c.packageManager()
+	if err != nil {
+		return nil, err
+	}
+
+	var commands [][]string
+	elevate := func(command []string) error {
+		elevated, err := Elevate(command)
+		if err != nil && !c.dryRun {
+			return err
+		}
+		if err != nil {
+			// Show what would run once sudo is allowed.
+			elevated = append([]string{"sudo"}, command...)
+		}
+		commands = append(commands, elevated)
+		return nil
+	}
+
+	if packages := pm.PackagesFor(c.packages); len(packages) > 0 {
+		install := append(append([]string{}, pm.InstallCmd...), packages...)
+		if err := elevate(install); err != nil {
+			return nil, err
+		}
+	}
+	for _, command := range c.postCommands {
+		// Commands are written with sudo where they need root; elevation
+		// is decided like for the package manager. Others run as the user.
+		if command[0] != "sudo" {
+			commands = append(commands, command)
+			continue
+		}
+		if len(command) > 1 {
+			if err := elevate(command[1:]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return commands, nil
 }
 
-// InstallTool executes the commands specified in the ShellClient's cmdArgs to install
-// the tool identified by toolName. It splits the commands on "&&" and executes them
-// sequentially, displaying the output to stdout and stderr. The execution has a timeout
-// defined by the client's timeout field. It returns an error if any command fails to execute
-// or if the timeout is reached, otherwise it returns nil on successful installation.
+// InstallTool installs the tool's packages with the detected package
+// manager, then runs any follow-up commands, displaying their output. In
+// dry-run mode the commands are printed instead, and ErrDryRun is
+// returned. The execution has a timeout defined by the client's timeout
+// field.
 func (c *ShellClient) InstallTool() error {
+	commands, err := c.Commands()
+	if err != nil {
+		return fmt.Errorf("failed to prepare installation of %s: %w", c.toolName, err)
+	}
+
+	if c.dryRun {
+		fmt.Printf("Dry run: %s would be installed with:\n", c.toolName)
+		for _, cmdArgs := range commands {
+			fmt.Printf("  %s\n", strings.Join(cmdArgs, " "))
+		}
+		return ErrDryRun
+	}
+
+	packageManagerMu.Lock()
+	defer packageManagerMu.Unlock()
+
 	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
 	defer cancel()
 
-	fmt.Printf("Installing %s with command %s ...\n", c.toolName, c.cmdArgs)
+	for i, cmdArgs := range commands {
+		fmt.Printf("Installing %s with command %s ...\n", c.toolName, strings.Join(cmdArgs, " "))
 
-	var cmdCommands [][]string
-	currentCommand := []string{}
+		cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
 
-	for _, arg := range c.cmdArgs {
-		if arg == "&&" {
-			if len(currentCommand) > 0 {
-				cmdCommands = append(cmdCommands, currentCommand)
-				currentCommand = []string{}
+		if err := cmd.Run(); err != nil {
+			if i > 0 {
+				return fmt.Errorf("packages for %s were installed, but command '%v' failed: %w", c.toolName, cmdArgs, err)
 			}
-		} else {
-			currentCommand = append(currentCommand, arg)
+			return fmt.Errorf("failed to execute command '%v': %w", cmdArgs, err)
 		}
 	}
 
-	if len(currentCommand) > 0 {
-		cmdCommands = append(cmdCommands, currentCommand)
+	fmt.Printf("%s installed successfully\n", c.toolName)
+	return nil
+}
+
+// UninstallTool removes the tool's own package with the package manager,
+// leaving the dependencies that were installed with it. In dry-run mode
+// the command is printed instead, and ErrDryRun is returned.
+func (c *ShellClient) UninstallTool() error {
+	pm, err := c.packageManager()
+	if err != nil {
+		return err
+	}
+	command := append(append([]string{}, pm.RemoveCmd...), pm.PackagesFor([]string{c.toolName})...)
+	elevated, err := Elevate(command)
+	if c.dryRun {
+		if err != nil {
+			elevated = append([]string{"sudo"}, command...)
+		}
+		fmt.Printf("Dry run: %s would be uninstalled with:\n  %s\n", c.toolName, strings.Join(elevated, " "))
+		return ErrDryRun
+	}
+	if err != nil {
+		return err
 	}
+	command = elevated
 
-	for _, cmdArgs := range cmdCommands {
-		if len(cmdArgs) > 0 {
-			cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
+	packageManagerMu.Lock()
+	defer packageManagerMu.Unlock()
 
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to execute command '%v': %w", cmdArgs, err)
-			}
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to execute command '%v': %w", command, err)
 	}
 
-	fmt.Printf("%s installed successfully\n", c.toolName)
+	fmt.Printf("%s uninstalled\n", c.toolName)
 	return nil
 }
+
+// Resolution reports the package manager and packages the tool was
+// installed with. The package version is chosen by the distribution.
+func (c *ShellClient) Resolution() Resolution {
+	pm, err := c.packageManager()
+	if err != nil {
+		return Resolution{}
+	}
+	return Resolution{Source: pm.Name + ":" + strings.Join(pm.PackagesFor([]string{c.toolName}), ",")}
+}
+
+func (c *ShellClient) packageManager() (PackageManager, error) {
+	if c.manager != "" {
+		return LookupPackageManager(c.manager)
+	}
+	return DetectPackageManager()
+}
+
+// Elevate prefixes command with sudo unless the process already runs as
+// root. Using sudo needs the explicit opt-in of utils.SudoAllowed.
+func Elevate(command []string) ([]string, error) {
+	if os.Geteuid() == 0 {
+		return command, nil
+	}
+	if !utils.SudoAllowed() {
+		return nil, fmt.Errorf("%s needs root privileges; run ASM as root, or allow sudo with --sudo or %s=1", command[0], utils.SudoEnv)
+	}
+	if _, err := exec.LookPath("sudo"); err != nil {
+		return nil, fmt.Errorf("%s needs root privileges, but the process is not root and sudo is not available", command[0])
+	}
+	return append([]string{"sudo"}, command...), nil
+}
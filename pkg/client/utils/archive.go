@@ -0,0 +1,521 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	// DefaultMaxExecutableSize bounds the size of an extracted executable,
+	// so a decompression bomb cannot fill the disk.
+	DefaultMaxExecutableSize = 1 << 30
+	// maxArchiveEntries bounds the number of entries read from an archive.
+	maxArchiveEntries = 100000
+	// maxDirSize bounds the total size unpacked by ExtractTarGzDir.
+	maxDirSize = 16 << 30
+	// maxListedMembers bounds the members listed in error messages.
+	maxListedMembers = 20
+)
+
+// compressedFormat is a compressed tarball or a bare compressed file.
+type compressedFormat struct {
+	suffixes   []string
+	tar        bool
+	decompress func(io.Reader) (io.ReadCloser, error)
+}
+
+// compressedFormats is checked in order, so tarballs are recognized before
+// bare compressed files with the same final suffix.
+var compressedFormats = []compressedFormat{
+	{suffixes: []string{".tar.gz", ".tgz"}, tar: true, decompress: gunzip},
+	{suffixes: []string{".tar.xz", ".txz"}, tar: true, decompress: unxz},
+	{suffixes: []string{".tar.bz2", ".tbz2", ".tbz"}, tar: true, decompress: bunzip2},
+	{suffixes: []string{".tar.zst", ".tzst"}, tar: true, decompress: unzstd},
+	{suffixes: []string{".tar"}, tar: true, decompress: uncompressed},
+	{suffixes: []string{".gz"}, decompress: gunzip},
+	{suffixes: []string{".xz"}, decompress: unxz},
+	{suffixes: []string{".bz2"}, decompress: bunzip2},
+	{suffixes: []string{".zst"}, decompress: unzstd},
+}
+
+func gunzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func unxz(r io.Reader) (io.ReadCloser, error) {
+	xzr, err := xz.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(xzr), nil
+}
+
+func bunzip2(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(bzip2.NewReader(r)), nil
+}
+
+func unzstd(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+func uncompressed(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+func formatOf(name string) (compressedFormat, bool) {
+	lower := strings.ToLower(name)
+	for _, format := range compressedFormats {
+		for _, suffix := range format.suffixes {
+			if strings.HasSuffix(lower, suffix) {
+				return format, true
+			}
+		}
+	}
+	return compressedFormat{}, false
+}
+
+// IsArchive reports whether name is a zip file, a tarball, or a compressed
+// file that ExtractExecutable can unpack.
+func IsArchive(name string) bool {
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		return true
+	}
+	_, ok := formatOf(name)
+	return ok
+}
+
+// ExtractOptions select the executable to extract from an archive.
+type ExtractOptions struct {
+	// ToolName selects the member named after the tool, optionally with an
+	// ".exe" suffix, when Member is empty. If there is none, a member whose
+	// name contains ToolName and that is marked executable is used.
+	ToolName string
+	// Member is the path of the executable in the archive, or a glob as
+	// understood by path.Match, such as "*/bin/nuclei".
+	Member string
+	// MaxSize bounds the size of the executable; 0 means
+	// DefaultMaxExecutableSize.
+	MaxSize int64
+}
+
+func (o ExtractOptions) maxSize() int64 {
+	if o.MaxSize > 0 {
+		return o.MaxSize
+	}
+	return DefaultMaxExecutableSize
+}
+
+// member is a regular file in an archive.
+type member struct {
+	// header is the name as stored in the archive.
+	header string
+	// name is the cleaned, slash-separated path.
+	name string
+	size int64
+	// executable is set if the archive records an executable mode.
+	executable bool
+	// hasMode is set if the archive records Unix permissions at all.
+	hasMode bool
+}
+
+// ExtractExecutable extracts the executable selected by options from the
+// archive at archivePath, whose format is taken from archiveName, to
+// targetPath. Bare compressed files are decompressed as a whole.
+//
+// Archives with members that would land outside the extraction directory
+// are rejected, and the executable must be smaller than options.MaxSize
+// and be a native executable or a script (see CheckExecutable).
+func ExtractExecutable(archivePath, archiveName, targetPath string, options ExtractOptions) error {
+	if strings.HasSuffix(strings.ToLower(archiveName), ".zip") {
+		return extractFromZip(archivePath, targetPath, options)
+	}
+	format, ok := formatOf(archiveName)
+	if !ok {
+		return fmt.Errorf("unsupported archive format for %s", archiveName)
+	}
+	if format.tar {
+		return extractFromTar(archivePath, targetPath, format, options)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	r, err := format.decompress(file)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", archiveName, err)
+	}
+	defer r.Close()
+	return writeExecutable(r, targetPath, options.maxSize())
+}
+
+func extractFromZip(archivePath, targetPath string, options ExtractOptions) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip file: %w", err)
+	}
+	defer reader.Close()
+
+	if len(reader.File) > maxArchiveEntries {
+		return fmt.Errorf("zip archive has more than %d entries", maxArchiveEntries)
+	}
+
+	var members []member
+	files := make(map[string]*zip.File)
+	for _, file := range reader.File {
+		name, err := memberPath(file.Name)
+		if err != nil {
+			return err
+		}
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		// Zip files only record Unix permissions if made on Unix or macOS.
+		creator := file.CreatorVersion >> 8
+		members = append(members, member{
+			header:     file.Name,
+			name:       name,
+			size:       int64(file.UncompressedSize64),
+			executable: file.Mode()&0111 != 0,
+			hasMode:    creator == 3 || creator == 19,
+		})
+		files[file.Name] = file
+	}
+
+	selected, err := selectMember(members, options)
+	if err != nil {
+		return err
+	}
+	if selected.size > options.maxSize() {
+		return fmt.Errorf("%s is %d bytes, more than the limit of %d", selected.name, selected.size, options.maxSize())
+	}
+
+	fileReader, err := files[selected.header].Open()
+	if err != nil {
+		return fmt.Errorf("failed to open file in archive: %w", err)
+	}
+	defer fileReader.Close()
+	return writeExecutable(fileReader, targetPath, options.maxSize())
+}
+
+// extractFromTar reads the tarball twice: once to list its members and once
+// to extract the selected one, since compressed streams cannot seek.
+func extractFromTar(archivePath, targetPath string, format compressedFormat, options ExtractOptions) error {
+	var members []member
+	err := walkTar(archivePath, format, func(header *tar.Header, _ io.Reader) (bool, error) {
+		name, err := memberPath(header.Name)
+		if err != nil {
+			return false, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			members = append(members, member{
+				header:     header.Name,
+				name:       name,
+				size:       header.Size,
+				executable: header.Mode&0111 != 0,
+				hasMode:    true,
+			})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectMember(members, options)
+	if err != nil {
+		return err
+	}
+	if selected.size > options.maxSize() {
+		return fmt.Errorf("%s is %d bytes, more than the limit of %d", selected.name, selected.size, options.maxSize())
+	}
+
+	found := false
+	err = walkTar(archivePath, format, func(header *tar.Header, r io.Reader) (bool, error) {
+		if header.Typeflag != tar.TypeReg || header.Name != selected.header {
+			return true, nil
+		}
+		found = true
+		return false, writeExecutable(r, targetPath, options.maxSize())
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s disappeared from the archive", selected.name)
+	}
+	return nil
+}
+
+// walkTar calls fn for each entry of the tarball at archivePath until fn
+// returns false or an error.
+func walkTar(archivePath string, format compressedFormat, fn func(*tar.Header, io.Reader) (bool, error)) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	r, err := format.decompress(file)
+	if err != nil {
+		return fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for entries := 0; ; entries++ {
+		if entries == maxArchiveEntries {
+			return fmt.Errorf("tar archive has more than %d entries", maxArchiveEntries)
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+		more, err := fn(header, tr)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// memberPath cleans the name of an archive member, rejecting names that
+// would escape the directory the archive is extracted to.
+func memberPath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	cleaned := path.Clean(slashed)
+	if path.IsAbs(slashed) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("archive member %q escapes the archive", name)
+	}
+	return cleaned, nil
+}
+
+// selectMember picks the member options select, failing if there is none
+// or more than one.
+func selectMember(members []member, options ExtractOptions) (member, error) {
+	var candidates []member
+	if options.Member != "" {
+		for _, m := range members {
+			if matched, _ := path.Match(options.Member, m.name); matched || m.name == options.Member {
+				candidates = append(candidates, m)
+			}
+		}
+		return onlyMember(candidates, members, fmt.Sprintf("member %q", options.Member))
+	}
+
+	for _, m := range members {
+		base := path.Base(m.name)
+		if base == options.ToolName || base == options.ToolName+".exe" {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, m := range members {
+			if m.executable && strings.Contains(path.Base(m.name), options.ToolName) {
+				candidates = append(candidates, m)
+			}
+		}
+	}
+
+	// Prefer the members marked executable, if the archive marks any.
+	var executables []member
+	for _, m := range candidates {
+		if m.hasMode && m.executable {
+			executables = append(executables, m)
+		}
+	}
+	if len(executables) > 0 {
+		candidates = executables
+	}
+	return onlyMember(candidates, members, "executable "+options.ToolName)
+}
+
+func onlyMember(candidates, members []member, what string) (member, error) {
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 0:
+		return member{}, fmt.Errorf("no %s in archive; members: %s", what, listMembers(members))
+	default:
+		return member{}, fmt.Errorf("more than one %s in archive, set the archive member to choose: %s", what, listMembers(candidates))
+	}
+}
+
+func listMembers(members []member) string {
+	var names []string
+	for i, m := range members {
+		if i == maxListedMembers {
+			names = append(names, fmt.Sprintf("and %d more", len(members)-i))
+			break
+		}
+		names = append(names, m.name)
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeExecutable writes r to targetPath once it has been read completely
+// and checked, so a rejected file never replaces the target.
+func writeExecutable(r io.Reader, targetPath string, maxSize int64) error {
+	tmp, err := os.CreateTemp("", "asm-extract-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, err := io.Copy(tmp, io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+	if n > maxSize {
+		return fmt.Errorf("executable is larger than the limit of %d bytes", maxSize)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := CheckExecutable(tmp.Name()); err != nil {
+		return err
+	}
+	if err := CopyFile(tmp.Name(), targetPath); err != nil {
+		return fmt.Errorf("failed to create target file: %w", err)
+	}
+	return nil
+}
+
+// executableMagic lists the magic numbers of native executables by GOOS.
+// Other Unix systems use ELF.
+var executableMagic = map[string][][]byte{
+	"darwin": {
+		{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf},
+		{0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+		{0xca, 0xfe, 0xba, 0xbe},
+	},
+	"windows": {[]byte("MZ")},
+}
+
+var elfMagic = []byte("\x7fELF")
+
+// CheckExecutable checks that the file at path is an executable for the
+// running OS, or a script with a "#!" line, so a README or a shell
+// completion file picked by mistake is not installed as the tool.
+func CheckExecutable(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	head := make([]byte, 4)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	head = head[:n]
+
+	if bytes.HasPrefix(head, []byte("#!")) {
+		return nil
+	}
+	magics, ok := executableMagic[runtime.GOOS]
+	if !ok {
+		magics = [][]byte{elfMagic}
+	}
+	for _, magic := range magics {
+		if bytes.HasPrefix(head, magic) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the extracted file is not a %s executable (starts with %q)", runtime.GOOS, head)
+}
+
+// ExtractTarGzDir unpacks the regular files and directories of a gzipped
+// tar into dest, dropping the first strip path components of every name.
+// Entries that would land outside dest are rejected, as are archives that
+// unpack to more than a fixed size or number of entries.
+func ExtractTarGzDir(r io.Reader, dest string, strip int) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	var total int64
+	for entries := 0; ; entries++ {
+		if entries == maxArchiveEntries {
+			return fmt.Errorf("tar archive has more than %d entries", maxArchiveEntries)
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		name, err := memberPath(header.Name)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(strings.Trim(name, "/"), "/")
+		if len(parts) <= strip {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(path.Join(parts[strip:]...)))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			total += header.Size
+			if total > maxDirSize {
+				return fmt.Errorf("tar archive unpacks to more than %d bytes", int64(maxDirSize))
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			mode := os.FileMode(0644)
+			if header.Mode&0111 != 0 {
+				mode = 0755
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, tr); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
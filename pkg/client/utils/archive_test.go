@@ -0,0 +1,362 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// script is a member that passes CheckExecutable on every OS.
+const script = "#!/bin/sh\necho tool\n"
+
+type testMember struct {
+	name    string
+	mode    int64
+	content string
+}
+
+// writeArchive writes members to a new archive in dir, in the format given
+// by the suffix of name, and returns its path. A bare compressed file holds
+// the content of the first member.
+func writeArchive(t *testing.T, dir, name string, members ...testMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		zw := zip.NewWriter(&buf)
+		for _, m := range members {
+			header := &zip.FileHeader{Name: m.name, Method: zip.Deflate}
+			header.SetMode(os.FileMode(m.mode))
+			w, err := zw.CreateHeader(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(w, m.content)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".tar.zst"):
+		w := compressor(t, name, &buf)
+		tw := tar.NewWriter(w)
+		for _, m := range members {
+			header := &tar.Header{Name: m.name, Mode: m.mode, Size: int64(len(m.content)), Typeflag: tar.TypeReg}
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(tw, m.content)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		w := compressor(t, name, &buf)
+		io.WriteString(w, members[0].content)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archivePath := filepath.Join(dir, name)
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func compressor(t *testing.T, name string, w io.Writer) io.WriteCloser {
+	t.Helper()
+	var (
+		cw  io.WriteCloser
+		err error
+	)
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		cw = gzip.NewWriter(w)
+	case strings.HasSuffix(name, ".xz"):
+		cw, err = xz.NewWriter(w)
+	case strings.HasSuffix(name, ".zst"):
+		cw, err = zstd.NewWriter(w)
+	default:
+		t.Fatalf("no compressor for %s", name)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cw
+}
+
+func TestExtractExecutable(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		members []testMember
+		// fixture is an archive in testdata used instead of members, for
+		// formats the standard library cannot write.
+		fixture string
+		options ExtractOptions
+		wantErr string
+	}{
+		{
+			name:    "tar.gz",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool_1.0/tool", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+		},
+		{
+			name:    "tar.xz",
+			archive: "tool.tar.xz",
+			members: []testMember{{"tool", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+		},
+		{
+			name:    "tar.zst",
+			archive: "tool.tar.zst",
+			members: []testMember{{"bin/tool", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+		},
+		{
+			name:    "tar.bz2",
+			fixture: "tool.tar.bz2",
+			options: ExtractOptions{ToolName: "tool"},
+		},
+		{
+			name:    "bare gz",
+			archive: "tool_linux_amd64.gz",
+			members: []testMember{{"", 0, script}},
+			options: ExtractOptions{ToolName: "tool"},
+		},
+		{
+			name:    "zip",
+			archive: "tool.zip",
+			members: []testMember{{"tool", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+		},
+		{
+			name:    "parent directory in tar",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool", 0755, script}, {"../evil", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "escapes the archive",
+		},
+		{
+			name:    "nested parent directory in tar",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool", 0755, script}, {"bin/../../evil", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "escapes the archive",
+		},
+		{
+			name:    "absolute path in tar",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool", 0755, script}, {"/etc/evil", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "escapes the archive",
+		},
+		{
+			name:    "parent directory in zip",
+			archive: "tool.zip",
+			members: []testMember{{"tool", 0755, script}, {`..\evil`, 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "escapes the archive",
+		},
+		{
+			name:    "absolute path in zip",
+			archive: "tool.zip",
+			members: []testMember{{"tool", 0755, script}, {"/etc/evil", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "escapes the archive",
+		},
+		{
+			name:    "readme named after the tool",
+			archive: "nuclei.tar.gz",
+			members: []testMember{
+				{"nuclei_3.0/README-nuclei.md", 0755, "# nuclei\n"},
+				{"nuclei_3.0/nuclei", 0755, script},
+			},
+			options: ExtractOptions{ToolName: "nuclei"},
+		},
+		{
+			name:    "readme named after the tool in zip",
+			archive: "nuclei.zip",
+			members: []testMember{
+				{"README-nuclei.md", 0644, "# nuclei\n"},
+				{"nuclei", 0755, script},
+			},
+			options: ExtractOptions{ToolName: "nuclei"},
+		},
+		{
+			name:    "member glob",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool/docs/tool", 0644, "# tool\n"}, {"tool/bin/tool", 0755, script}},
+			options: ExtractOptions{Member: "*/bin/tool"},
+		},
+		{
+			name:    "two candidates",
+			archive: "tool.tar.gz",
+			members: []testMember{{"a/tool", 0755, script}, {"b/tool", 0755, script}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "more than one executable tool",
+		},
+		{
+			name:    "oversized member in tar",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool", 0755, script + strings.Repeat("#", 64)}},
+			options: ExtractOptions{ToolName: "tool", MaxSize: 32},
+			wantErr: "more than the limit of 32",
+		},
+		{
+			name:    "oversized member in zip",
+			archive: "tool.zip",
+			members: []testMember{{"tool", 0755, script + strings.Repeat("#", 64)}},
+			options: ExtractOptions{ToolName: "tool", MaxSize: 32},
+			wantErr: "more than the limit of 32",
+		},
+		{
+			name:    "oversized bare gz",
+			archive: "tool.gz",
+			members: []testMember{{"", 0, script + strings.Repeat("#", 1<<16)}},
+			options: ExtractOptions{ToolName: "tool", MaxSize: 1024},
+			wantErr: "larger than the limit of 1024",
+		},
+		{
+			name:    "not an executable",
+			archive: "tool.tar.gz",
+			members: []testMember{{"tool", 0755, "tool: a tool\n"}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "is not a",
+		},
+		{
+			name:    "no tool",
+			archive: "tool.tar.gz",
+			members: []testMember{{"README.md", 0644, "# tool\n"}},
+			options: ExtractOptions{ToolName: "tool"},
+			wantErr: "no executable tool in archive; members: README.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join("testdata", tt.fixture)
+			archiveName := tt.fixture
+			if tt.fixture == "" {
+				archivePath = writeArchive(t, dir, tt.archive, tt.members...)
+				archiveName = tt.archive
+			}
+			target := filepath.Join(dir, "installed")
+
+			err := ExtractExecutable(archivePath, archiveName, target, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExtractExecutable error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if _, statErr := os.Stat(target); !os.IsNotExist(statErr) {
+					t.Errorf("a rejected archive left %s behind", target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractExecutable: %v", err)
+			}
+			got, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != script {
+				t.Errorf("extracted %q, want the tool %q", got, script)
+			}
+		})
+	}
+}
+
+func TestCheckExecutable(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"script", script, false},
+		{"text", "tool 1.0\n", true},
+		{"markdown", "# tool\n", true},
+		{"empty", "", true},
+		{"short", "#", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "tool")
+			if err := os.WriteFile(file, []byte(tt.content), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := CheckExecutable(file); (err != nil) != tt.wantErr {
+				t.Errorf("CheckExecutable(%q) = %v, want error %v", tt.content, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractTarGzDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []testMember
+		strip   int
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "strip",
+			members: []testMember{{"pkg-1.0/bin/tool", 0755, script}, {"pkg-1.0/README", 0644, "readme"}},
+			strip:   1,
+			want:    map[string]string{"bin/tool": script, "README": "readme"},
+		},
+		{
+			name:    "parent directory",
+			members: []testMember{{"pkg/../../evil", 0644, "evil"}},
+			wantErr: "escapes the archive",
+		},
+		{
+			name:    "absolute path",
+			members: []testMember{{"/tmp/evil", 0644, "evil"}},
+			wantErr: "escapes the archive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := writeArchive(t, t.TempDir(), "dir.tar.gz", tt.members...)
+			file, err := os.Open(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			dest := t.TempDir()
+			err = ExtractTarGzDir(file, dest, tt.strip)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExtractTarGzDir error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractTarGzDir: %v", err)
+			}
+			for name, content := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+				if err != nil || string(got) != content {
+					t.Errorf("%s = %q, %v, want %q", name, got, err, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// previousDir is the directory, next to installed executables, keeping the
+// version each one replaced.
+const previousDir = ".previous"
+
+// probeTimeout bounds how long ProbeExecutable waits for a tool. A tool
+// still running then has started fine, it just does not exit on --version.
+const probeTimeout = 30 * time.Second
+
+// Stage creates a staging directory next to target and returns the path in
+// it to write the new executable to. Staging on the same file system lets
+// Commit rename it into place atomically. cleanup removes the directory and
+// whatever is left in it.
+func Stage(target string) (staged string, cleanup func(), err error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	stagingDir, err := os.MkdirTemp(dir, ".asm-staging-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return filepath.Join(stagingDir, filepath.Base(target)), func() { os.RemoveAll(stagingDir) }, nil
+}
+
+// Commit installs the staged executable as target. The staged file must
+// run (see ProbeExecutable), and is then renamed over target, so target is
+// never missing or truncated. The version it replaces is kept for Rollback.
+func Commit(staged, target string) error {
+	if err := os.Chmod(staged, 0755); err != nil {
+		return fmt.Errorf("failed to make %s executable: %w", staged, err)
+	}
+	if err := ProbeExecutable(staged); err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(target); err == nil {
+		if err := keepPrevious(target); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(staged, target); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", target, err)
+	}
+	return nil
+}
+
+// PreviousPath returns where the version of target replaced by the last
+// Commit is kept.
+func PreviousPath(target string) string {
+	return filepath.Join(filepath.Dir(target), previousDir, filepath.Base(target))
+}
+
+// HasPrevious reports whether target can be rolled back.
+func HasPrevious(target string) bool {
+	_, err := os.Lstat(PreviousPath(target))
+	return err == nil
+}
+
+// Rollback restores the version of target that the last Commit replaced.
+// There is one step of history, so the restored version cannot be rolled
+// back further.
+func Rollback(target string) error {
+	previous := PreviousPath(target)
+	if _, err := os.Lstat(previous); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no previous version of %s to roll back to", target)
+		}
+		return err
+	}
+	if err := os.Rename(previous, target); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", target, err)
+	}
+	return nil
+}
+
+// RemovePrevious deletes the version kept for rolling target back, e.g.
+// when the tool is uninstalled.
+func RemovePrevious(target string) error {
+	if err := os.Remove(PreviousPath(target)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", PreviousPath(target), err)
+	}
+	return nil
+}
+
+// keepPrevious links target into the previous directory, replacing the
+// version kept before it. Linking leaves target in place until Commit
+// renames the new version over it; file systems without hard links get a
+// copy instead.
+func keepPrevious(target string) error {
+	previous := PreviousPath(target)
+	if err := os.MkdirAll(filepath.Dir(previous), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(previous), err)
+	}
+	if err := os.Remove(previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to replace %s: %w", previous, err)
+	}
+
+	info, err := os.Lstat(target)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(target)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(link, previous); err != nil {
+			return fmt.Errorf("failed to keep the previous version of %s: %w", target, err)
+		}
+		return nil
+	}
+
+	if err := os.Link(target, previous); err == nil {
+		return nil
+	}
+	if err := copyExecutable(target, previous); err != nil {
+		return fmt.Errorf("failed to keep the previous version of %s: %w", target, err)
+	}
+	return nil
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ProbeExecutable checks that the executable at path runs, by running it
+// with --version. The exit status is not checked, since not every tool
+// knows the flag; an executable that cannot be started, such as a
+// truncated binary or a script whose interpreter is missing, or that is
+// killed by a signal, fails the probe.
+func ProbeExecutable(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, "--version")
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if err == nil || ctx.Err() != nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		// A shell exits with 126 or 127 when it cannot run the script's
+		// interpreter or command.
+		if code := exitErr.ExitCode(); code != 126 && code != 127 {
+			return nil
+		}
+	}
+	if len(output) > 0 {
+		return fmt.Errorf("%s does not run: %w: %s", filepath.Base(path), err, firstLine(output))
+	}
+	return fmt.Errorf("%s does not run: %w", filepath.Base(path), err)
+}
+
+func firstLine(output []byte) string {
+	for i, b := range output {
+		if b == '\n' {
+			return string(output[:i])
+		}
+	}
+	return string(output)
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// SudoEnv opts in to using sudo for installs that need root, when set to
+// "1" or "true". ASM never uses sudo otherwise.
+const SudoEnv = "ASM_ALLOW_SUDO"
+
+var sudoAllowed atomic.Bool
+
+// AllowSudo opts the current process in to using sudo, as SudoEnv does.
+func AllowSudo() {
+	sudoAllowed.Store(true)
+}
+
+// SudoAllowed reports whether installs may use sudo.
+func SudoAllowed() bool {
+	if sudoAllowed.Load() {
+		return true
+	}
+	value := os.Getenv(SudoEnv)
+	return value == "1" || value == "true"
+}
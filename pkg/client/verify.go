@@ -0,0 +1,278 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"aead.dev/minisign"
+)
+
+// DefaultChecksumPattern matches the checksums files published by goreleaser
+// and most other release tooling, such as "subfinder_2.6.6_checksums.txt" or
+// "SHA256SUMS".
+const DefaultChecksumPattern = `(?i)(checksums?|sha256sums?)(\.txt)?$`
+
+// maxChecksumFileSize bounds the checksums and signature files read into
+// memory.
+const maxChecksumFileSize = 1 << 20
+
+// Verification configures how a downloaded release asset is verified before
+// it is installed.
+type Verification struct {
+	// ChecksumPattern is a regular expression matching the name of the
+	// release's checksums file. Defaults to DefaultChecksumPattern.
+	ChecksumPattern string
+	// SHA256 pins the expected digest of the asset, in hex. When set, the
+	// release's checksums file is not consulted.
+	SHA256 string
+	// MinisignPublicKey is a minisign public key ("RW..."). When set, the
+	// checksums file, or the asset itself, must have a valid ".minisig"
+	// signature from this key.
+	MinisignPublicKey string
+	// CosignPublicKey is a PEM encoded ECDSA public key. When set, the
+	// checksums file, or the asset itself, must have a valid ".sig"
+	// signature from this key, as produced by "cosign sign-blob --key".
+	CosignPublicKey string
+	// AllowUnverified installs the asset with a warning when the release
+	// publishes no checksums file. Signature checks are never skipped.
+	AllowUnverified bool
+}
+
+// verify checks the downloaded asset at path against the configured digest
+// or the release's checksums file, and checks any configured signatures.
+func (c *GithubClient) verify(ctx context.Context, assetName, path string) error {
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	if c.verification.SHA256 != "" {
+		if !strings.EqualFold(digest, c.verification.SHA256) {
+			return fmt.Errorf("SHA-256 mismatch for %s: expected %s, got %s", assetName, c.verification.SHA256, digest)
+		}
+		fmt.Printf("Verified SHA-256 of %s against the pinned digest\n", assetName)
+		return c.verifySignatures(ctx, assetName, path, "", nil)
+	}
+
+	checksumAsset, err := c.findChecksumAsset()
+	if err != nil {
+		return err
+	}
+	if checksumAsset == nil {
+		if c.verification.MinisignPublicKey != "" || c.verification.CosignPublicKey != "" {
+			return c.verifySignatures(ctx, assetName, path, "", nil)
+		}
+		if c.verification.AllowUnverified {
+			fmt.Printf("WARNING: no checksums file found for %s, installing it unverified\n", assetName)
+			return nil
+		}
+		return fmt.Errorf("no checksums file found for %s", assetName)
+	}
+
+	checksums, err := c.fetchSmall(ctx, checksumAsset.BrowserDownloadURL)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", checksumAsset.Name, err)
+	}
+	if err := c.verifySignatures(ctx, assetName, path, checksumAsset.Name, checksums); err != nil {
+		return err
+	}
+
+	expected, found := lookupChecksum(checksums, assetName)
+	if !found {
+		return fmt.Errorf("%s has no entry for %s", checksumAsset.Name, assetName)
+	}
+	if !strings.EqualFold(digest, expected) {
+		return fmt.Errorf("SHA-256 mismatch for %s: %s lists %s, got %s", assetName, checksumAsset.Name, expected, digest)
+	}
+
+	fmt.Printf("Verified SHA-256 of %s against %s\n", assetName, checksumAsset.Name)
+	return nil
+}
+
+// verifySignatures checks the configured minisign and cosign signatures.
+// The signature of the checksums file is preferred, since that is what most
+// projects sign; otherwise the asset's own signature is used.
+func (c *GithubClient) verifySignatures(ctx context.Context, assetName, assetPath, checksumName string, checksums []byte) error {
+	type signer struct {
+		kind      string
+		key       string
+		extension string
+		verify    func(key string, message, signature []byte) error
+	}
+	signers := []signer{
+		{"minisign", c.verification.MinisignPublicKey, ".minisig", verifyMinisign},
+		{"cosign", c.verification.CosignPublicKey, ".sig", verifyCosign},
+	}
+
+	for _, s := range signers {
+		if s.key == "" {
+			continue
+		}
+
+		signedName, message := checksumName, checksums
+		signature := c.findAsset(checksumName + s.extension)
+		if checksumName == "" || signature == nil {
+			signedName = assetName
+			signature = c.findAsset(assetName + s.extension)
+			if signature != nil {
+				data, err := os.ReadFile(assetPath)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", assetPath, err)
+				}
+				message = data
+			}
+		}
+		if signature == nil {
+			return fmt.Errorf("no %s signature found for %s", s.kind, assetName)
+		}
+
+		sig, err := c.fetchSmall(ctx, signature.BrowserDownloadURL)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", signature.Name, err)
+		}
+		if err := s.verify(s.key, message, sig); err != nil {
+			return fmt.Errorf("%s signature of %s is invalid: %w", s.kind, signedName, err)
+		}
+		fmt.Printf("Verified %s signature of %s\n", s.kind, signedName)
+	}
+	return nil
+}
+
+// findChecksumAsset returns the release asset matching the checksums file
+// pattern, or nil if the release has none.
+func (c *GithubClient) findChecksumAsset() (*Asset, error) {
+	if c.release == nil {
+		return nil, nil
+	}
+
+	pattern := c.verification.ChecksumPattern
+	if pattern == "" {
+		pattern = DefaultChecksumPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum pattern %q: %w", pattern, err)
+	}
+
+	for i := range c.release.Assets {
+		if re.MatchString(c.release.Assets[i].Name) {
+			return &c.release.Assets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *GithubClient) findAsset(name string) *Asset {
+	if c.release == nil || name == "" {
+		return nil
+	}
+	for i := range c.release.Assets {
+		if c.release.Assets[i].Name == name {
+			return &c.release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// fetchSmall downloads a checksums or signature file into memory.
+func (c *GithubClient) fetchSmall(ctx context.Context, url string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.downloadFile(ctx, url, &limitedWriter{w: &buf, remaining: maxChecksumFileSize}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		return 0, fmt.Errorf("file is larger than %d bytes", maxChecksumFileSize)
+	}
+	l.remaining -= int64(len(p))
+	return l.w.Write(p)
+}
+
+// lookupChecksum finds the digest of name in a checksums file in the format
+// written by sha256sum: "<hex digest>  <file name>", where the name may be
+// prefixed with "*" for binary mode.
+func lookupChecksum(checksums []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func verifyMinisign(key string, message, signature []byte) error {
+	var publicKey minisign.PublicKey
+	if err := publicKey.UnmarshalText([]byte(strings.TrimSpace(key))); err != nil {
+		return fmt.Errorf("invalid minisign public key: %w", err)
+	}
+	if !minisign.Verify(publicKey, message, signature) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// verifyCosign checks a "cosign sign-blob --key" signature: a base64 encoded
+// ASN.1 ECDSA signature over the SHA-256 of the message.
+func verifyCosign(key string, message, signature []byte) error {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return fmt.Errorf("invalid cosign public key: no PEM block found")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid cosign public key: %w", err)
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported cosign public key type %T", parsed)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	digest := sha256.Sum256(message)
+	if !ecdsa.VerifyASN1(publicKey, digest[:], raw) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
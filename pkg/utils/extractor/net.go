@@ -1,42 +1,26 @@
 package extractor
 
-import "strings"
-
-func ExtractDomain(domain string) string {
-	domain = strings.TrimPrefix(domain, "https://")
-	domain = strings.TrimPrefix(domain, "http://")
-
-	if idx := strings.Index(domain, "/"); idx != -1 {
-		domain = domain[:idx]
+// ExtractDomain returns the host of target, or target itself if it cannot
+// be parsed.
+//
+// Deprecated: use Parse, which also reports the port, scheme and kind of
+// the target.
+func ExtractDomain(target string) string {
+	t, err := Parse(target)
+	if err != nil || t.Host == "" {
+		return target
 	}
-
-	if idx := strings.Index(domain, ":"); idx != -1 {
-		domain = domain[:idx]
-	}
-
-	return domain
+	return t.Host
 }
 
-func ExtractHostAndPort(url string) (host string, port string) {
-	port = ""
-
-	if strings.HasPrefix(url, "https://") {
-		url = strings.TrimPrefix(url, "https://")
-		port = "443"
-	} else if strings.HasPrefix(url, "http://") {
-		url = strings.TrimPrefix(url, "http://")
-		port = "80"
-	}
-
-	if idx := strings.Index(url, "/"); idx != -1 {
-		url = url[:idx]
-	}
-
-	if idx := strings.Index(url, ":"); idx != -1 {
-		host = url[:idx]
-		port = url[idx+1:]
-	} else {
-		host = url
+// ExtractHostAndPort returns the host of target and its port, or the
+// default port of its scheme.
+//
+// Deprecated: use Parse and Target.HostPort.
+func ExtractHostAndPort(target string) (host string, port string) {
+	t, err := Parse(target)
+	if err != nil {
+		return target, ""
 	}
-	return host, port
+	return t.Host, t.DefaultPort()
 }
@@ -0,0 +1,328 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// embeddedSuffixList is the Public Suffix List ASM was built with, from
+// https://publicsuffix.org/list/public_suffix_list.dat.
+//
+//go:embed public_suffix_list.dat
+var embeddedSuffixList []byte
+
+// ErrNoRegistrableDomain is returned, wrapped, by RegistrableDomain for
+// names that have no registrable domain: addresses, and public suffixes
+// such as co.uk.
+var ErrNoRegistrableDomain = errors.New("no registrable domain")
+
+// ruleKind is a set of the Public Suffix List rules written for a name.
+type ruleKind uint8
+
+const (
+	// ruleNormal makes the name itself a public suffix, as "co.uk".
+	ruleNormal ruleKind = 1 << iota
+	// ruleWildcard makes every child of the name a public suffix, as
+	// "*.ck".
+	ruleWildcard
+	// ruleException excepts the name from a wildcard rule of its parent,
+	// as "!www.ck".
+	ruleException
+)
+
+// SuffixList is a parsed Public Suffix List: the domains under which
+// anyone can register names, such as com, co.uk or github.io. The
+// registrable domain of a name is the label left of its public suffix
+// together with that suffix, and is what tells the organizations owning
+// names apart.
+type SuffixList struct {
+	rules map[string]ruleKind
+	// ICANN and Private count the rules of each section of the list.
+	ICANN   int
+	Private int
+	// SHA256 is the digest of the list, in hex.
+	SHA256 string
+}
+
+// ParseSuffixList parses a list in the format of public_suffix_list.dat,
+// section markers included. International rules are converted to
+// punycode, so lookups match the hosts Parse returns.
+func ParseSuffixList(r io.Reader) (*SuffixList, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public suffix list: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	list := &SuffixList{
+		rules:  make(map[string]ruleKind),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+
+	// The section markers tell a complete list from a truncated download.
+	icann, private, end := false, false, false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "// ===BEGIN ICANN DOMAINS==="):
+			icann = true
+			continue
+		case strings.HasPrefix(line, "// ===BEGIN PRIVATE DOMAINS==="):
+			private = true
+			continue
+		case strings.HasPrefix(line, "// ===END PRIVATE DOMAINS==="):
+			end = true
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+		rule := strings.Fields(line)[0]
+
+		kind := ruleNormal
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			kind, rule = ruleException, rule[1:]
+		case strings.HasPrefix(rule, "*."):
+			kind, rule = ruleWildcard, rule[2:]
+		}
+		name, err := normalizeDomain(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public suffix rule on line %d: %v", n, err)
+		}
+		list.rules[name] |= kind
+		if private {
+			list.Private++
+		} else {
+			list.ICANN++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read public suffix list: %w", err)
+	}
+	if !icann || !end {
+		return nil, errors.New("public suffix list is incomplete: its ICANN or private section markers are missing")
+	}
+	if list.ICANN == 0 {
+		return nil, errors.New("public suffix list has no ICANN rules")
+	}
+	return list, nil
+}
+
+// LoadSuffixList parses the public suffix list in the file at path.
+func LoadSuffixList(path string) (*SuffixList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open public suffix list: %w", err)
+	}
+	defer file.Close()
+	return ParseSuffixList(file)
+}
+
+var (
+	defaultSuffixList atomic.Pointer[SuffixList]
+	embeddedOnce      sync.Once
+	embeddedList      *SuffixList
+)
+
+// DefaultSuffixList returns the list the package functions use: the one
+// passed to SetDefaultSuffixList, or the list embedded at build time.
+func DefaultSuffixList() *SuffixList {
+	if list := defaultSuffixList.Load(); list != nil {
+		return list
+	}
+	return EmbeddedSuffixList()
+}
+
+// EmbeddedSuffixList returns the list embedded at build time.
+func EmbeddedSuffixList() *SuffixList {
+	embeddedOnce.Do(func() {
+		list, err := ParseSuffixList(bytes.NewReader(embeddedSuffixList))
+		if err != nil {
+			panic(fmt.Sprintf("embedded public suffix list: %v", err))
+		}
+		embeddedList = list
+	})
+	return embeddedList
+}
+
+// SetDefaultSuffixList replaces the list the package functions use, for
+// a list refreshed after the build. A nil list restores the embedded one.
+func SetDefaultSuffixList(list *SuffixList) {
+	defaultSuffixList.Store(list)
+}
+
+// PublicSuffix returns the public suffix of name, a domain, URL or any
+// other target Parse accepts, using the default list. A name under no
+// rule has its top-level label as suffix. It is "" for addresses and
+// networks.
+func PublicSuffix(name string) string {
+	return DefaultSuffixList().PublicSuffix(name)
+}
+
+// RegistrableDomain returns the registrable domain of name using the
+// default list: example.co.uk for www.shop.example.co.uk.
+func RegistrableDomain(name string) (string, error) {
+	return DefaultSuffixList().RegistrableDomain(name)
+}
+
+// SubdomainLabels returns the labels of name left of its registrable
+// domain using the default list: [www shop] for www.shop.example.co.uk.
+func SubdomainLabels(name string) []string {
+	return DefaultSuffixList().SubdomainLabels(name)
+}
+
+// IsPublicSuffix reports whether name is listed as a public suffix in the
+// default list.
+func IsPublicSuffix(name string) bool {
+	return DefaultSuffixList().IsPublicSuffix(name)
+}
+
+// PublicSuffix returns the public suffix of name. A name under no rule has
+// its top-level label as suffix. It is "" for addresses and networks.
+func (l *SuffixList) PublicSuffix(name string) string {
+	domain, ok := domainName(name)
+	if !ok {
+		return ""
+	}
+	suffix, _ := l.lookup(domain)
+	return suffix
+}
+
+// RegistrableDomain returns the registrable domain of name: its public
+// suffix and the label left of it. It fails for addresses, networks and
+// public suffixes.
+func (l *SuffixList) RegistrableDomain(name string) (string, error) {
+	domain, ok := domainName(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not a domain", ErrNoRegistrableDomain, name)
+	}
+	suffix, _ := l.lookup(domain)
+	if len(domain) == len(suffix) {
+		return "", fmt.Errorf("%w: %s is a public suffix", ErrNoRegistrableDomain, domain)
+	}
+	rest := domain[:len(domain)-len(suffix)-1]
+	return rest[strings.LastIndexByte(rest, '.')+1:] + "." + suffix, nil
+}
+
+// SubdomainLabels returns the labels of name left of its registrable
+// domain, outermost first. It is nil for registrable domains and for
+// names that have none.
+func (l *SuffixList) SubdomainLabels(name string) []string {
+	domain, ok := domainName(name)
+	if !ok {
+		return nil
+	}
+	registrable, err := l.RegistrableDomain(domain)
+	if err != nil || registrable == domain {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(domain, "."+registrable), ".")
+}
+
+// IsPublicSuffix reports whether name is a public suffix under a rule of
+// the list. Unlisted top-level domains, which only count as suffixes by
+// the list's implicit "*" rule, are not.
+func (l *SuffixList) IsPublicSuffix(name string) bool {
+	domain, ok := domainName(name)
+	if !ok {
+		return false
+	}
+	suffix, listed := l.lookup(domain)
+	return listed && suffix == domain
+}
+
+// lookup returns the public suffix of domain, a normalized host name, and
+// whether a rule of the list matched it, rather than the implicit "*".
+// The longest matching rule wins, and an exception beats the wildcard it
+// excepts from.
+func (l *SuffixList) lookup(domain string) (string, bool) {
+	name := domain
+	for {
+		parent := ""
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			parent = name[i+1:]
+		}
+		kind := l.rules[name]
+		switch {
+		case kind&ruleException != 0:
+			return parent, true
+		case kind&ruleNormal != 0:
+			return name, true
+		case parent != "" && l.rules[parent]&ruleWildcard != 0:
+			return name, true
+		case parent == "":
+			return name, false
+		}
+		name = parent
+	}
+}
+
+// domainName returns the normalized host of name if it is a domain.
+func domainName(name string) (string, bool) {
+	t, err := Parse(name)
+	if err != nil || t.Kind != KindDomain {
+		return "", false
+	}
+	return t.Host, true
+}
+
+// RegistrableDomain returns the registrable domain of a domain target using
+// the default list, or "" if it has none.
+func (t Target) RegistrableDomain() string {
+	if t.Kind != KindDomain {
+		return ""
+	}
+	domain, err := RegistrableDomain(t.Host)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// DomainGroup is a registrable domain and names under it.
+type DomainGroup struct {
+	Domain string
+	Names  []string
+}
+
+// GroupByRegistrableDomain groups names by their registrable domain using
+// the default list, dropping duplicates. Groups are ordered by domain and
+// names keep their order. A name without a registrable domain, such as an
+// address, forms a group of its own.
+func GroupByRegistrableDomain(names []string) []DomainGroup {
+	list := DefaultSuffixList()
+	index := map[string]int{}
+	seen := map[string]bool{}
+	var groups []DomainGroup
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		domain, err := list.RegistrableDomain(name)
+		if err != nil {
+			domain = name
+		}
+		i, ok := index[domain]
+		if !ok {
+			i = len(groups)
+			index[domain] = i
+			groups = append(groups, DomainGroup{Domain: domain})
+		}
+		groups[i].Names = append(groups[i].Names, name)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Domain < groups[j].Domain })
+	return groups
+}
@@ -0,0 +1,217 @@
+package extractor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSuffixList is a small list in the format of public_suffix_list.dat,
+// with a rule of each kind.
+const testSuffixList = `// ===BEGIN ICANN DOMAINS===
+uk
+co.uk
+jp
+*.kawasaki.jp
+!city.kawasaki.jp
+cn
+公司.cn
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+github.io
+// ===END PRIVATE DOMAINS===
+`
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		input  string
+		domain string
+		labels []string
+		suffix string
+	}{
+		{input: "example.com", domain: "example.com", suffix: "com"},
+		{input: "www.example.com", domain: "example.com", labels: []string{"www"}, suffix: "com"},
+		{input: "www.shop.example.co.uk", domain: "example.co.uk", labels: []string{"www", "shop"}, suffix: "co.uk"},
+		{input: "example.uk", domain: "example.uk", suffix: "uk"},
+		{input: "ixbahy.github.io", domain: "ixbahy.github.io", suffix: "github.io"},
+		{input: "docs.ixbahy.github.io", domain: "ixbahy.github.io", labels: []string{"docs"}, suffix: "github.io"},
+		{input: "www.example.kawasaki.jp", domain: "www.example.kawasaki.jp", suffix: "example.kawasaki.jp"},
+		{input: "a.b.example.kawasaki.jp", domain: "b.example.kawasaki.jp", labels: []string{"a"}, suffix: "example.kawasaki.jp"},
+		{input: "city.kawasaki.jp", domain: "city.kawasaki.jp", suffix: "kawasaki.jp"},
+		{input: "www.city.kawasaki.jp", domain: "city.kawasaki.jp", labels: []string{"www"}, suffix: "kawasaki.jp"},
+		{input: "shop.example.公司.cn", domain: "example.xn--55qx5d.cn", labels: []string{"shop"}, suffix: "xn--55qx5d.cn"},
+		{input: "www.bücher.de", domain: "xn--bcher-kva.de", labels: []string{"www"}, suffix: "de"},
+		{input: "www.example.co.uk.", domain: "example.co.uk", labels: []string{"www"}, suffix: "co.uk"},
+		{input: "https://WWW.Example.co.uk:8443/login", domain: "example.co.uk", labels: []string{"www"}, suffix: "co.uk"},
+		{input: "*.example.co.uk", domain: "example.co.uk", suffix: "co.uk"},
+		{input: "host.internal", domain: "host.internal", suffix: "internal"},
+	}
+	list := EmbeddedSuffixList()
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			domain, err := list.RegistrableDomain(tt.input)
+			if err != nil {
+				t.Fatalf("RegistrableDomain(%q): %v", tt.input, err)
+			}
+			if domain != tt.domain {
+				t.Errorf("RegistrableDomain(%q) = %q, want %q", tt.input, domain, tt.domain)
+			}
+			if got := list.SubdomainLabels(tt.input); !reflect.DeepEqual(got, tt.labels) {
+				t.Errorf("SubdomainLabels(%q) = %q, want %q", tt.input, got, tt.labels)
+			}
+			if got := list.PublicSuffix(tt.input); got != tt.suffix {
+				t.Errorf("PublicSuffix(%q) = %q, want %q", tt.input, got, tt.suffix)
+			}
+		})
+	}
+}
+
+func TestRegistrableDomainNone(t *testing.T) {
+	tests := []string{
+		"com",
+		"co.uk",
+		"co.uk.",
+		"github.io",
+		"example.kawasaki.jp",
+		"公司.cn",
+		"10.0.0.1",
+		"[::1]:443",
+		"10.0.0.0/24",
+		"exa mple.com",
+	}
+	list := EmbeddedSuffixList()
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			domain, err := list.RegistrableDomain(input)
+			if !errors.Is(err, ErrNoRegistrableDomain) {
+				t.Fatalf("RegistrableDomain(%q) = %q, %v, want ErrNoRegistrableDomain", input, domain, err)
+			}
+			if labels := list.SubdomainLabels(input); labels != nil {
+				t.Errorf("SubdomainLabels(%q) = %q, want none", input, labels)
+			}
+		})
+	}
+}
+
+func TestIsPublicSuffix(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"com", true},
+		{"co.uk", true},
+		{"CO.UK.", true},
+		{"github.io", true},
+		{"公司.cn", true},
+		{"xn--55qx5d.cn", true},
+		{"example.kawasaki.jp", true},
+		{"city.kawasaki.jp", false},
+		{"example.co.uk", false},
+		{"ixbahy.github.io", false},
+		{"internal", false},
+		{"10.0.0.1", false},
+	}
+	list := EmbeddedSuffixList()
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := list.IsPublicSuffix(tt.input); got != tt.want {
+				t.Errorf("IsPublicSuffix(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSuffixList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "public_suffix_list.dat")
+	if err := os.WriteFile(path, []byte(testSuffixList), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadSuffixList(path)
+	if err != nil {
+		t.Fatalf("LoadSuffixList: %v", err)
+	}
+	if list.ICANN != 7 || list.Private != 1 {
+		t.Errorf("LoadSuffixList counted %d ICANN and %d private rules, want 7 and 1", list.ICANN, list.Private)
+	}
+	if len(list.SHA256) != 64 {
+		t.Errorf("LoadSuffixList digest = %q, want a SHA-256 in hex", list.SHA256)
+	}
+
+	// A loaded list replaces the embedded one in the package functions, as
+	// "tools psl -update" does, until it is reset.
+	SetDefaultSuffixList(list)
+	t.Cleanup(func() { SetDefaultSuffixList(nil) })
+	if DefaultSuffixList() != list {
+		t.Fatal("DefaultSuffixList is not the list passed to SetDefaultSuffixList")
+	}
+	if got, err := RegistrableDomain("www.example.com"); err != nil || got != "example.com" {
+		t.Errorf("RegistrableDomain(www.example.com) = %q, %v, want example.com under the implicit rule", got, err)
+	}
+	if !IsPublicSuffix("github.io") || IsPublicSuffix("com") {
+		t.Error("IsPublicSuffix does not use the loaded list")
+	}
+	if got := SubdomainLabels("a.b.example.kawasaki.jp"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("SubdomainLabels(a.b.example.kawasaki.jp) = %q, want [a]", got)
+	}
+	SetDefaultSuffixList(nil)
+	if DefaultSuffixList() != EmbeddedSuffixList() {
+		t.Error("SetDefaultSuffixList(nil) did not restore the embedded list")
+	}
+}
+
+func TestLoadSuffixListInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "truncated",
+			content: strings.Split(testSuffixList, "github.io")[0],
+			wantErr: "incomplete",
+		},
+		{
+			name:    "no section markers",
+			content: "com\nco.uk\n",
+			wantErr: "incomplete",
+		},
+		{
+			name:    "html page",
+			content: "<!DOCTYPE html>\n<html><body>Not Found</body></html>\n",
+			wantErr: "invalid public suffix rule on line 1",
+		},
+		{
+			name:    "invalid rule",
+			content: strings.Replace(testSuffixList, "co.uk", "co..uk", 1),
+			wantErr: "invalid public suffix rule on line 3",
+		},
+		{
+			name:    "no ICANN rules",
+			content: "// ===BEGIN ICANN DOMAINS===\n// ===END ICANN DOMAINS===\n// ===BEGIN PRIVATE DOMAINS===\ngithub.io\n// ===END PRIVATE DOMAINS===\n",
+			wantErr: "no ICANN rules",
+		},
+		{
+			name:    "empty",
+			wantErr: "incomplete",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "public_suffix_list.dat")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			list, err := LoadSuffixList(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadSuffixList = %+v, %v, want an error containing %q", list, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadSuffixList(filepath.Join(t.TempDir(), "missing.dat")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadSuffixList of a missing file = %v, want it to wrap os.ErrNotExist", err)
+	}
+}
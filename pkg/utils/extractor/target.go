@@ -0,0 +1,492 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Kind is what a Target names.
+type Kind string
+
+const (
+	// KindDomain is a host name, possibly a wildcard such as *.example.com.
+	KindDomain Kind = "domain"
+	// KindIP is a single IPv4 or IPv6 address.
+	KindIP Kind = "ip"
+	// KindCIDR is a network in CIDR notation, such as 10.0.0.0/24.
+	KindCIDR Kind = "cidr"
+	// KindRange is an inclusive range of addresses, such as
+	// 10.0.0.1-10.0.0.20 or its short form 10.0.0.1-20.
+	KindRange Kind = "range"
+)
+
+// ErrInvalidTarget is returned, wrapped, by Parse for input that is not a
+// target.
+var ErrInvalidTarget = errors.New("invalid target")
+
+// Target is a parsed scan target: a URL of any scheme, a host with an
+// optional port, an IP address, a network or a range of addresses.
+type Target struct {
+	// Raw is the input Parse was given, without surrounding whitespace.
+	Raw  string
+	Kind Kind
+	// Scheme is the URL scheme in lower case, or "" if there was none.
+	Scheme string
+	// User is the userinfo of a URL, such as "user" or "user:password".
+	User string
+	// Host is the domain in lower case ASCII, with international names in
+	// punycode and without a trailing dot, or the address of a KindIP
+	// target. It is "" for networks and ranges.
+	Host string
+	// Wildcard is set for targets such as *.example.com, whose Host is
+	// example.com.
+	Wildcard bool
+	// Port is the port given explicitly, or "". See DefaultPort.
+	Port string
+	// Path, Query and Fragment are the rest of a URL, as given.
+	Path     string
+	Query    string
+	Fragment string
+
+	// Addr is the address of a KindIP target.
+	Addr netip.Addr
+	// Prefix is the network of a KindCIDR target.
+	Prefix netip.Prefix
+	// First and Last are the bounds of a KindRange target.
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// schemePattern matches the scheme of a URL, per RFC 3986.
+var schemePattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*)://`)
+
+// defaultPorts are the well-known ports of the schemes scanners see most.
+var defaultPorts = map[string]string{
+	"http":     "80",
+	"https":    "443",
+	"ws":       "80",
+	"wss":      "443",
+	"ftp":      "21",
+	"ftps":     "990",
+	"ssh":      "22",
+	"sftp":     "22",
+	"telnet":   "23",
+	"smtp":     "25",
+	"smtps":    "465",
+	"dns":      "53",
+	"pop3":     "110",
+	"imap":     "143",
+	"imaps":    "993",
+	"ldap":     "389",
+	"ldaps":    "636",
+	"smb":      "445",
+	"mysql":    "3306",
+	"rdp":      "3389",
+	"postgres": "5432",
+	"redis":    "6379",
+	"mongodb":  "27017",
+	"git":      "9418",
+}
+
+// idnaProfile converts international domain names to punycode. It allows
+// the underscores of service names such as _dmarc.example.com, which a
+// strict lookup profile rejects.
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.VerifyDNSLength(true),
+)
+
+// Parse parses a scan target. It accepts:
+//
+//   - URLs of any scheme, with userinfo, port, path, query and fragment
+//   - host names, international ones included, with or without a port
+//   - wildcards such as *.example.com
+//   - IPv4 and IPv6 addresses, bracketed when a port follows ([::1]:443)
+//   - networks in CIDR notation (10.0.0.0/24, 2001:db8::/64)
+//   - address ranges (10.0.0.1-10.0.0.20, 10.0.0.1-20)
+func Parse(input string) (Target, error) {
+	raw := strings.TrimSpace(input)
+	t := Target{Raw: raw}
+	if raw == "" {
+		return t, fmt.Errorf("%w: empty target", ErrInvalidTarget)
+	}
+
+	rest := raw
+	if match := schemePattern.FindStringSubmatch(rest); match != nil {
+		t.Scheme = strings.ToLower(match[1])
+		rest = rest[len(match[0]):]
+	} else if network, ok, err := parseNetwork(rest); ok {
+		network.Raw = raw
+		if err != nil {
+			return t, fmt.Errorf("%w: %q: %v", ErrInvalidTarget, raw, err)
+		}
+		return network, nil
+	}
+
+	authority := rest
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority = rest[:i]
+		rest = rest[i:]
+	} else {
+		rest = ""
+	}
+	rest, t.Fragment, _ = strings.Cut(rest, "#")
+	t.Path, t.Query, _ = strings.Cut(rest, "?")
+
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		t.User = authority[:i]
+		authority = authority[i+1:]
+	}
+
+	host, port, err := splitHostPort(authority)
+	if err != nil {
+		return t, fmt.Errorf("%w: %q: %v", ErrInvalidTarget, raw, err)
+	}
+	t.Port = port
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		t.Kind = KindIP
+		t.Addr = addr.Unmap()
+		t.Host = t.Addr.String()
+		return t, nil
+	}
+
+	if strings.HasPrefix(host, "*.") {
+		t.Wildcard = true
+		host = host[2:]
+	}
+	domain, err := normalizeDomain(host)
+	if err != nil {
+		return t, fmt.Errorf("%w: %q: %v", ErrInvalidTarget, raw, err)
+	}
+	t.Kind = KindDomain
+	t.Host = domain
+	return t, nil
+}
+
+// splitHostPort splits an authority into host and port. IPv6 addresses
+// must be bracketed to carry a port; an unbracketed one is all host.
+func splitHostPort(authority string) (string, string, error) {
+	if authority == "" {
+		return "", "", errors.New("no host")
+	}
+
+	host, port := authority, ""
+	if strings.HasPrefix(authority, "[") {
+		end := strings.Index(authority, "]")
+		if end < 0 {
+			return "", "", errors.New("missing ] in IPv6 address")
+		}
+		host = authority[1:end]
+		after := authority[end+1:]
+		if after != "" {
+			if !strings.HasPrefix(after, ":") {
+				return "", "", fmt.Errorf("unexpected %q after IPv6 address", after)
+			}
+			port = after[1:]
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.Is6() {
+			return "", "", fmt.Errorf("invalid IPv6 address %q", host)
+		}
+	} else if strings.Count(authority, ":") == 1 {
+		host, port, _ = strings.Cut(authority, ":")
+	}
+
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return "", "", fmt.Errorf("invalid port %q", port)
+		}
+		port = strconv.Itoa(n)
+	}
+	if host == "" {
+		return "", "", errors.New("no host")
+	}
+	return host, port, nil
+}
+
+// normalizeDomain lower-cases a host name, removes a trailing dot and
+// converts international names to punycode.
+func normalizeDomain(host string) (string, error) {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return "", errors.New("no host")
+	}
+	if strings.ContainsAny(host, " \t*[]<>\\\"'`{}|^%") {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	ascii, err := idnaProfile.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %v", host, err)
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// parseNetwork parses a CIDR network or an address range. It reports
+// whether s has the form of one, even if it is invalid, such as a range
+// whose end comes before its start.
+func parseNetwork(s string) (Target, bool, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return Target{Kind: KindCIDR, Prefix: prefix.Masked()}, true, nil
+	}
+
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return Target{}, false, nil
+	}
+	first, err := netip.ParseAddr(from)
+	if err != nil {
+		return Target{}, false, nil
+	}
+	last, err := netip.ParseAddr(to)
+	if err != nil && first.Is4() {
+		// The short form replaces the last octet: 10.0.0.1-20.
+		n, convErr := strconv.Atoi(to)
+		if convErr != nil || n < 0 || n > 255 {
+			return Target{}, true, fmt.Errorf("invalid end of range %q", to)
+		}
+		octets := first.As4()
+		octets[3] = byte(n)
+		last, err = netip.AddrFrom4(octets), nil
+	}
+	switch {
+	case err != nil:
+		return Target{}, true, fmt.Errorf("invalid end of range %q", to)
+	case first.Is4() != last.Is4():
+		return Target{}, true, errors.New("range mixes IPv4 and IPv6 addresses")
+	case last.Less(first):
+		return Target{}, true, errors.New("range ends before it starts")
+	}
+	return Target{Kind: KindRange, First: first, Last: last}, true, nil
+}
+
+// DefaultPort returns the explicit port, or the well-known port of the
+// scheme, or "" if neither is known.
+func (t Target) DefaultPort() string {
+	if t.Port != "" {
+		return t.Port
+	}
+	return defaultPorts[t.Scheme]
+}
+
+// HostPort returns host:port with the DefaultPort, or fallback when there
+// is none, bracketing IPv6 addresses. It is "" for networks and ranges.
+func (t Target) HostPort(fallback string) string {
+	if t.Host == "" {
+		return ""
+	}
+	port := t.DefaultPort()
+	if port == "" {
+		port = fallback
+	}
+	if port == "" {
+		return t.hostLiteral()
+	}
+	return net.JoinHostPort(t.Host, port)
+}
+
+// URL returns the target as a URL, with the scheme defaulting to
+// defaultScheme. It is "" for networks and ranges.
+func (t Target) URL(defaultScheme string) string {
+	if t.Host == "" {
+		return ""
+	}
+	scheme := t.Scheme
+	if scheme == "" {
+		scheme = defaultScheme
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme)
+	b.WriteString("://")
+	if t.User != "" {
+		b.WriteString(t.User)
+		b.WriteByte('@')
+	}
+	b.WriteString(t.hostLiteral())
+	if t.Port != "" && t.Port != defaultPorts[scheme] {
+		b.WriteByte(':')
+		b.WriteString(t.Port)
+	}
+	b.WriteString(t.Path)
+	if t.Query != "" {
+		b.WriteByte('?')
+		b.WriteString(t.Query)
+	}
+	if t.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(t.Fragment)
+	}
+	return b.String()
+}
+
+// String returns the target in canonical form: the URL for targets with
+// a scheme, host[:port] for hosts and addresses, and the network or range
+// otherwise.
+func (t Target) String() string {
+	switch t.Kind {
+	case KindCIDR:
+		return t.Prefix.String()
+	case KindRange:
+		return t.First.String() + "-" + t.Last.String()
+	}
+	if t.Scheme != "" {
+		return t.URL(t.Scheme)
+	}
+	host := t.hostLiteral()
+	if t.Wildcard {
+		host = "*." + host
+	}
+	if t.Port != "" {
+		host += ":" + t.Port
+	}
+	return host
+}
+
+// Unicode returns the domain of the target with punycode decoded, for
+// display.
+func (t Target) Unicode() string {
+	if t.Kind != KindDomain {
+		return t.Host
+	}
+	name, err := idnaProfile.ToUnicode(t.Host)
+	if err != nil {
+		return t.Host
+	}
+	return name
+}
+
+// IsHost reports whether the target names a single host: a domain that is
+// not a wildcard, or an address.
+func (t Target) IsHost() bool {
+	return t.Kind == KindIP || (t.Kind == KindDomain && !t.Wildcard)
+}
+
+// IsNetwork reports whether the target is a CIDR network or a range.
+func (t Target) IsNetwork() bool {
+	return t.Kind == KindCIDR || t.Kind == KindRange
+}
+
+// Prefixes returns the smallest set of CIDR networks covering the
+// addresses of the target: one for an address or network, and as many as
+// needed for a range. It is nil for domains.
+func (t Target) Prefixes() []netip.Prefix {
+	switch t.Kind {
+	case KindIP:
+		return []netip.Prefix{netip.PrefixFrom(t.Addr, t.Addr.BitLen())}
+	case KindCIDR:
+		return []netip.Prefix{t.Prefix}
+	case KindRange:
+		return rangePrefixes(t.First, t.Last)
+	}
+	return nil
+}
+
+// HostSpecs returns the target as host arguments for port scanners: the
+// host of a domain or address, and the CIDR networks covering a network
+// or range.
+func (t Target) HostSpecs() []string {
+	if !t.IsNetwork() {
+		return []string{t.Host}
+	}
+	var specs []string
+	for _, prefix := range t.Prefixes() {
+		specs = append(specs, prefix.String())
+	}
+	return specs
+}
+
+// Contains reports whether every host or address of other is part of t.
+// A domain contains itself and its subdomains, and an address, network or
+// range contains the addresses inside it.
+func (t Target) Contains(other Target) bool {
+	if t.Kind == KindDomain {
+		return other.Kind == KindDomain && (other.Host == t.Host || strings.HasSuffix(other.Host, "."+t.Host))
+	}
+	first, last, ok := t.bounds()
+	otherFirst, otherLast, otherOK := other.bounds()
+	return ok && otherOK && first.Is4() == otherFirst.Is4() && !otherFirst.Less(first) && !last.Less(otherLast)
+}
+
+// Overlaps reports whether t and other share any host or address.
+func (t Target) Overlaps(other Target) bool {
+	if t.Kind == KindDomain || other.Kind == KindDomain {
+		return t.Contains(other) || other.Contains(t)
+	}
+	first, last, ok := t.bounds()
+	otherFirst, otherLast, otherOK := other.bounds()
+	return ok && otherOK && first.Is4() == otherFirst.Is4() && !otherLast.Less(first) && !last.Less(otherFirst)
+}
+
+// bounds returns the first and last address of an address, network or
+// range target.
+func (t Target) bounds() (netip.Addr, netip.Addr, bool) {
+	switch t.Kind {
+	case KindIP:
+		return t.Addr, t.Addr, true
+	case KindCIDR:
+		return t.Prefix.Addr(), lastAddr(t.Prefix), true
+	case KindRange:
+		return t.First, t.Last, true
+	}
+	return netip.Addr{}, netip.Addr{}, false
+}
+
+func (t Target) hostLiteral() string {
+	if t.Kind == KindIP && t.Addr.Is6() {
+		return "[" + t.Host + "]"
+	}
+	return t.Host
+}
+
+// lastAddr returns the last address of prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Masked().Addr()
+	bytes := addr.As16()
+	offset := 0
+	if addr.Is4() {
+		offset = 96
+	}
+	for bit := prefix.Bits() + offset; bit < 128; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	last := netip.AddrFrom16(bytes)
+	if addr.Is4() {
+		return last.Unmap()
+	}
+	return last
+}
+
+// rangePrefixes splits the range first-last into CIDR networks, taking
+// the largest network that starts at the next address each time.
+func rangePrefixes(first, last netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for first.IsValid() && !last.Less(first) {
+		bits := first.BitLen()
+		for bits > 0 {
+			wider := netip.PrefixFrom(first, bits-1)
+			if wider.Masked().Addr() != first || last.Less(lastAddr(wider)) {
+				break
+			}
+			bits--
+		}
+		prefix := netip.PrefixFrom(first, bits)
+		prefixes = append(prefixes, prefix)
+		end := lastAddr(prefix)
+		if end == last {
+			break
+		}
+		first = end.Next()
+	}
+	return prefixes
+}